@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -22,6 +24,11 @@ type config struct {
 	DBPath         string
 	CacheTTL       time.Duration
 	NotifyInterval time.Duration
+	QuoteProviders []string
+	RateLimit      float64
+	Breaker        finance.BreakerConfig
+	AdminChatIDs   []int64
+	YahooBaseURL   string
 }
 
 func loadConfig() config {
@@ -37,11 +44,34 @@ func loadConfig() config {
 		notifyInterval = 30 * time.Minute
 	}
 
+	rateLimit, err := strconv.ParseFloat(getEnv("RATE_LIMIT_PER_SEC", "5"), 64)
+	if err != nil {
+		rateLimit = 5
+	}
+
+	failureThreshold, err := strconv.Atoi(getEnv("CIRCUIT_FAILURE_THRESHOLD", "5"))
+	if err != nil {
+		failureThreshold = 5
+	}
+
+	openTimeout, err := time.ParseDuration(getEnv("CIRCUIT_OPEN_TIMEOUT", "30s"))
+	if err != nil {
+		openTimeout = 30 * time.Second
+	}
+
 	return config{
 		TelegramToken:  mustEnv("TELEGRAM_BOT_TOKEN"),
 		DBPath:         getEnv("DB_PATH", "./portfolio.db"),
 		CacheTTL:       cacheTTL,
 		NotifyInterval: notifyInterval,
+		QuoteProviders: splitList(getEnv("QUOTE_PROVIDERS", "yahoo,stooq")),
+		RateLimit:      rateLimit,
+		Breaker: finance.BreakerConfig{
+			FailureThreshold: failureThreshold,
+			OpenTimeout:      openTimeout,
+		},
+		AdminChatIDs: parseChatIDs(getEnv("ADMIN_CHAT_IDS", "")),
+		YahooBaseURL: getEnv("YAHOO_BASE_URL", ""),
 	}
 }
 
@@ -52,6 +82,29 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, strings.ToLower(item))
+		}
+	}
+	return items
+}
+
+func parseChatIDs(s string) []int64 {
+	var ids []int64
+	for _, item := range splitList(s) {
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			log.Printf("invalid chat ID %q in ADMIN_CHAT_IDS, skipping", item)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func mustEnv(key string) string {
 	v := os.Getenv(key)
 	if v == "" {
@@ -60,6 +113,28 @@ func mustEnv(key string) string {
 	return v
 }
 
+// buildProvider chains the configured quote providers in order behind priceCache.
+// Unknown names are skipped; Yahoo is used if nothing valid is configured.
+func buildProvider(names []string, yahoo *finance.YahooClient, breaker finance.BreakerConfig, priceCache *finance.PriceCache) *finance.FallbackProvider {
+	var providers []finance.QuoteProvider
+	for _, name := range names {
+		switch name {
+		case "yahoo":
+			providers = append(providers, yahoo)
+		case "stooq":
+			providers = append(providers, finance.NewStooqClient("", breaker))
+		default:
+			log.Printf("unknown quote provider %q, skipping", name)
+		}
+	}
+	if len(providers) == 0 {
+		providers = append(providers, yahoo)
+	}
+	provider := finance.NewFallbackProvider(providers...)
+	provider.SetCache(priceCache)
+	return provider
+}
+
 func main() {
 	cfg := loadConfig()
 
@@ -69,14 +144,34 @@ func main() {
 	}
 	defer func() { _ = database.Close() }()
 
+	repo := db.NewRepository(database)
+
 	priceCache := finance.NewPriceCache(cfg.CacheTTL)
 	rateCache := finance.NewExchangeRateCache(cfg.CacheTTL)
-	yahooClient := finance.NewYahooClient(priceCache)
 
-	repo := db.NewRepository(database)
-	svc := portfolio.NewService(repo, yahooClient, rateCache)
+	cacheStore := portfolio.NewCacheStore(repo)
+	if n, err := cacheStore.Restore(priceCache, rateCache); err != nil {
+		log.Printf("restore price cache: %v", err)
+	} else {
+		log.Printf("restored %d persisted quotes", n)
+	}
+	priceCache.SetStore(cacheStore)
+	rateCache.SetStore(cacheStore)
+
+	yahooClient := finance.NewYahooClient(cfg.CacheTTL, cfg.RateLimit, cfg.Breaker)
+	if cfg.YahooBaseURL != "" {
+		yahooClient.SetEndpoints(finance.YahooEndpointsAt(cfg.YahooBaseURL))
+		log.Printf("yahoo endpoints: %s", cfg.YahooBaseURL)
+	}
+	if err := yahooClient.SetSessionStore(portfolio.NewSessionStore(repo)); err != nil {
+		log.Printf("restore yahoo session: %v", err)
+	}
+	provider := buildProvider(cfg.QuoteProviders, yahooClient, cfg.Breaker, priceCache)
+	log.Printf("quote providers: %s", provider.Name())
+
+	svc := portfolio.NewService(repo, provider, rateCache)
 
-	tgBot, err := bot.New(cfg.TelegramToken, svc, yahooClient)
+	tgBot, err := bot.New(cfg.TelegramToken, svc, cfg.AdminChatIDs)
 	if err != nil {
 		log.Fatalf("bot init: %v", err)
 	}
@@ -88,5 +183,7 @@ func main() {
 	defer cancel()
 
 	go sched.Run(ctx)
+	go provider.RunRefresh(ctx)
+	go yahooClient.RunSessionRefresh(ctx)
 	tgBot.Start(ctx)
 }
@@ -3,10 +3,10 @@ package bot
 import (
 	"context"
 	"log"
+	"runtime/debug"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
-	"stock-portfolio-bot/internal/finance"
 	"stock-portfolio-bot/internal/portfolio"
 )
 
@@ -20,13 +20,20 @@ type Bot struct {
 var botCommands = []tgbotapi.BotCommand{
 	{Command: "b", Description: "Show total balance"},
 	{Command: "p", Description: "Show full portfolio details"},
+	{Command: "d", Description: "Show dividend income and upcoming ex-dates"},
+	{Command: "info", Description: "Show fundamentals for a symbol"},
+	{Command: "news", Description: "Show headlines for your holdings"},
+	{Command: "exchanges", Description: "Set preferred exchanges for search"},
+	{Command: "currency", Description: "Set the currency your portfolio is valued in"},
+	{Command: "cost", Description: "Set the cost basis method (FIFO, LIFO, average)"},
+	{Command: "portfolios", Description: "List, create, switch, rename or delete portfolios"},
 	{Command: "r", Description: "Remove a holding from your portfolio"},
 	{Command: "h", Description: "Show usage instructions"},
 	{Command: "start", Description: "Welcome message and reset state"},
 }
 
-// New creates a Bot, verifying the token with Telegram.
-func New(token string, svc *portfolio.Service, yahoo *finance.YahooClient) (*Bot, error) {
+// New creates a Bot, verifying the token with Telegram. adminChatIDs may use /health.
+func New(token string, svc *portfolio.Service, adminChatIDs []int64) (*Bot, error) {
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, err
@@ -37,7 +44,7 @@ func New(token string, svc *portfolio.Service, yahoo *finance.YahooClient) (*Bot
 		log.Printf("set bot commands: %v", err)
 	}
 
-	h := newHandler(api, svc, yahoo)
+	h := newHandler(api, svc, adminChatIDs)
 	return &Bot{api: api, handler: h}, nil
 }
 
@@ -72,6 +79,13 @@ func (b *Bot) SendMarkdown(chatID int64, text string) {
 }
 
 func (b *Bot) dispatch(ctx context.Context, update tgbotapi.Update) {
+	// A bug in one handler must not take the whole bot down.
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic handling update %d: %v\n%s", update.UpdateID, r, debug.Stack())
+		}
+	}()
+
 	switch {
 	case update.Message != nil:
 		b.handler.HandleMessage(ctx, update.Message)
@@ -3,10 +3,13 @@ package bot
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
@@ -18,33 +21,46 @@ import (
 const welcomeText = `Welcome! I'm your stock portfolio assistant 📈
 
 Here's what I can do:
-• Send me a ticker symbol or company name — I'll look it up
+• Send me a ticker symbol, company name, ISIN or CUSIP — I'll look it up
 • Select the right match from the list
-• Tell me how many shares you own
+• Tell me how many shares you bought or sold, with the price and date if you like
 • I'll track prices and notify you every hour with your total balance
 
 Commands:
 • /b — Show total balance
 • /p — Show full portfolio details
+• /d — Show dividends received and upcoming ex-dates
+• /info SYMBOL — Show market cap, P/E, dividend yield and sector
+• /news — Show recent headlines for your holdings (/news on|off for alerts)
+• /exchanges — Set exchanges to list first in search results (e.g. /exchanges XETRA, LSE)
+• /currency — Set the currency your portfolio is valued in (e.g. /currency EUR)
+• /cost — Set how cost basis is computed: fifo, lifo or average
+• /portfolios — List your portfolios; new, use, rename or delete one (e.g. /portfolios new Retirement)
 • /r — Remove a holding
 • /h — Show usage instructions
 
 Let's start — send me a ticker symbol or company name!`
 
+const unknownCommandText = "Unknown command. Use /b, /p, /d, /info, /news, /exchanges, /currency, /cost, /portfolios, /r, or /h."
+
 // Handler processes Telegram messages and callbacks using a per-user FSM.
 type Handler struct {
-	api   *tgbotapi.BotAPI
-	svc   *portfolio.Service
-	yahoo *finance.YahooClient
-	repo  *db.Repository
+	api    *tgbotapi.BotAPI
+	svc    *portfolio.Service
+	repo   *db.Repository
+	admins map[int64]struct{}
 }
 
-func newHandler(api *tgbotapi.BotAPI, svc *portfolio.Service, yahoo *finance.YahooClient) *Handler {
+func newHandler(api *tgbotapi.BotAPI, svc *portfolio.Service, adminChatIDs []int64) *Handler {
+	admins := make(map[int64]struct{}, len(adminChatIDs))
+	for _, id := range adminChatIDs {
+		admins[id] = struct{}{}
+	}
 	return &Handler{
-		api:   api,
-		svc:   svc,
-		yahoo: yahoo,
-		repo:  svc.Repo(),
+		api:    api,
+		svc:    svc,
+		repo:   svc.Repo(),
+		admins: admins,
 	}
 }
 
@@ -97,8 +113,19 @@ func (h *Handler) HandleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery
 		h.handleTickerSelect(ctx, chatID, symbol)
 
 	case strings.HasPrefix(data, "remove:"):
-		symbol := strings.TrimPrefix(data, "remove:")
-		h.handleRemove(ctx, chatID, symbol)
+		if portfolioID, symbol, ok := positionCallback(data); ok {
+			h.handleRemove(ctx, chatID, portfolioID, symbol)
+		}
+
+	case strings.HasPrefix(data, "close:"):
+		if portfolioID, symbol, ok := positionCallback(data); ok {
+			h.handleClose(ctx, chatID, portfolioID, symbol)
+		}
+
+	case strings.HasPrefix(data, "erase:"):
+		if portfolioID, symbol, ok := positionCallback(data); ok {
+			h.handleErase(ctx, chatID, portfolioID, symbol)
+		}
 	}
 }
 
@@ -117,47 +144,320 @@ func (h *Handler) handleCommand(ctx context.Context, msg *tgbotapi.Message) {
 	case "p":
 		h.handlePortfolio(ctx, chatID)
 
+	case "d":
+		h.handleDividends(ctx, chatID)
+
+	case "info":
+		h.handleInfo(ctx, chatID, msg.CommandArguments())
+
+	case "news":
+		h.handleNews(ctx, chatID, msg.CommandArguments())
+
+	case "exchanges":
+		h.handleExchanges(chatID, msg.CommandArguments())
+
+	case "currency":
+		h.handleCurrency(ctx, chatID, msg.CommandArguments())
+
+	case "cost":
+		h.handleCostMethod(chatID, msg.CommandArguments())
+
+	case "portfolios":
+		h.handlePortfolios(ctx, chatID, msg.CommandArguments())
+
 	case "r":
 		h.handleRemoveMenu(ctx, chatID)
 
 	case "h":
 		h.sendText(chatID, welcomeText)
 
+	case "health":
+		if _, ok := h.admins[chatID]; !ok {
+			h.sendText(chatID, unknownCommandText)
+			return
+		}
+		h.sendMarkdown(chatID, portfolio.FormatHealth(h.svc.ProviderHealth()))
+
 	default:
-		h.sendText(chatID, "Unknown command. Use /b, /p, /r, or /h.")
+		h.sendText(chatID, unknownCommandText)
 	}
 }
 
 func (h *Handler) handleBalance(ctx context.Context, chatID int64) {
-	report, err := h.svc.ComputeBalance(ctx, chatID)
+	balance, err := h.svc.ComputeUserBalance(ctx, chatID)
 	if err != nil {
 		log.Printf("compute balance %d: %v", chatID, err)
-		h.sendText(chatID, "Failed to fetch prices. Please try again later.")
+		h.sendText(chatID, fetchErrorText(err))
 		return
 	}
-	if report == nil || len(report.Holdings) == 0 {
+	if balance == nil {
 		h.sendText(chatID, "Your portfolio is empty. Send me a ticker symbol to get started!")
 		return
 	}
-	h.sendMarkdown(chatID, report.FormatSummary())
+	h.sendMarkdown(chatID, balance.FormatSummary()+missingText(balance.Missing()))
 }
 
 func (h *Handler) handlePortfolio(ctx context.Context, chatID int64) {
 	report, err := h.svc.ComputeBalance(ctx, chatID)
 	if err != nil {
 		log.Printf("compute balance %d: %v", chatID, err)
-		h.sendText(chatID, "Failed to fetch prices. Please try again later.")
+		h.sendText(chatID, fetchErrorText(err))
 		return
 	}
 	if report == nil || len(report.Holdings) == 0 {
 		h.sendText(chatID, "Your portfolio is empty. Send me a ticker symbol to get started!")
 		return
 	}
+	h.sendMarkdown(chatID, report.Format()+missingText(report.Missing))
+}
+
+func (h *Handler) handleDividends(ctx context.Context, chatID int64) {
+	report, err := h.svc.ComputeDividends(ctx, chatID)
+	if err != nil {
+		log.Printf("compute dividends %d: %v", chatID, err)
+		h.sendText(chatID, "Failed to load dividends. Please try again later.")
+		return
+	}
+	if report == nil {
+		h.sendText(chatID, "Your portfolio is empty. Send me a ticker symbol to get started!")
+		return
+	}
 	h.sendMarkdown(chatID, report.Format())
 }
 
+func (h *Handler) handleInfo(ctx context.Context, chatID int64, args string) {
+	symbol := strings.ToUpper(strings.TrimSpace(args))
+	if symbol == "" {
+		h.sendText(chatID, "Usage: /info SYMBOL (e.g. /info AAPL)")
+		return
+	}
+
+	f, err := h.svc.GetFundamentals(ctx, symbol)
+	if err != nil {
+		log.Printf("get fundamentals %s: %v", symbol, err)
+		switch {
+		case errors.Is(err, finance.ErrSymbolNotFound):
+			h.sendText(chatID, fmt.Sprintf("%s was not found. Check the symbol and try again.", symbol))
+		case errors.Is(err, finance.ErrUnsupported):
+			h.sendText(chatID, "Fundamentals are not available from the configured price providers.")
+		default:
+			h.sendText(chatID, "Failed to load fundamentals. Please try again later.")
+		}
+		return
+	}
+	h.sendMarkdown(chatID, portfolio.FormatFundamentals(f))
+}
+
+func (h *Handler) handleNews(ctx context.Context, chatID int64, args string) {
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "on":
+		if err := h.svc.SetNewsSubscription(chatID, true); err != nil {
+			log.Printf("subscribe news %d: %v", chatID, err)
+			h.sendText(chatID, "Failed to turn on news alerts. Please try again.")
+			return
+		}
+		h.sendText(chatID, "🔔 News alerts on. I'll send new headlines for your holdings as they appear.")
+		return
+	case "off":
+		if err := h.svc.SetNewsSubscription(chatID, false); err != nil {
+			log.Printf("unsubscribe news %d: %v", chatID, err)
+			h.sendText(chatID, "Failed to turn off news alerts. Please try again.")
+			return
+		}
+		h.sendText(chatID, "🔕 News alerts off.")
+		return
+	case "":
+	default:
+		h.sendText(chatID, "Usage: /news, /news on or /news off")
+		return
+	}
+
+	report, err := h.svc.ComputeNews(ctx, chatID)
+	if err != nil {
+		log.Printf("compute news %d: %v", chatID, err)
+		h.sendText(chatID, "Failed to load news. Please try again later.")
+		return
+	}
+	if report == nil {
+		h.sendText(chatID, "Your portfolio is empty. Send me a ticker symbol to get started!")
+		return
+	}
+
+	text := report.Format()
+	if subscribed, err := h.repo.IsNewsSubscribed(chatID); err != nil {
+		log.Printf("get news subscription %d: %v", chatID, err)
+	} else if !subscribed {
+		text += "\n\n_Get new headlines pushed to you with /news on_"
+	}
+	h.sendMarkdownNoPreview(chatID, text)
+}
+
+func (h *Handler) handleExchanges(chatID int64, args string) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		exchanges, err := h.svc.PreferredExchanges(chatID)
+		if err != nil {
+			log.Printf("get preferred exchanges %d: %v", chatID, err)
+			h.sendText(chatID, "Failed to load your settings. Please try again.")
+			return
+		}
+		h.sendText(chatID, portfolio.FormatExchanges(exchanges)+
+			"\n\nSet with /exchanges XETRA, LSE or clear with /exchanges clear.")
+		return
+	}
+
+	var exchanges []string
+	if !strings.EqualFold(args, "clear") {
+		exchanges = portfolio.ParseExchanges(args)
+	}
+	if err := h.svc.SetPreferredExchanges(chatID, exchanges); err != nil {
+		log.Printf("set preferred exchanges %d: %v", chatID, err)
+		h.sendText(chatID, "Failed to save your settings. Please try again.")
+		return
+	}
+	h.sendText(chatID, "✅ "+portfolio.FormatExchanges(exchanges))
+}
+
+func (h *Handler) handleCurrency(ctx context.Context, chatID int64, args string) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		currency, err := h.svc.BaseCurrency(chatID)
+		if err != nil {
+			log.Printf("get base currency %d: %v", chatID, err)
+			h.sendText(chatID, "Failed to load your settings. Please try again.")
+			return
+		}
+		h.sendText(chatID, portfolio.FormatBaseCurrency(currency)+
+			"\n\nChange with /currency EUR (any ISO currency code).")
+		return
+	}
+
+	currency, err := h.svc.SetBaseCurrency(ctx, chatID, args)
+	if err != nil {
+		log.Printf("set base currency %d: %v", chatID, err)
+		switch {
+		case errors.Is(err, portfolio.ErrUnknownCurrency) && errors.Is(err, finance.ErrCircuitOpen):
+			h.sendText(chatID, "Exchange rates are temporarily unavailable. Please try again later.")
+		case errors.Is(err, portfolio.ErrUnknownCurrency):
+			h.sendText(chatID, fmt.Sprintf("No exchange rate found for %q. Use an ISO code such as EUR or GBP.", args))
+		default:
+			h.sendText(chatID, "Failed to save your settings. Please try again.")
+		}
+		return
+	}
+
+	// Totals are now in another currency; start the scheduler's comparison afresh.
+	if _, _, err := h.svc.ResetBaseline(ctx, chatID); err != nil {
+		log.Printf("reset baseline %d: %v", chatID, err)
+	}
+	h.sendText(chatID, "✅ "+portfolio.FormatBaseCurrency(currency))
+}
+
+func (h *Handler) handleCostMethod(chatID int64, args string) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		method, err := h.svc.CostMethod(chatID)
+		if err != nil {
+			log.Printf("get cost method %d: %v", chatID, err)
+			h.sendText(chatID, "Failed to load your settings. Please try again.")
+			return
+		}
+		h.sendText(chatID, portfolio.FormatCostMethod(method)+
+			"\n\nChange with /cost fifo, /cost lifo or /cost average.")
+		return
+	}
+
+	method, err := h.svc.SetCostMethod(chatID, args)
+	if err != nil {
+		log.Printf("set cost method %d: %v", chatID, err)
+		if errors.Is(err, portfolio.ErrUnknownCostMethod) {
+			h.sendText(chatID, "Use /cost fifo, /cost lifo or /cost average.")
+			return
+		}
+		h.sendText(chatID, "Failed to save your settings. Please try again.")
+		return
+	}
+	h.sendText(chatID, "✅ "+portfolio.FormatCostMethod(method))
+}
+
+func (h *Handler) handlePortfolios(ctx context.Context, chatID int64, args string) {
+	action, name, _ := strings.Cut(strings.TrimSpace(args), " ")
+	var (
+		text string
+		err  error
+	)
+	switch strings.ToLower(action) {
+	case "":
+		var portfolios []db.Portfolio
+		var active db.Portfolio
+		if portfolios, err = h.svc.Portfolios(chatID); err == nil {
+			if active, err = h.svc.ActivePortfolio(chatID); err == nil {
+				text = portfolio.FormatPortfolios(portfolios, active) +
+					"\n\nManage them with /portfolios new, use, rename or delete NAME."
+			}
+		}
+
+	case "new":
+		var p db.Portfolio
+		if p, err = h.svc.CreatePortfolio(chatID, name); err == nil {
+			text = fmt.Sprintf("✅ Created %s. New trades now go to it; /portfolios use NAME switches back.", p.Name)
+		}
+
+	case "use":
+		var p db.Portfolio
+		if p, err = h.svc.UsePortfolio(chatID, name); err == nil {
+			text = fmt.Sprintf("✅ Now working in %s. Trades, /p, /d and /r apply to it.", p.Name)
+		}
+
+	case "rename":
+		var p db.Portfolio
+		if p, err = h.svc.RenamePortfolio(chatID, name); err == nil {
+			text = fmt.Sprintf("✅ Renamed your current portfolio to %s.", p.Name)
+		}
+
+	case "delete":
+		var deleted, active db.Portfolio
+		if deleted, active, err = h.svc.DeletePortfolio(chatID, name); err == nil {
+			text = fmt.Sprintf("✅ Deleted %s with its holdings and trades. Now working in %s.", deleted.Name, active.Name)
+			// The combined total lost a portfolio; start the scheduler's comparison afresh.
+			if _, _, err := h.svc.ResetBaseline(ctx, chatID); err != nil {
+				log.Printf("reset baseline %d: %v", chatID, err)
+			}
+		}
+
+	default:
+		h.sendText(chatID, "Use /portfolios, or /portfolios new, use, rename or delete followed by a name.")
+		return
+	}
+
+	if err != nil {
+		log.Printf("portfolios %q %d: %v", action, chatID, err)
+		switch {
+		case errors.Is(err, portfolio.ErrPortfolioNotFound):
+			h.sendText(chatID, fmt.Sprintf("You have no portfolio called %q. See /portfolios.", strings.TrimSpace(name)))
+		case errors.Is(err, portfolio.ErrPortfolioExists):
+			h.sendText(chatID, "You already have a portfolio with that name.")
+		case errors.Is(err, portfolio.ErrInvalidPortfolioName):
+			h.sendText(chatID, fmt.Sprintf("Please give a name of up to %d characters, e.g. /portfolios %s Retirement.",
+				portfolio.MaxPortfolioName, strings.ToLower(action)))
+		case errors.Is(err, portfolio.ErrLastPortfolio):
+			h.sendText(chatID, "You can't delete your only portfolio. Remove its holdings with /r instead.")
+		default:
+			h.sendText(chatID, "Failed to update your portfolios. Please try again.")
+		}
+		return
+	}
+	h.sendText(chatID, text)
+}
+
 func (h *Handler) handleRemoveMenu(ctx context.Context, chatID int64) {
-	holdings, err := h.repo.GetHoldings(chatID)
+	p, err := h.svc.ActivePortfolio(chatID)
+	if err != nil {
+		log.Printf("get active portfolio %d: %v", chatID, err)
+		h.sendText(chatID, "Failed to load your portfolio. Please try again.")
+		return
+	}
+	holdings, err := h.repo.GetHoldings(p.ID)
 	if err != nil || len(holdings) == 0 {
 		h.sendText(chatID, "Your portfolio is empty.")
 		return
@@ -166,11 +466,12 @@ func (h *Handler) handleRemoveMenu(ctx context.Context, chatID int64) {
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for _, holding := range holdings {
 		label := fmt.Sprintf("❌ %s — %s", holding.Symbol, holding.Name)
-		btn := tgbotapi.NewInlineKeyboardButtonData(label, "remove:"+holding.Symbol)
+		data := fmt.Sprintf("remove:%d:%s", p.ID, holding.Symbol)
+		btn := tgbotapi.NewInlineKeyboardButtonData(label, data)
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(btn))
 	}
 
-	msg := tgbotapi.NewMessage(chatID, "Select a holding to remove:")
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Select a holding to remove from %s:", p.Name))
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 	if _, err := h.api.Send(msg); err != nil {
 		log.Printf("send remove menu %d: %v", chatID, err)
@@ -185,7 +486,15 @@ func (h *Handler) handleTickerSearch(ctx context.Context, chatID int64, query st
 		return
 	}
 
-	results, err := h.yahoo.SearchTickers(ctx, query)
+	results, err := h.svc.SearchTickers(ctx, chatID, query)
+	if errors.Is(err, finance.ErrInvalidIdentifier) {
+		h.sendText(chatID, "That looks like an ISIN or CUSIP, but its check digit is wrong. Please check it and try again.")
+		return
+	}
+	if errors.Is(err, finance.ErrUnsupportedIdentifier) {
+		h.sendText(chatID, "That looks like a CINS number, which can't be searched for. Please send the ISIN or ticker instead.")
+		return
+	}
 	if err != nil {
 		log.Printf("search tickers %q: %v", query, err)
 		h.sendText(chatID, "Search failed. Please try again.")
@@ -198,7 +507,11 @@ func (h *Handler) handleTickerSearch(ctx context.Context, chatID int64, query st
 
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for _, r := range results {
-		label := fmt.Sprintf("%s — %s (%s)", r.Symbol, r.Name, r.Exchange)
+		exchange := r.ExchangeName
+		if exchange == "" {
+			exchange = r.Exchange
+		}
+		label := fmt.Sprintf("%s — %s (%s, %s)", r.Symbol, r.Name, finance.TypeLabel(r.Type), exchange)
 		btn := tgbotapi.NewInlineKeyboardButtonData(label, "select:"+r.Symbol)
 		rows = append(rows, tgbotapi.NewInlineKeyboardRow(btn))
 	}
@@ -223,22 +536,26 @@ func (h *Handler) handleTickerSelect(ctx context.Context, chatID int64, symbol s
 		return
 	}
 
-	// Recover the name from the stored search results.
+	// Recover the name and type from the stored search results.
 	var results []finance.TickerResult
 	name := symbol // fallback
+	quoteType := finance.GuessType(symbol)
 	if err := json.Unmarshal([]byte(stateData), &results); err == nil {
 		for _, r := range results {
 			if r.Symbol == symbol {
 				name = r.Name
+				quoteType = r.Type
 				break
 			}
 		}
 	}
 
-	pending := struct {
-		Symbol string `json:"symbol"`
-		Name   string `json:"name"`
-	}{Symbol: symbol, Name: name}
+	if finance.WatchOnly(quoteType) {
+		h.handleWatchSelect(ctx, chatID, symbol, name)
+		return
+	}
+
+	pending := pendingHolding{Symbol: symbol, Name: name, Type: quoteType}
 
 	pendingJSON, _ := json.Marshal(pending)
 	if err := h.repo.SetUserState(chatID, "awaiting_shares", string(pendingJSON)); err != nil {
@@ -246,23 +563,56 @@ func (h *Handler) handleTickerSelect(ctx context.Context, chatID int64, symbol s
 		return
 	}
 
+	var question string
+	switch quoteType {
+	case finance.TypeFuture:
+		question = "How many contracts did you buy?"
+		if mult, ok := finance.ContractMultiplier(symbol); ok {
+			question += fmt.Sprintf(" (1 contract = %g units of the quoted price)", mult)
+		}
+	case finance.TypeCrypto, finance.TypeCurrency:
+		question = "How many units did you buy? (fractions are supported)"
+	default:
+		question = "How many shares did you buy? (fractional shares are supported)"
+	}
+	h.sendText(chatID, fmt.Sprintf("You selected *%s* (%s).\n\n%s\n\n%s", symbol, name, question, tradeInputHelp))
+}
+
+// tradeInputHelp explains the awaiting_shares input format.
+const tradeInputHelp = "Reply with the quantity, optionally followed by the price, date and fees, e.g.\n" +
+	"10\n10 @ 152.30\n10 @ 152.30 on 2024-03-01 fee 1.50\nsell 5 @ 160\n" +
+	"Without a price, today's quote is used."
+
+// handleWatchSelect adds a watch-only instrument (an index) with zero shares.
+func (h *Handler) handleWatchSelect(ctx context.Context, chatID int64, symbol, name string) {
+	p, err := h.svc.ActivePortfolio(chatID)
+	if err != nil {
+		log.Printf("get active portfolio %d: %v", chatID, err)
+		h.sendText(chatID, "Failed to save. Please try again.")
+		return
+	}
+	if err := h.repo.AddWatchHolding(p.ID, symbol, name); err != nil {
+		log.Printf("upsert watch %d %s: %v", chatID, symbol, err)
+		h.sendText(chatID, "Failed to save. Please try again.")
+		return
+	}
+	if err := h.repo.SetUserState(chatID, "idle", ""); err != nil {
+		log.Printf("reset user state %d: %v", chatID, err)
+	}
 	h.sendText(chatID, fmt.Sprintf(
-		"You selected *%s* (%s).\n\nHow many shares do you own? (fractional shares are supported)",
+		"👁 Watching %s (%s). Indices can't be held, so it's shown in /p without counting towards your total.",
 		symbol, name,
 	))
 }
 
 func (h *Handler) handleSharesInput(ctx context.Context, chatID int64, text, stateData string) {
-	shares, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
-	if err != nil || shares <= 0 {
-		h.sendText(chatID, "Please enter a valid positive number of shares (e.g. 10 or 2.5).")
+	trade, err := portfolio.ParseTrade(text, time.Now())
+	if err != nil {
+		h.sendText(chatID, fmt.Sprintf("Couldn't read that (%v).\n\n%s", err, tradeInputHelp))
 		return
 	}
 
-	var pending struct {
-		Symbol string `json:"symbol"`
-		Name   string `json:"name"`
-	}
+	var pending pendingHolding
 	if err := json.Unmarshal([]byte(stateData), &pending); err != nil {
 		log.Printf("unmarshal pending state %d: %v", chatID, err)
 		h.sendText(chatID, "Something went wrong. Please start over by sending a ticker symbol.")
@@ -270,35 +620,62 @@ func (h *Handler) handleSharesInput(ctx context.Context, chatID int64, text, sta
 		return
 	}
 
-	if err := h.repo.UpsertHolding(chatID, pending.Symbol, pending.Name, shares); err != nil {
-		log.Printf("upsert holding %d %s: %v", chatID, pending.Symbol, err)
-		h.sendText(chatID, "Failed to save holding. Please try again.")
+	result, err := h.svc.RecordTrade(ctx, chatID, pending.Symbol, pending.Name, trade)
+	switch {
+	case errors.Is(err, portfolio.ErrInvalidTrade):
+		h.sendText(chatID, fmt.Sprintf("Couldn't record that (%v).\n\n%s", err, tradeInputHelp))
+		return
+	case errors.Is(err, portfolio.ErrPriceRequired):
+		h.sendText(chatID, "Please include the price for a past trade, e.g. 10 @ 152.30 on 2024-03-01.")
+		return
+	case errors.Is(err, portfolio.ErrStaleQuote):
+		h.sendText(chatID, fmt.Sprintf("The latest %s price is out of date, so please include the price you traded at, "+
+			"e.g. 10 @ 152.30.", pending.Symbol))
+		return
+	case errors.Is(err, portfolio.ErrTradeBeforeSplit):
+		h.sendText(chatID, fmt.Sprintf("Couldn't record that (%v). Please enter it at the post-split quantity "+
+			"and price, dated on or after the split.", err))
+		return
+	case errors.Is(err, db.ErrInsufficientShares):
+		h.sendText(chatID, fmt.Sprintf("You can't sell more %s than you held at the time. Please check the quantity and date.",
+			finance.UnitLabel(pending.Type)))
+		return
+	case err != nil:
+		log.Printf("record trade %d %s: %v", chatID, pending.Symbol, err)
+		h.sendText(chatID, "Failed to save the trade. Please try again.")
 		return
 	}
+	saved := result.Format(pending.Name, pending.Type)
 
 	if err := h.repo.SetUserState(chatID, "idle", ""); err != nil {
 		log.Printf("reset user state %d: %v", chatID, err)
 	}
 
+	// Load dividend history now so /d is useful before the daily sync.
+	if _, err := h.svc.SyncEvents(ctx, []string{pending.Symbol}); err != nil {
+		log.Printf("sync events %s: %v", pending.Symbol, err)
+	}
+	if err := h.svc.SyncSymbolInfo(ctx, []string{pending.Symbol}); err != nil {
+		log.Printf("sync symbol info %s: %v", pending.Symbol, err)
+	}
+
 	// Reset baseline to ensure next scheduler report only shows performance changes.
-	report, prevTotal, err := h.svc.ResetBaseline(ctx, chatID)
+	balance, prevTotal, err := h.svc.ResetBaseline(ctx, chatID)
 	if err != nil {
 		log.Printf("reset baseline %d: %v", chatID, err)
-		h.sendText(chatID, fmt.Sprintf(
-			"✅ Saved: %.4f shares of %s (%s).\n\n(Could not compute balance. Use /b to check later.)",
-			shares, pending.Symbol, pending.Name,
-		))
+		h.sendText(chatID, saved+"\n\n(Could not compute balance. Use /b to check later.)")
+		return
+	}
+	if balance == nil {
+		h.sendText(chatID, saved)
 		return
 	}
 
 	// Build confirmation message with balance and change info.
-	msg := fmt.Sprintf(
-		"✅ Saved: %.4f shares of %s (%s).\n\n💰 *Total: $%.2f*",
-		shares, pending.Symbol, pending.Name, report.TotalUSD,
-	)
+	msg := fmt.Sprintf("%s\n\n%s", saved, balance.FormatTotal())
 
-	if prevTotal > 0 {
-		change := (report.TotalUSD - prevTotal) / prevTotal * 100
+	if prevTotal.Sign() > 0 {
+		change := balance.Total.Sub(prevTotal).Float64() / prevTotal.Float64() * 100
 		sign := "+"
 		if change < 0 {
 			sign = ""
@@ -311,17 +688,167 @@ func (h *Handler) handleSharesInput(ctx context.Context, chatID int64, text, sta
 	h.sendMarkdown(chatID, msg)
 }
 
-func (h *Handler) handleRemove(ctx context.Context, chatID int64, symbol string) {
-	if err := h.repo.DeleteHolding(chatID, symbol); err != nil {
-		log.Printf("delete holding %d %s: %v", chatID, symbol, err)
+// handleRemove asks how to remove a held position: closing it keeps the ledger and
+// realized gains, erasing deletes them. Watch-only holdings have neither and are
+// removed straight away.
+func (h *Handler) handleRemove(ctx context.Context, chatID, portfolioID int64, symbol string) {
+	holdings, err := h.repo.GetHoldings(portfolioID)
+	if err != nil {
+		log.Printf("get holdings %d/%d: %v", chatID, portfolioID, err)
+		h.sendText(chatID, "Failed to remove holding. Please try again.")
+		return
+	}
+	for _, holding := range holdings {
+		if holding.Symbol != symbol || holding.ChatID != chatID {
+			continue
+		}
+		if holding.Shares.Sign() <= 0 {
+			h.handleErase(ctx, chatID, portfolioID, symbol)
+			return
+		}
+
+		key := fmt.Sprintf("%d:%s", portfolioID, symbol)
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"How should %s %s of %s be removed?\n\n"+
+				"💰 Close position records a sale of all of them at the current price. "+
+				"Your trades and the gains realized on them are kept.\n\n"+
+				"🗑 Erase deletes the holding with its whole trade history, as if it had never been entered. "+
+				"Realized gains from it are lost.",
+			holding.Shares, finance.UnitLabel(finance.GuessType(symbol)), symbol))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("💰 Close position", "close:"+key)),
+			tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("🗑 Erase with history", "erase:"+key)),
+		)
+		if _, err := h.api.Send(msg); err != nil {
+			log.Printf("send remove confirmation %d: %v", chatID, err)
+		}
+		return
+	}
+	h.sendText(chatID, fmt.Sprintf("%s is no longer in your portfolio.", symbol))
+}
+
+// handleClose sells the whole position at the current price, keeping its ledger.
+func (h *Handler) handleClose(ctx context.Context, chatID, portfolioID int64, symbol string) {
+	result, holding, err := h.svc.ClosePosition(ctx, chatID, portfolioID, symbol)
+	switch {
+	case errors.Is(err, portfolio.ErrHoldingNotFound):
+		h.sendText(chatID, fmt.Sprintf("%s is no longer in your portfolio.", symbol))
+		return
+	case err != nil:
+		log.Printf("close position %d/%d %s: %v", chatID, portfolioID, symbol, err)
+		h.sendText(chatID, fmt.Sprintf(
+			"Couldn't get a price for %s to close the position. Please try again later.", symbol))
+		return
+	}
+	h.resetBaseline(ctx, chatID)
+	h.sendText(chatID, result.Format(holding.Name, finance.GuessType(symbol)))
+}
+
+// handleErase deletes a holding together with its ledger.
+func (h *Handler) handleErase(ctx context.Context, chatID, portfolioID int64, symbol string) {
+	if err := h.repo.DeleteHolding(chatID, portfolioID, symbol); err != nil {
+		log.Printf("delete holding %d/%d %s: %v", chatID, portfolioID, symbol, err)
 		h.sendText(chatID, "Failed to remove holding. Please try again.")
 		return
 	}
+	h.resetBaseline(ctx, chatID)
 	h.sendText(chatID, fmt.Sprintf("✅ Removed %s from your portfolio.", symbol))
 }
 
+// resetBaseline starts the scheduler's comparison afresh after the portfolio's
+// composition changed.
+func (h *Handler) resetBaseline(ctx context.Context, chatID int64) {
+	if _, _, err := h.svc.ResetBaseline(ctx, chatID); err != nil {
+		log.Printf("reset baseline %d: %v", chatID, err)
+	}
+}
+
 // --- helpers ---
 
+// pendingHolding is the awaiting_shares state payload.
+type pendingHolding struct {
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+	Type   string `json:"type,omitempty"`
+}
+
+// positionCallback parses callback data of the form ACTION:PORTFOLIO_ID:SYMBOL.
+func positionCallback(data string) (portfolioID int64, symbol string, ok bool) {
+	_, rest, _ := strings.Cut(data, ":")
+	id, symbol, found := strings.Cut(rest, ":")
+	portfolioID, err := strconv.ParseInt(id, 10, 64)
+	if !found || err != nil || symbol == "" {
+		log.Printf("bad position callback %q", data)
+		return 0, "", false
+	}
+	return portfolioID, symbol, true
+}
+
+// fetchErrorText maps a price fetch failure to a message for the user.
+func fetchErrorText(err error) string {
+	var batchErr *finance.BatchError
+	if errors.As(err, &batchErr) && len(batchErr.Failures) > 0 {
+		symbols := make([]string, 0, len(batchErr.Failures))
+		for sym := range batchErr.Failures {
+			symbols = append(symbols, sym)
+		}
+		sort.Strings(symbols)
+
+		var sb strings.Builder
+		sb.WriteString("Could not price your holdings:")
+		for _, sym := range symbols {
+			sb.WriteString("\n• " + symbolErrorText(sym, batchErr.Failures[sym]))
+		}
+		return sb.String()
+	}
+
+	switch {
+	case errors.Is(err, finance.ErrCircuitOpen):
+		return "The price service is down. Please try again in a few minutes."
+	case errors.Is(err, finance.ErrRateLimited):
+		return "The price service is rate limiting us. Please try again in a minute."
+	case errors.Is(err, finance.ErrUnauthorized):
+		return "The price service rejected our session. Please try again in a few minutes."
+	default:
+		return "Failed to fetch prices. Please try again later."
+	}
+}
+
+// symbolErrorText explains why a single symbol could not be priced.
+func symbolErrorText(symbol string, err error) string {
+	switch {
+	case errors.Is(err, finance.ErrSymbolNotFound):
+		return fmt.Sprintf("%s no longer exists. Remove it with /r.", symbol)
+	case errors.Is(err, finance.ErrCircuitOpen):
+		return fmt.Sprintf("%s: the price service is down, try again in a few minutes.", symbol)
+	case errors.Is(err, finance.ErrNoPrice):
+		return fmt.Sprintf("%s has no price data right now.", symbol)
+	case errors.Is(err, finance.ErrRateLimited):
+		return fmt.Sprintf("%s: the price service is rate limiting us, try again shortly.", symbol)
+	default:
+		return fmt.Sprintf("%s: price unavailable, try again later.", symbol)
+	}
+}
+
+// missingText lists holdings left out of a total, or returns "" if there are none.
+func missingText(missing map[string]error) string {
+	if len(missing) == 0 {
+		return ""
+	}
+	symbols := make([]string, 0, len(missing))
+	for sym := range missing {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	var sb strings.Builder
+	sb.WriteString("\n\n⚠️ Not included in the total:")
+	for _, sym := range symbols {
+		sb.WriteString("\n• " + symbolErrorText(sym, missing[sym]))
+	}
+	return sb.String()
+}
+
 func (h *Handler) sendText(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	if _, err := h.api.Send(msg); err != nil {
@@ -336,3 +863,13 @@ func (h *Handler) sendMarkdown(chatID int64, text string) {
 		log.Printf("send markdown %d: %v", chatID, err)
 	}
 }
+
+// sendMarkdownNoPreview sends Markdown without a link preview, for messages with many links.
+func (h *Handler) sendMarkdownNoPreview(chatID int64, text string) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeMarkdown
+	msg.DisableWebPagePreview = true
+	if _, err := h.api.Send(msg); err != nil {
+		log.Printf("send markdown %d: %v", chatID, err)
+	}
+}
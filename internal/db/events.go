@@ -0,0 +1,279 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"stock-portfolio-bot/internal/money"
+)
+
+// DividendRecord is a stored dividend, keyed by symbol and ex-dividend date.
+type DividendRecord struct {
+	Symbol   string
+	ExDate   time.Time
+	Amount   float64
+	Currency string
+}
+
+// SplitRecord is a stored stock split, keyed by symbol and date.
+type SplitRecord struct {
+	Symbol      string
+	Date        time.Time
+	Numerator   float64
+	Denominator float64
+}
+
+// SaveDividends upserts dividends; re-syncing the same events is a no-op.
+func (r *Repository) SaveDividends(records []DividendRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, d := range records {
+		if _, err := tx.Exec(`
+			INSERT INTO dividends (symbol, ex_date, amount, currency)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(symbol, ex_date) DO UPDATE SET
+				amount   = excluded.amount,
+				currency = excluded.currency`,
+			d.Symbol, d.ExDate.UTC(), d.Amount, d.Currency,
+		); err != nil {
+			return fmt.Errorf("upsert dividend %s: %w", d.Symbol, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveSplits inserts splits that are not stored yet and returns the ones that were new.
+func (r *Repository) SaveSplits(records []SplitRecord) ([]SplitRecord, error) {
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var added []SplitRecord
+	for _, s := range records {
+		res, err := tx.Exec(`
+			INSERT INTO splits (symbol, split_date, numerator, denominator)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(symbol, split_date) DO NOTHING`,
+			s.Symbol, s.Date.UTC(), s.Numerator, s.Denominator,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("insert split %s: %w", s.Symbol, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added = append(added, s)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// GetDividends returns the dividends for symbols with an ex-date at or after since, oldest first.
+func (r *Repository) GetDividends(symbols []string, since time.Time) ([]DividendRecord, error) {
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+
+	query, args := inClause(`
+		SELECT symbol, ex_date, amount, currency
+		FROM dividends
+		WHERE ex_date >= ? AND symbol IN (%s)
+		ORDER BY ex_date`, symbols, since.UTC())
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query dividends: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var records []DividendRecord
+	for rows.Next() {
+		var d DividendRecord
+		if err := rows.Scan(&d.Symbol, &d.ExDate, &d.Amount, &d.Currency); err != nil {
+			return nil, err
+		}
+		records = append(records, d)
+	}
+	return records, rows.Err()
+}
+
+// GetSplits returns the splits for symbol dated at or after since, oldest first.
+func (r *Repository) GetSplits(symbol string, since time.Time) ([]SplitRecord, error) {
+	rows, err := r.db.Query(`
+		SELECT symbol, split_date, numerator, denominator
+		FROM splits
+		WHERE symbol = ? AND split_date >= ?
+		ORDER BY split_date`, symbol, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query splits %s: %w", symbol, err)
+	}
+	return scanSplits(rows)
+}
+
+func scanSplits(rows *sql.Rows) ([]SplitRecord, error) {
+	defer func() { _ = rows.Close() }()
+
+	var records []SplitRecord
+	for rows.Next() {
+		var s SplitRecord
+		if err := rows.Scan(&s.Symbol, &s.Date, &s.Numerator, &s.Denominator); err != nil {
+			return nil, err
+		}
+		records = append(records, s)
+	}
+	return records, rows.Err()
+}
+
+// inClause expands the single %s in query to one placeholder per value and
+// returns the arguments with leading appended before the values.
+func inClause(query string, values []string, leading ...any) (string, []any) {
+	placeholders := make([]byte, 0, len(values)*2)
+	args := append([]any{}, leading...)
+	for i, v := range values {
+		if i > 0 {
+			placeholders = append(placeholders, ',')
+		}
+		placeholders = append(placeholders, '?')
+		args = append(args, v)
+	}
+	return fmt.Sprintf(query, placeholders), args
+}
+
+// SplitAdjustment records a holding whose share count was scaled by a split.
+type SplitAdjustment struct {
+	ChatID        int64
+	PortfolioID   int64
+	PortfolioName string
+	Symbol        string
+	SplitDate     time.Time
+	Ratio         string // e.g. "4:1"
+	OldShares     money.Decimal
+	NewShares     money.Decimal
+}
+
+// GetSplitsForHeldSymbols returns splits dated at or after since for symbols that
+// at least one user holds, oldest first.
+func (r *Repository) GetSplitsForHeldSymbols(since time.Time) ([]SplitRecord, error) {
+	rows, err := r.db.Query(`
+		SELECT symbol, split_date, numerator, denominator
+		FROM splits
+		WHERE split_date >= ? AND symbol IN (SELECT DISTINCT symbol FROM holdings)
+		ORDER BY split_date`, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query held splits: %w", err)
+	}
+	return scanSplits(rows)
+}
+
+// ApplySplit records split in the ledger of every position in split.Symbol that held
+// shares before the split date, updates the holdings and records each change in
+// holding_adjustments, all in one transaction. Positions already adjusted for this
+// split are left alone, so calling it repeatedly is safe. It returns the adjustments
+// made; their share counts are the whole position before and after.
+func (r *Repository) ApplySplit(split SplitRecord) ([]SplitAdjustment, error) {
+	if split.Numerator <= 0 || split.Denominator <= 0 {
+		return nil, fmt.Errorf("invalid split ratio %g:%g", split.Numerator, split.Denominator)
+	}
+	numerator, denominator := money.FromFloat(split.Numerator), money.FromFloat(split.Denominator)
+	ratio := fmt.Sprintf("%g:%g", split.Numerator, split.Denominator)
+	splitDate := split.Date.UTC()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query(`
+		SELECT DISTINCT p.chat_id, p.id, p.name
+		FROM transactions t JOIN portfolios p ON p.id = t.portfolio_id
+		WHERE t.symbol = ? AND t.trade_date < ?
+		  AND NOT EXISTS (
+			SELECT 1 FROM holding_adjustments a
+			WHERE a.portfolio_id = t.portfolio_id AND a.symbol = t.symbol
+			  AND a.reason = 'split' AND a.event_date = ?)`,
+		split.Symbol, splitDate, splitDate)
+	if err != nil {
+		return nil, fmt.Errorf("query positions to split: %w", err)
+	}
+	var positions []Portfolio
+	for rows.Next() {
+		var p Portfolio
+		if err := rows.Scan(&p.ChatID, &p.ID, &p.Name); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	var adjustments []SplitAdjustment
+	for _, p := range positions {
+		txs, err := queryTransactions(tx, p.ID, split.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		held, total := money.Zero, money.Zero
+		for _, t := range txs {
+			if t.Date.Before(splitDate) {
+				held = held.Add(t.Quantity)
+			}
+			total = total.Add(t.Quantity)
+		}
+		if held.Sign() <= 0 {
+			continue
+		}
+		after, err := held.MulDiv(numerator, denominator)
+		if err != nil {
+			return nil, fmt.Errorf("split %s: %w", split.Symbol, err)
+		}
+		delta := after.Sub(held)
+		if delta.IsZero() {
+			continue
+		}
+
+		a := SplitAdjustment{
+			ChatID: p.ChatID, PortfolioID: p.ID, PortfolioName: p.Name,
+			Symbol: split.Symbol, SplitDate: splitDate, Ratio: ratio, OldShares: total,
+		}
+		if _, err := insertTransaction(tx, Transaction{
+			ChatID: p.ChatID, PortfolioID: p.ID, Symbol: split.Symbol,
+			Kind: TxSplit, Date: splitDate, Quantity: delta,
+		}); err != nil {
+			return nil, err
+		}
+		if a.NewShares, err = syncHolding(tx, p.ChatID, p.ID, split.Symbol, ""); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO holding_adjustments (chat_id, portfolio_id, symbol, reason, event_date, detail, old_shares, new_shares)
+			VALUES (?, ?, ?, 'split', ?, ?, ?, ?)`,
+			a.ChatID, a.PortfolioID, a.Symbol, splitDate, ratio, a.OldShares, a.NewShares,
+		); err != nil {
+			return nil, fmt.Errorf("record adjustment %d %s: %w", a.ChatID, a.Symbol, err)
+		}
+		adjustments = append(adjustments, a)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return adjustments, nil
+}
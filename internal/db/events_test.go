@@ -0,0 +1,134 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"stock-portfolio-bot/internal/money"
+)
+
+// newTestRepo returns a Repository over a fresh, fully migrated in-memory database.
+func newTestRepo(t *testing.T) *Repository {
+	t.Helper()
+	database, err := New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	return NewRepository(database)
+}
+
+// newTestPortfolio returns the Main portfolio of a fresh user 1.
+func newTestPortfolio(t *testing.T, r *Repository) Portfolio {
+	t.Helper()
+	if err := r.UpsertUser(1, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.EnsurePortfolio(1, "Main"); err != nil {
+		t.Fatal(err)
+	}
+	portfolios, err := r.GetPortfolios(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return portfolios[0]
+}
+
+func testDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// testTrade is a USD trade of symbol in p at 100; a negative quantity is a sale.
+func testTrade(t *testing.T, p Portfolio, symbol, on, quantity string) Transaction {
+	t.Helper()
+	price := money.MustParse("100")
+	tx := Transaction{
+		ChatID: p.ChatID, PortfolioID: p.ID, Symbol: symbol, Kind: TxBuy, Date: testDate(t, on),
+		Quantity: money.MustParse(quantity), Price: &price, Currency: "USD",
+	}
+	if tx.Quantity.Sign() < 0 {
+		tx.Kind = TxSell
+	}
+	return tx
+}
+
+func TestBackdatedTradeIsScaledByAppliedSplit(t *testing.T) {
+	r := newTestRepo(t)
+	p := newTestPortfolio(t, r)
+
+	if _, _, err := r.RecordTransaction(testTrade(t, p, "NVDA", "2024-01-02", "10"), "NVIDIA"); err != nil {
+		t.Fatal(err)
+	}
+	split := SplitRecord{Symbol: "NVDA", Date: testDate(t, "2024-06-10"), Numerator: 10, Denominator: 1}
+	if _, err := r.SaveSplits([]SplitRecord{split}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ApplySplit(split); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		trade Transaction
+		want  string
+	}{
+		{"after the split", testTrade(t, p, "NVDA", "2024-07-01", "5"), "105"},
+		{"back-dated buy", testTrade(t, p, "NVDA", "2024-03-01", "2"), "125"},
+		{"back-dated sell", testTrade(t, p, "NVDA", "2024-04-01", "-4"), "85"},
+	}
+	for _, tt := range tests {
+		_, shares, err := r.RecordTransaction(tt.trade, "")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if shares.String() != tt.want {
+			t.Errorf("%s: holding %s shares, want %s", tt.name, shares, tt.want)
+		}
+	}
+
+	txs, err := r.GetTransactions(p.ID, "NVDA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tx := range txs {
+		if tx.Kind == TxSplit && tx.Quantity.String() != "72" {
+			t.Errorf("split entry adds %s shares, want 72 (8 held × 9)", tx.Quantity)
+		}
+	}
+}
+
+func TestSplitAppliesAgainAfterErase(t *testing.T) {
+	r := newTestRepo(t)
+	p := newTestPortfolio(t, r)
+
+	split := SplitRecord{Symbol: "NVDA", Date: testDate(t, "2024-06-10"), Numerator: 4, Denominator: 1}
+	if _, err := r.SaveSplits([]SplitRecord{split}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.RecordTransaction(testTrade(t, p, "NVDA", "2024-01-02", "10"), "NVIDIA"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ApplySplit(split); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteHolding(p.ChatID, p.ID, "NVDA"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-added with a buy from before the split: the split must apply to it.
+	if _, _, err := r.RecordTransaction(testTrade(t, p, "NVDA", "2024-05-01", "3"), "NVIDIA"); err != nil {
+		t.Fatal(err)
+	}
+	adjustments, err := r.ApplySplit(split)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(adjustments) != 1 || adjustments[0].NewShares.String() != "12" {
+		t.Errorf("adjustments = %+v, want one taking 3 shares to 12", adjustments)
+	}
+}
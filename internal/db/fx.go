@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// FXRateRecord is a stored daily closing rate: how many units of To equal one unit
+// of From on Date (midnight UTC).
+type FXRateRecord struct {
+	From string
+	To   string
+	Date time.Time
+	Rate float64
+}
+
+// SaveFXHistory upserts daily rates; re-syncing the same days overwrites them.
+func (r *Repository) SaveFXHistory(records []FXRateRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO fx_history (from_currency, to_currency, rate_date, rate)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(from_currency, to_currency, rate_date) DO UPDATE SET
+			rate = excluded.rate`)
+	if err != nil {
+		return fmt.Errorf("prepare upsert fx rate: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, fx := range records {
+		if _, err := stmt.Exec(fx.From, fx.To, fx.Date.UTC(), fx.Rate); err != nil {
+			return fmt.Errorf("upsert fx rate %s/%s: %w", fx.From, fx.To, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetFXRateOn returns the stored rate for from/to on date or, failing that, the
+// nearest earlier day. It returns nil if no rate on or before date is stored.
+func (r *Repository) GetFXRateOn(from, to string, date time.Time) (*FXRateRecord, error) {
+	fx := FXRateRecord{From: from, To: to}
+	err := r.db.QueryRow(`
+		SELECT rate_date, rate FROM fx_history
+		WHERE from_currency = ? AND to_currency = ? AND rate_date <= ?
+		ORDER BY rate_date DESC LIMIT 1`,
+		from, to, date.UTC(),
+	).Scan(&fx.Date, &fx.Rate)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get fx rate %s/%s on %s: %w", from, to, date.Format("2006-01-02"), err)
+	}
+	return &fx, nil
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetFXRateOnNearestPriorDay(t *testing.T) {
+	r := newTestRepo(t)
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	if err := r.SaveFXHistory([]FXRateRecord{
+		{From: "EUR", To: "USD", Date: day("2024-03-04 00:00"), Rate: 1.08},
+		{From: "EUR", To: "USD", Date: day("2024-03-05 00:00"), Rate: 1.09},
+		{From: "EUR", To: "USD", Date: day("2024-03-08 00:00"), Rate: 1.10}, // Friday
+		{From: "GBP", To: "USD", Date: day("2024-03-06 00:00"), Rate: 1.27},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		date     time.Time
+		want     float64 // 0 means no rate
+		wantDay  string
+	}{
+		{"exact day", "EUR", "USD", day("2024-03-05 00:00"), 1.09, "2024-03-05"},
+		{"later the same day", "EUR", "USD", day("2024-03-05 16:30"), 1.09, "2024-03-05"},
+		{"gap in the series", "EUR", "USD", day("2024-03-07 00:00"), 1.09, "2024-03-05"},
+		{"weekend", "EUR", "USD", day("2024-03-10 12:00"), 1.10, "2024-03-08"},
+		{"other pair", "GBP", "USD", day("2024-03-08 00:00"), 1.27, "2024-03-06"},
+		{"before the series", "EUR", "USD", day("2024-03-01 00:00"), 0, ""},
+		{"reversed pair", "USD", "EUR", day("2024-03-08 00:00"), 0, ""},
+		{"unknown pair", "CHF", "USD", day("2024-03-08 00:00"), 0, ""},
+	}
+	for _, tt := range tests {
+		fx, err := r.GetFXRateOn(tt.from, tt.to, tt.date)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tt.want == 0 {
+			if fx != nil {
+				t.Errorf("%s: got %+v, want no rate", tt.name, fx)
+			}
+			continue
+		}
+		if fx == nil {
+			t.Errorf("%s: no rate, want %v", tt.name, tt.want)
+			continue
+		}
+		if fx.Rate != tt.want || fx.Date.Format("2006-01-02") != tt.wantDay {
+			t.Errorf("%s: %v on %s, want %v on %s", tt.name, fx.Rate, fx.Date.Format("2006-01-02"), tt.want, tt.wantDay)
+		}
+	}
+}
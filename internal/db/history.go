@@ -0,0 +1,204 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"stock-portfolio-bot/internal/money"
+)
+
+// Report is one saved balance report of a portfolio.
+type Report struct {
+	ID          int64
+	PortfolioID int64
+	Total       money.Decimal // in the user's base currency at the time
+	ReportedAt  time.Time
+}
+
+// HoldingSnapshot is one holding's part of a saved report. Value is Shares ×
+// Multiplier × Price × Rate, rounded to the base currency's minor unit.
+type HoldingSnapshot struct {
+	Symbol     string
+	Shares     money.Decimal // contracts for futures
+	Multiplier float64       // units per contract; 1 for everything but futures
+	Price      float64       // per unit, in Currency
+	Currency   string
+	Rate       float64       // units of the base currency per unit of Currency
+	Value      money.Decimal // in the base currency
+}
+
+// HoldingValue is a holding's snapshot in one report.
+type HoldingValue struct {
+	ReportID   int64
+	ReportedAt time.Time
+	HoldingSnapshot
+}
+
+// Contribution is a holding's share of the change in total between two reports. From
+// or To is the zero snapshot if the holding was not in that report.
+type Contribution struct {
+	Symbol string
+	From   HoldingSnapshot
+	To     HoldingSnapshot
+	Change money.Decimal // To.Value - From.Value
+}
+
+// SaveReport records a portfolio's balance report total, in the user's base currency,
+// in the history table along with the holdings it is made of, all in one transaction.
+func (r *Repository) SaveReport(portfolioID int64, total money.Decimal, holdings []HoldingSnapshot) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`
+		INSERT INTO history (chat_id, portfolio_id, total_usd)
+		SELECT chat_id, id, ? FROM portfolios WHERE id = ?`,
+		total, portfolioID,
+	)
+	if err != nil {
+		return fmt.Errorf("insert report %d: %w", portfolioID, err)
+	}
+	reportID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if len(holdings) > 0 {
+		stmt, err := tx.Prepare(`
+			INSERT INTO history_holdings (history_id, symbol, shares, multiplier, price, currency, fx_rate, value)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return fmt.Errorf("prepare insert report holding: %w", err)
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for _, h := range holdings {
+			if _, err := stmt.Exec(reportID, h.Symbol, h.Shares, h.Multiplier, h.Price, h.Currency, h.Rate, h.Value); err != nil {
+				return fmt.Errorf("insert report holding %s: %w", h.Symbol, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// GetLastReport returns the most recent historical total for a portfolio.
+// Returns zero, nil if no previous report exists.
+func (r *Repository) GetLastReport(portfolioID int64) (money.Decimal, error) {
+	var total money.Decimal
+	err := r.db.QueryRow(`
+		SELECT total_usd FROM history
+		WHERE portfolio_id = ?
+		ORDER BY reported_at DESC, id DESC
+		LIMIT 1`, portfolioID).Scan(&total)
+	if err == sql.ErrNoRows {
+		return money.Zero, nil
+	}
+	return total, err
+}
+
+// GetReports returns a portfolio's reports saved since the given time, oldest first.
+func (r *Repository) GetReports(portfolioID int64, since time.Time) ([]Report, error) {
+	rows, err := r.db.Query(`
+		SELECT id, portfolio_id, total_usd, reported_at
+		FROM history
+		WHERE portfolio_id = ? AND reported_at >= ?
+		ORDER BY reported_at, id`, portfolioID, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query reports %d: %w", portfolioID, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var reports []Report
+	for rows.Next() {
+		var rep Report
+		if err := rows.Scan(&rep.ID, &rep.PortfolioID, &rep.Total, &rep.ReportedAt); err != nil {
+			return nil, err
+		}
+		reports = append(reports, rep)
+	}
+	return reports, rows.Err()
+}
+
+// GetHoldingHistory returns a holding's snapshots in a portfolio's reports saved since
+// the given time, oldest first. Reports in which the holding was not held are skipped.
+func (r *Repository) GetHoldingHistory(portfolioID int64, symbol string, since time.Time) ([]HoldingValue, error) {
+	rows, err := r.db.Query(`
+		SELECT h.id, h.reported_at, s.symbol, s.shares, s.multiplier, s.price, s.currency, s.fx_rate, s.value
+		FROM history_holdings s JOIN history h ON h.id = s.history_id
+		WHERE h.portfolio_id = ? AND s.symbol = ? AND h.reported_at >= ?
+		ORDER BY h.reported_at, h.id`, portfolioID, symbol, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query holding history %d %s: %w", portfolioID, symbol, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var values []HoldingValue
+	for rows.Next() {
+		var v HoldingValue
+		if err := rows.Scan(&v.ReportID, &v.ReportedAt, &v.Symbol, &v.Shares, &v.Multiplier,
+			&v.Price, &v.Currency, &v.Rate, &v.Value); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// GetContributions breaks the change in a portfolio's total between two of its reports
+// down by holding, largest move first. Reports saved before per-holding snapshots were
+// recorded have no breakdown, so their holdings all appear to be new or gone.
+func (r *Repository) GetContributions(portfolioID, fromReportID, toReportID int64) ([]Contribution, error) {
+	from, err := r.reportHoldings(portfolioID, fromReportID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := r.reportHoldings(portfolioID, toReportID)
+	if err != nil {
+		return nil, err
+	}
+
+	contributions := make([]Contribution, 0, len(to))
+	for symbol, t := range to {
+		contributions = append(contributions, Contribution{
+			Symbol: symbol, From: from[symbol], To: t, Change: t.Value.Sub(from[symbol].Value),
+		})
+	}
+	for symbol, f := range from {
+		if _, ok := to[symbol]; !ok {
+			contributions = append(contributions, Contribution{Symbol: symbol, From: f, Change: f.Value.Neg()})
+		}
+	}
+	sort.Slice(contributions, func(i, j int) bool {
+		if c := contributions[i].Change.Abs().Cmp(contributions[j].Change.Abs()); c != 0 {
+			return c > 0
+		}
+		return contributions[i].Symbol < contributions[j].Symbol
+	})
+	return contributions, nil
+}
+
+// reportHoldings returns the holdings of one of a portfolio's reports by symbol.
+func (r *Repository) reportHoldings(portfolioID, reportID int64) (map[string]HoldingSnapshot, error) {
+	rows, err := r.db.Query(`
+		SELECT s.symbol, s.shares, s.multiplier, s.price, s.currency, s.fx_rate, s.value
+		FROM history_holdings s JOIN history h ON h.id = s.history_id
+		WHERE h.portfolio_id = ? AND h.id = ?`, portfolioID, reportID)
+	if err != nil {
+		return nil, fmt.Errorf("query report holdings %d: %w", reportID, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	holdings := make(map[string]HoldingSnapshot)
+	for rows.Next() {
+		var s HoldingSnapshot
+		if err := rows.Scan(&s.Symbol, &s.Shares, &s.Multiplier, &s.Price, &s.Currency, &s.Rate, &s.Value); err != nil {
+			return nil, err
+		}
+		holdings[s.Symbol] = s
+	}
+	return holdings, rows.Err()
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"stock-portfolio-bot/internal/money"
+)
+
+func snapshot(symbol, shares, price, value string) HoldingSnapshot {
+	p := money.MustParse(price)
+	return HoldingSnapshot{
+		Symbol: symbol, Shares: money.MustParse(shares), Multiplier: 1,
+		Price: p.Float64(), Currency: "USD", Rate: 1, Value: money.MustParse(value),
+	}
+}
+
+func TestHoldingHistoryAndContributions(t *testing.T) {
+	r := newTestRepo(t)
+	p := newTestPortfolio(t, r)
+	if err := r.EnsurePortfolio(1, "Other"); err != nil {
+		t.Fatal(err)
+	}
+	portfolios, err := r.GetPortfolios(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := portfolios[1]
+
+	reports := []struct {
+		portfolio Portfolio
+		holdings  []HoldingSnapshot
+	}{
+		{p, []HoldingSnapshot{snapshot("AAPL", "10", "190", "1900"), snapshot("MSFT", "2", "400", "800")}},
+		{other, []HoldingSnapshot{snapshot("AAPL", "1", "1000", "1000")}},
+		{p, nil}, // saved before per-holding snapshots were recorded
+		{p, []HoldingSnapshot{snapshot("AAPL", "10", "200", "2000"), snapshot("NVDA", "5", "100", "500")}},
+	}
+	for _, rep := range reports {
+		total := money.Zero
+		for _, h := range rep.holdings {
+			total = total.Add(h.Value)
+		}
+		if err := r.SaveReport(rep.portfolio.ID, total, rep.holdings); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved, err := r.GetReports(p.ID, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 3 {
+		t.Fatalf("%d reports for Main, want 3", len(saved))
+	}
+
+	history, err := r.GetHoldingHistory(p.ID, "AAPL", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].ReportID != saved[0].ID || history[1].ReportID != saved[2].ID {
+		t.Fatalf("AAPL history = %+v, want its snapshots in Main's first and last reports", history)
+	}
+	if history[0].Price != 190 || history[1].Price != 200 || history[1].Value.String() != "2000" {
+		t.Errorf("AAPL history = %+v, want 190 then 200", history)
+	}
+
+	contributions, err := r.GetContributions(p.ID, saved[0].ID, saved[2].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ symbol, change string }{
+		{"MSFT", "-800"}, // sold
+		{"NVDA", "500"},  // bought
+		{"AAPL", "100"},
+	}
+	if len(contributions) != len(want) {
+		t.Fatalf("contributions = %+v, want %d", contributions, len(want))
+	}
+	for i, w := range want {
+		if c := contributions[i]; c.Symbol != w.symbol || c.Change.String() != w.change {
+			t.Errorf("contribution %d = %s %s, want %s %s", i, c.Symbol, c.Change, w.symbol, w.change)
+		}
+	}
+	if c := contributions[0]; c.To.Shares.Sign() != 0 || c.From.Shares.String() != "2" {
+		t.Errorf("MSFT contribution = %+v, want from 2 shares to none", c)
+	}
+
+	// Against a report without snapshots every holding appears new.
+	contributions, err = r.GetContributions(p.ID, saved[1].ID, saved[2].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contributions) != 2 || contributions[0].Symbol != "AAPL" || contributions[0].Change.String() != "2000" {
+		t.Errorf("contributions since a report without snapshots = %+v, want AAPL and NVDA in full", contributions)
+	}
+
+	// Another portfolio's report is not Main's to compare against.
+	contributions, err = r.GetContributions(p.ID, saved[0].ID, saved[0].ID+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range contributions {
+		if c.To.Shares.Sign() != 0 {
+			t.Errorf("contribution %+v comes from another portfolio's report", c)
+		}
+	}
+}
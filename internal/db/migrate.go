@@ -0,0 +1,272 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"stock-portfolio-bot/internal/money"
+)
+
+// Migrations live in migrations/ as NNNN_name.sql and are applied in version order,
+// each in its own transaction together with its schema_migrations row. Steps that
+// need Go (data rewrites SQLite cannot express) are registered in goMigrations under
+// a version with no SQL file. Applied migrations must never be edited; add a new one.
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// baselineFile is the schema of migration 1, reused by steps that rebuild a table as
+// it stood then.
+const baselineFile = "migrations/0001_baseline.sql"
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer build than
+// this one; running against it could corrupt tables this build does not know about.
+var ErrSchemaTooNew = errors.New("database schema is newer than this build")
+
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// goMigrations are the steps implemented in Go, keyed by version.
+var goMigrations = map[int]migration{
+	2: {name: "decimal_columns", up: migrateDecimalColumns},
+}
+
+// migrate brings the database up to the latest version, refusing to touch one whose
+// recorded version is ahead of the migrations compiled in.
+func migrate(sqlDB *sql.DB) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	if _, err := sqlDB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		    version     INTEGER PRIMARY KEY,
+		    name        TEXT NOT NULL,
+		    applied_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := sqlDB.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, this build knows up to %d",
+			ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(sqlDB, m); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+		}
+		log.Printf("db: applied migration %04d_%s", m.version, m.name)
+	}
+	return nil
+}
+
+func applyMigration(sqlDB *sql.DB, m migration) error {
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`,
+		m.version, m.name); err != nil {
+		return fmt.Errorf("record version: %w", err)
+	}
+	return tx.Commit()
+}
+
+// loadMigrations returns the embedded SQL and registered Go migrations sorted by
+// version, checking that versions run 1..N without gaps or duplicates.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]migration, len(entries)+len(goMigrations))
+	for _, e := range entries {
+		version, name, err := parseMigrationName(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := byVersion[version]; dup {
+			return nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		file := path.Join("migrations", e.Name())
+		byVersion[version] = migration{version: version, name: name, up: execFile(file)}
+	}
+	for version, m := range goMigrations {
+		if _, dup := byVersion[version]; dup {
+			return nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		m.version = version
+		byVersion[version] = m
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migrations embedded")
+	}
+	for i, m := range migrations {
+		if m.version != i+1 {
+			return nil, fmt.Errorf("migration versions must run 1..%d without gaps, found %d", len(migrations), m.version)
+		}
+	}
+	return migrations, nil
+}
+
+// parseMigrationName splits "0003_add_index.sql" into 3 and "add_index".
+func parseMigrationName(file string) (int, string, error) {
+	base, ok := strings.CutSuffix(file, ".sql")
+	prefix, name, found := strings.Cut(base, "_")
+	version, err := strconv.Atoi(prefix)
+	if !ok || !found || name == "" || err != nil || version <= 0 {
+		return 0, "", fmt.Errorf("migration file %q: want NNNN_name.sql", file)
+	}
+	return version, name, nil
+}
+
+// execFile returns a step that runs the statements in an embedded SQL file.
+func execFile(file string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		stmts, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(string(stmts))
+		return err
+	}
+}
+
+// decimalColumns are the columns holding money.Decimal values as TEXT. Databases
+// created when they were REAL are rebuilt once by migrateDecimalColumns.
+var decimalColumns = []struct {
+	table   string
+	columns []string
+}{
+	{"holdings", []string{"shares"}},
+	{"history", []string{"total_usd"}},
+	{"holding_adjustments", []string{"old_shares", "new_shares"}},
+}
+
+// migrateDecimalColumns converts REAL decimal columns to TEXT. SQLite cannot change a
+// column's type in place, so each table is renamed, recreated from the baseline, and
+// its rows copied back with every value rewritten at its shortest decimal representation.
+// Tables that already have TEXT columns are left alone.
+func migrateDecimalColumns(tx *sql.Tx) error {
+	for _, dc := range decimalColumns {
+		var colType string
+		err := tx.QueryRow(`
+			SELECT type FROM pragma_table_info(?) WHERE name = ?`,
+			dc.table, dc.columns[0]).Scan(&colType)
+		if err != nil {
+			return fmt.Errorf("inspect %s: %w", dc.table, err)
+		}
+		if !strings.EqualFold(colType, "REAL") {
+			continue
+		}
+		if err := rebuildDecimalTable(tx, dc.table, dc.columns); err != nil {
+			return fmt.Errorf("rebuild %s: %w", dc.table, err)
+		}
+		log.Printf("db: migrated %s.%s to decimal text", dc.table, strings.Join(dc.columns, ", "))
+	}
+	return nil
+}
+
+func rebuildDecimalTable(tx *sql.Tx, table string, columns []string) error {
+	// Indexes keep their names when the table is renamed; drop them so the baseline
+	// recreates them on the new table.
+	rows, err := tx.Query(`
+		SELECT name FROM sqlite_master
+		WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL`, table)
+	if err != nil {
+		return fmt.Errorf("list indexes: %w", err)
+	}
+	var indexes []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		indexes = append(indexes, name)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	for _, name := range indexes {
+		if _, err := tx.Exec(`DROP INDEX "` + name + `"`); err != nil {
+			return fmt.Errorf("drop index %s: %w", name, err)
+		}
+	}
+
+	old := table + "_real"
+	if _, err := tx.Exec(`ALTER TABLE "` + table + `" RENAME TO "` + old + `"`); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	if err := execFile(baselineFile)(tx); err != nil {
+		return fmt.Errorf("recreate: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO "` + table + `" SELECT * FROM "` + old + `"`); err != nil {
+		return fmt.Errorf("copy rows: %w", err)
+	}
+
+	// The copy stored SQLite's text rendering of each REAL (up to 15 significant
+	// digits, exponent form for small values); rewrite them as canonical decimals.
+	for _, col := range columns {
+		values, err := tx.Query(`SELECT rowid, "` + col + `" FROM "` + old + `"`)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", col, err)
+		}
+		converted := make(map[int64]money.Decimal)
+		for values.Next() {
+			var rowID int64
+			var d money.Decimal
+			if err := values.Scan(&rowID, &d); err != nil {
+				_ = values.Close()
+				return fmt.Errorf("read %s: %w", col, err)
+			}
+			converted[rowID] = d
+		}
+		if err := values.Close(); err != nil {
+			return err
+		}
+		for rowID, d := range converted {
+			if _, err := tx.Exec(`UPDATE "`+table+`" SET "`+col+`" = ? WHERE rowid = ?`, d, rowID); err != nil {
+				return fmt.Errorf("rewrite %s: %w", col, err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(`DROP TABLE "` + old + `"`); err != nil {
+		return fmt.Errorf("drop old table: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"stock-portfolio-bot/internal/money"
+)
+
+// openFixture returns an in-memory database loaded with a testdata SQL file, or an
+// empty one if fixture is "".
+func openFixture(t *testing.T, fixture string) *sql.DB {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: is a separate database.
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if fixture != "" {
+		stmts, err := os.ReadFile("testdata/" + fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sqlDB.Exec(string(stmts)); err != nil {
+			t.Fatalf("load %s: %v", fixture, err)
+		}
+	}
+	return sqlDB
+}
+
+func TestMigrateDecimalColumns(t *testing.T) {
+	sqlDB := openFixture(t, "legacy_real.sql")
+	if err := migrate(sqlDB); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`SELECT shares FROM holdings ORDER BY id`, []string{"10.1", "0.00012345", "3"}},
+		{`SELECT total_usd FROM history ORDER BY id`, []string{"1999.99", "0.1"}},
+		{`SELECT old_shares FROM holding_adjustments`, []string{"2.525"}},
+		{`SELECT new_shares FROM holding_adjustments`, []string{"10.1"}},
+	}
+	for _, tt := range tests {
+		rows, err := sqlDB.Query(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		var got []string
+		for rows.Next() {
+			var v any
+			if err := rows.Scan(&v); err != nil {
+				t.Fatal(err)
+			}
+			text, ok := v.(string)
+			if !ok {
+				t.Errorf("%s: value %v stored as %T, want TEXT", tt.query, v, v)
+			}
+			got = append(got, text)
+		}
+		_ = rows.Close()
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s = %v, want %v", tt.query, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s row %d = %q, want %q", tt.query, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	for _, dc := range decimalColumns {
+		for _, col := range dc.columns {
+			var colType string
+			if err := sqlDB.QueryRow(`SELECT type FROM pragma_table_info(?) WHERE name = ?`,
+				dc.table, col).Scan(&colType); err != nil {
+				t.Fatalf("inspect %s.%s: %v", dc.table, col, err)
+			}
+			if colType != "TEXT" {
+				t.Errorf("%s.%s type = %s, want TEXT", dc.table, col, colType)
+			}
+		}
+	}
+
+	// Decimal columns read back exactly.
+	var shares money.Decimal
+	if err := sqlDB.QueryRow(`SELECT shares FROM holdings WHERE symbol = 'BTC-USD'`).Scan(&shares); err != nil {
+		t.Fatal(err)
+	}
+	if shares != money.MustParse("0.00012345") {
+		t.Errorf("BTC-USD shares = %s, want 0.00012345", shares)
+	}
+}
+
+// latestVersion is the newest migration compiled in.
+func latestVersion(t *testing.T) int {
+	t.Helper()
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrations[len(migrations)-1].version
+}
+
+func schemaVersion(t *testing.T, sqlDB *sql.DB) (version, applied int) {
+	t.Helper()
+	if err := sqlDB.QueryRow(`SELECT MAX(version), COUNT(*) FROM schema_migrations`).Scan(&version, &applied); err != nil {
+		t.Fatal(err)
+	}
+	return version, applied
+}
+
+func TestMigrateBaselineToHead(t *testing.T) {
+	sqlDB := openFixture(t, "baseline.sql")
+	if err := migrate(sqlDB); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	latest := latestVersion(t)
+	if version, applied := schemaVersion(t, sqlDB); version != latest || applied != latest {
+		t.Errorf("schema at version %d with %d applied, want %d and %d", version, applied, latest, latest)
+	}
+
+	// Every user got a Main portfolio holding their positions, with an opening
+	// balance in the ledger for each.
+	tests := []struct {
+		chatID  int64
+		symbols int
+	}{
+		{1, 2},
+		{2, 1},
+	}
+	for _, tt := range tests {
+		var name string
+		var holdings, opening int
+		if err := sqlDB.QueryRow(`
+			SELECT p.name,
+			       (SELECT COUNT(*) FROM holdings h WHERE h.portfolio_id = p.id),
+			       (SELECT COUNT(*) FROM transactions x WHERE x.portfolio_id = p.id)
+			FROM portfolios p WHERE p.chat_id = ?`, tt.chatID).Scan(&name, &holdings, &opening); err != nil {
+			t.Fatalf("chat %d: %v", tt.chatID, err)
+		}
+		if name != "Main" || holdings != tt.symbols || opening != tt.symbols {
+			t.Errorf("chat %d: portfolio %q with %d holdings and %d trades, want Main with %d of each",
+				tt.chatID, name, holdings, opening, tt.symbols)
+		}
+	}
+
+	for _, table := range []string{"holdings", "transactions", "history", "holding_adjustments"} {
+		var orphans int
+		if err := sqlDB.QueryRow(`
+			SELECT COUNT(*) FROM ` + table + `
+			WHERE portfolio_id = 0 OR portfolio_id NOT IN (SELECT id FROM portfolios)`).Scan(&orphans); err != nil {
+			t.Fatal(err)
+		}
+		if orphans != 0 {
+			t.Errorf("%d %s rows have no portfolio", orphans, table)
+		}
+	}
+
+	var reports int
+	if err := sqlDB.QueryRow(`SELECT COUNT(*) FROM history`).Scan(&reports); err != nil {
+		t.Fatal(err)
+	}
+	if reports != 3 {
+		t.Errorf("%d reports after migrating, want 3", reports)
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	sqlDB := openFixture(t, "baseline.sql")
+	if err := migrate(sqlDB); err != nil {
+		t.Fatalf("first migrate: %v", err)
+	}
+	var before string
+	if err := sqlDB.QueryRow(`SELECT group_concat(symbol || '=' || shares) FROM holdings`).Scan(&before); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate(sqlDB); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	latest := latestVersion(t)
+	if version, applied := schemaVersion(t, sqlDB); version != latest || applied != latest {
+		t.Errorf("after re-run: version %d with %d applied, want %d and %d", version, applied, latest, latest)
+	}
+	var after string
+	if err := sqlDB.QueryRow(`SELECT group_concat(symbol || '=' || shares) FROM holdings`).Scan(&after); err != nil {
+		t.Fatal(err)
+	}
+	if after != before {
+		t.Errorf("holdings changed on re-run: %s, was %s", after, before)
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	sqlDB := openFixture(t, "")
+	if err := migrate(sqlDB); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := sqlDB.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, 'from_the_future')`,
+		latestVersion(t)+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(sqlDB); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("migrate error = %v, want ErrSchemaTooNew", err)
+	}
+}
@@ -0,0 +1,23 @@
+-- Buy, sell and split ledger. holdings.shares becomes the sum of a position's
+-- transactions, kept in step by the repository.
+CREATE TABLE transactions (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
+    symbol      TEXT NOT NULL,
+    kind        TEXT NOT NULL CHECK (kind IN ('buy', 'sell', 'split')),
+    trade_date  DATETIME NOT NULL,
+    quantity    TEXT NOT NULL,             -- money.Decimal, signed change in shares
+    price       TEXT,                      -- money.Decimal per unit in currency; NULL if unknown
+    currency    TEXT NOT NULL DEFAULT '',
+    fees        TEXT NOT NULL DEFAULT '0', -- money.Decimal, in currency
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_transactions_position ON transactions(chat_id, symbol);
+
+-- Existing positions become opening balances: a buy of the current share count at an
+-- unknown price on the day the holding was added.
+INSERT INTO transactions (chat_id, symbol, kind, trade_date, quantity)
+SELECT chat_id, symbol, 'buy', COALESCE(added_at, CURRENT_TIMESTAMP), shares
+FROM holdings
+WHERE CAST(shares AS REAL) > 0;
@@ -0,0 +1,72 @@
+-- Named portfolios per user. Every existing user gets a "Main" portfolio that takes
+-- over their holdings, ledger, report history and split adjustments.
+CREATE TABLE portfolios (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
+    name        TEXT NOT NULL COLLATE NOCASE,
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chat_id, name)
+);
+
+INSERT INTO portfolios (chat_id, name)
+SELECT chat_id, 'Main' FROM (
+    SELECT chat_id FROM users
+    UNION SELECT chat_id FROM holdings
+    UNION SELECT chat_id FROM transactions
+    UNION SELECT chat_id FROM history
+)
+ORDER BY chat_id;
+
+-- holdings: unique per portfolio instead of per user. chat_id stays for queries
+-- across all of a user's portfolios.
+CREATE TABLE holdings_new (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id      INTEGER NOT NULL REFERENCES users(chat_id),
+    portfolio_id INTEGER NOT NULL REFERENCES portfolios(id),
+    symbol       TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    shares       TEXT NOT NULL, -- money.Decimal
+    added_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(portfolio_id, symbol)
+);
+
+INSERT INTO holdings_new (id, chat_id, portfolio_id, symbol, name, shares, added_at)
+SELECT h.id, h.chat_id, p.id, h.symbol, h.name, h.shares, h.added_at
+FROM holdings h JOIN portfolios p ON p.chat_id = h.chat_id;
+
+DROP TABLE holdings;
+ALTER TABLE holdings_new RENAME TO holdings;
+CREATE INDEX idx_holdings_chat ON holdings(chat_id);
+
+-- holding_adjustments: a split is applied once per portfolio.
+CREATE TABLE holding_adjustments_new (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id      INTEGER NOT NULL REFERENCES users(chat_id),
+    portfolio_id INTEGER NOT NULL REFERENCES portfolios(id),
+    symbol       TEXT NOT NULL,
+    reason       TEXT NOT NULL,
+    event_date   DATETIME NOT NULL,
+    detail       TEXT NOT NULL DEFAULT '',
+    old_shares   TEXT NOT NULL, -- money.Decimal
+    new_shares   TEXT NOT NULL, -- money.Decimal
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(portfolio_id, symbol, reason, event_date)
+);
+
+INSERT INTO holding_adjustments_new
+    (id, chat_id, portfolio_id, symbol, reason, event_date, detail, old_shares, new_shares, created_at)
+SELECT a.id, a.chat_id, p.id, a.symbol, a.reason, a.event_date, a.detail, a.old_shares, a.new_shares, a.created_at
+FROM holding_adjustments a JOIN portfolios p ON p.chat_id = a.chat_id;
+
+DROP TABLE holding_adjustments;
+ALTER TABLE holding_adjustments_new RENAME TO holding_adjustments;
+
+-- transactions and history gain a portfolio; adding a column keeps their rows in place.
+ALTER TABLE transactions ADD COLUMN portfolio_id INTEGER NOT NULL DEFAULT 0; -- portfolios(id)
+UPDATE transactions SET portfolio_id = (SELECT id FROM portfolios p WHERE p.chat_id = transactions.chat_id);
+DROP INDEX idx_transactions_position;
+CREATE INDEX idx_transactions_position ON transactions(portfolio_id, symbol);
+
+ALTER TABLE history ADD COLUMN portfolio_id INTEGER NOT NULL DEFAULT 0; -- portfolios(id)
+UPDATE history SET portfolio_id = (SELECT id FROM portfolios p WHERE p.chat_id = history.chat_id);
+CREATE INDEX idx_history_portfolio ON history(portfolio_id, reported_at);
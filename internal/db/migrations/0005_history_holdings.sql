@@ -0,0 +1,15 @@
+-- Per-holding breakdown of each saved report, so changes between two reports can be
+-- attributed to positions. value = shares × multiplier × price × fx_rate.
+CREATE TABLE history_holdings (
+    history_id  INTEGER NOT NULL REFERENCES history(id),
+    symbol      TEXT NOT NULL,
+    shares      TEXT NOT NULL,             -- money.Decimal, contracts for futures
+    multiplier  REAL NOT NULL DEFAULT 1,   -- units per contract
+    price       REAL NOT NULL,             -- per unit, in currency
+    currency    TEXT NOT NULL DEFAULT '',
+    fx_rate     REAL NOT NULL,             -- units of the report's base currency per unit of currency
+    value       TEXT NOT NULL,             -- money.Decimal, in the report's base currency
+    PRIMARY KEY (history_id, symbol)
+);
+
+CREATE INDEX idx_history_holdings_symbol ON history_holdings(symbol, history_id);
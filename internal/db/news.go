@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// NewsSubscription is a user who opted in to pushed headlines.
+type NewsSubscription struct {
+	ChatID    int64
+	CreatedAt time.Time
+}
+
+// SetNewsSubscription opts a user in to or out of pushed headlines.
+func (r *Repository) SetNewsSubscription(chatID int64, subscribed bool) error {
+	var err error
+	if subscribed {
+		_, err = r.db.Exec(`
+			INSERT INTO news_subscriptions (chat_id, created_at) VALUES (?, ?)
+			ON CONFLICT(chat_id) DO NOTHING`, chatID, time.Now().UTC())
+	} else {
+		_, err = r.db.Exec(`DELETE FROM news_subscriptions WHERE chat_id = ?`, chatID)
+	}
+	if err != nil {
+		return fmt.Errorf("set news subscription %d: %w", chatID, err)
+	}
+	return nil
+}
+
+// IsNewsSubscribed reports whether a user opted in to pushed headlines.
+func (r *Repository) IsNewsSubscribed(chatID int64) (bool, error) {
+	var one int
+	err := r.db.QueryRow(`SELECT 1 FROM news_subscriptions WHERE chat_id = ?`, chatID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("query news subscription %d: %w", chatID, err)
+	}
+	return true, nil
+}
+
+// GetNewsSubscriptions returns all users who opted in to pushed headlines.
+func (r *Repository) GetNewsSubscriptions() ([]NewsSubscription, error) {
+	rows, err := r.db.Query(`SELECT chat_id, created_at FROM news_subscriptions`)
+	if err != nil {
+		return nil, fmt.Errorf("query news subscriptions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var subs []NewsSubscription
+	for rows.Next() {
+		var sub NewsSubscription
+		if err := rows.Scan(&sub.ChatID, &sub.CreatedAt); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// FilterUnsentNews returns the subset of uuids not yet sent to a user.
+func (r *Repository) FilterUnsentNews(chatID int64, uuids []string) ([]string, error) {
+	if len(uuids) == 0 {
+		return nil, nil
+	}
+
+	query, args := inClause(`
+		SELECT uuid FROM news_sent
+		WHERE chat_id = ? AND uuid IN (%s)`, uuids, chatID)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query sent news: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	sent := make(map[string]struct{})
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		sent[uuid] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var unsent []string
+	for _, uuid := range uuids {
+		if _, ok := sent[uuid]; !ok {
+			unsent = append(unsent, uuid)
+		}
+	}
+	return unsent, nil
+}
+
+// MarkNewsSent records that headlines were pushed to a user.
+func (r *Repository) MarkNewsSent(chatID int64, uuids []string) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO news_sent (chat_id, uuid, sent_at) VALUES (?, ?, ?)
+		ON CONFLICT(chat_id, uuid) DO NOTHING`)
+	if err != nil {
+		return fmt.Errorf("prepare insert sent news: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	now := time.Now().UTC()
+	for _, uuid := range uuids {
+		if _, err := stmt.Exec(chatID, uuid, now); err != nil {
+			return fmt.Errorf("insert sent news %d %s: %w", chatID, uuid, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// PruneSentNews deletes sent-news records older than before.
+func (r *Repository) PruneSentNews(before time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM news_sent WHERE sent_at < ?`, before.UTC()); err != nil {
+		return fmt.Errorf("prune sent news: %w", err)
+	}
+	return nil
+}
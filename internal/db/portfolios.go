@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// Portfolio is a named group of holdings belonging to one user.
+type Portfolio struct {
+	ID        int64
+	ChatID    int64
+	Name      string
+	CreatedAt time.Time
+}
+
+// CreatePortfolio adds a portfolio for a user. Names are unique per user, ignoring case.
+func (r *Repository) CreatePortfolio(chatID int64, name string) (Portfolio, error) {
+	res, err := r.db.Exec(`
+		INSERT INTO portfolios (chat_id, name) VALUES (?, ?)`, chatID, name)
+	if err != nil {
+		return Portfolio{}, fmt.Errorf("create portfolio %q: %w", name, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Portfolio{}, err
+	}
+	return Portfolio{ID: id, ChatID: chatID, Name: name, CreatedAt: time.Now()}, nil
+}
+
+// EnsurePortfolio creates a portfolio for a user unless one by that name exists. It
+// is safe to call concurrently: the UNIQUE(chat_id, name) constraint lets only one
+// insert through.
+func (r *Repository) EnsurePortfolio(chatID int64, name string) error {
+	if _, err := r.db.Exec(`
+		INSERT INTO portfolios (chat_id, name) VALUES (?, ?)
+		ON CONFLICT(chat_id, name) DO NOTHING`, chatID, name); err != nil {
+		return fmt.Errorf("ensure portfolio %q: %w", name, err)
+	}
+	return nil
+}
+
+// GetPortfolios returns a user's portfolios, oldest first.
+func (r *Repository) GetPortfolios(chatID int64) ([]Portfolio, error) {
+	rows, err := r.db.Query(`
+		SELECT id, chat_id, name, created_at
+		FROM portfolios WHERE chat_id = ?
+		ORDER BY id`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("query portfolios %d: %w", chatID, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var portfolios []Portfolio
+	for rows.Next() {
+		var p Portfolio
+		if err := rows.Scan(&p.ID, &p.ChatID, &p.Name, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		portfolios = append(portfolios, p)
+	}
+	return portfolios, rows.Err()
+}
+
+// RenamePortfolio renames one of a user's portfolios.
+func (r *Repository) RenamePortfolio(chatID, portfolioID int64, name string) error {
+	_, err := r.db.Exec(`
+		UPDATE portfolios SET name = ? WHERE id = ? AND chat_id = ?`,
+		name, portfolioID, chatID,
+	)
+	return err
+}
+
+// DeletePortfolio removes one of a user's portfolios with its holdings, ledger,
+// report history (with its per-holding breakdown) and adjustments, all in one
+// transaction.
+func (r *Repository) DeletePortfolio(chatID, portfolioID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`
+		DELETE FROM history_holdings WHERE history_id IN (
+			SELECT id FROM history WHERE portfolio_id = ? AND chat_id = ?)`,
+		portfolioID, chatID); err != nil {
+		return fmt.Errorf("delete history_holdings: %w", err)
+	}
+	for _, table := range []string{"holdings", "transactions", "history", "holding_adjustments"} {
+		if _, err := tx.Exec(`
+			DELETE FROM `+table+` WHERE portfolio_id = ? AND chat_id = ?`,
+			portfolioID, chatID); err != nil {
+			return fmt.Errorf("delete %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM portfolios WHERE id = ? AND chat_id = ?`, portfolioID, chatID); err != nil {
+		return fmt.Errorf("delete portfolio: %w", err)
+	}
+	return tx.Commit()
+}
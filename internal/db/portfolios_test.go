@@ -0,0 +1,22 @@
+package db
+
+import "testing"
+
+func TestEnsurePortfolioIsIdempotent(t *testing.T) {
+	r := newTestRepo(t)
+	if err := r.UpsertUser(1, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := r.EnsurePortfolio(1, "Main"); err != nil {
+			t.Fatalf("EnsurePortfolio #%d: %v", i+1, err)
+		}
+	}
+	portfolios, err := r.GetPortfolios(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(portfolios) != 1 || portfolios[0].Name != "Main" {
+		t.Errorf("portfolios = %+v, want one named Main", portfolios)
+	}
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// QuoteRecord is one persisted price observation.
+type QuoteRecord struct {
+	Symbol    string
+	Price     float64
+	Currency  string
+	Source    string
+	FetchedAt time.Time
+}
+
+// SaveQuotes appends price observations to the quotes table in a single transaction.
+func (r *Repository) SaveQuotes(records []QuoteRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO quotes (symbol, price, currency, source, fetched_at)
+		VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare insert quote: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, q := range records {
+		if _, err := stmt.Exec(q.Symbol, q.Price, q.Currency, q.Source, q.FetchedAt.UTC()); err != nil {
+			return fmt.Errorf("insert quote %s: %w", q.Symbol, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetLatestQuotes returns the most recent observation for every symbol.
+func (r *Repository) GetLatestQuotes() ([]QuoteRecord, error) {
+	rows, err := r.db.Query(`
+		SELECT q.symbol, q.price, q.currency, q.source, q.fetched_at
+		FROM quotes q
+		JOIN (
+			SELECT symbol, MAX(fetched_at) AS fetched_at
+			FROM quotes GROUP BY symbol
+		) latest ON latest.symbol = q.symbol AND latest.fetched_at = q.fetched_at
+		GROUP BY q.symbol`)
+	if err != nil {
+		return nil, fmt.Errorf("query latest quotes: %w", err)
+	}
+	return scanQuotes(rows)
+}
+
+// GetQuoteHistory returns all observations for symbol since the given time, oldest first.
+func (r *Repository) GetQuoteHistory(symbol string, since time.Time) ([]QuoteRecord, error) {
+	rows, err := r.db.Query(`
+		SELECT symbol, price, currency, source, fetched_at
+		FROM quotes
+		WHERE symbol = ? AND fetched_at >= ?
+		ORDER BY fetched_at`, symbol, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query quote history %s: %w", symbol, err)
+	}
+	return scanQuotes(rows)
+}
+
+func scanQuotes(rows *sql.Rows) ([]QuoteRecord, error) {
+	defer func() { _ = rows.Close() }()
+
+	var records []QuoteRecord
+	for rows.Next() {
+		var q QuoteRecord
+		if err := rows.Scan(&q.Symbol, &q.Price, &q.Currency, &q.Source, &q.FetchedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, q)
+	}
+	return records, rows.Err()
+}
@@ -3,15 +3,20 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"time"
+
+	"stock-portfolio-bot/internal/money"
 )
 
 // Holding represents a single portfolio position.
 type Holding struct {
-	ID     int64
-	ChatID int64
-	Symbol string
-	Name   string
-	Shares float64
+	ID          int64
+	ChatID      int64
+	PortfolioID int64
+	Symbol      string
+	Name        string
+	Shares      money.Decimal // 0 for watch-only instruments
+	AddedAt     time.Time
 }
 
 // Repository provides CRUD operations for users and holdings.
@@ -55,25 +60,24 @@ func (r *Repository) GetUserState(chatID int64) (state, stateData string, err er
 	return
 }
 
-// UpsertHolding inserts or updates a holding (updates shares on conflict).
-func (r *Repository) UpsertHolding(chatID int64, symbol, name string, shares float64) error {
+// AddWatchHolding adds a watch-only holding (an index), which has no shares and no
+// ledger. Adding it again only refreshes the name.
+func (r *Repository) AddWatchHolding(portfolioID int64, symbol, name string) error {
 	_, err := r.db.Exec(`
-		INSERT INTO holdings (chat_id, symbol, name, shares)
-		VALUES (?, ?, ?, ?)
-		ON CONFLICT(chat_id, symbol) DO UPDATE SET
-			name   = excluded.name,
-			shares = excluded.shares`,
-		chatID, symbol, name, shares,
+		INSERT INTO holdings (chat_id, portfolio_id, symbol, name, shares)
+		SELECT chat_id, id, ?, ?, ? FROM portfolios WHERE id = ?
+		ON CONFLICT(portfolio_id, symbol) DO UPDATE SET name = excluded.name`,
+		symbol, name, money.Zero, portfolioID,
 	)
 	return err
 }
 
-// GetHoldings returns all holdings for a user.
-func (r *Repository) GetHoldings(chatID int64) ([]Holding, error) {
+// GetHoldings returns all holdings in a portfolio.
+func (r *Repository) GetHoldings(portfolioID int64) ([]Holding, error) {
 	rows, err := r.db.Query(`
-		SELECT id, chat_id, symbol, name, shares
-		FROM holdings WHERE chat_id = ?
-		ORDER BY symbol`, chatID)
+		SELECT id, chat_id, portfolio_id, symbol, name, shares, added_at
+		FROM holdings WHERE portfolio_id = ?
+		ORDER BY symbol`, portfolioID)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +86,7 @@ func (r *Repository) GetHoldings(chatID int64) ([]Holding, error) {
 	var holdings []Holding
 	for rows.Next() {
 		var h Holding
-		if err := rows.Scan(&h.ID, &h.ChatID, &h.Symbol, &h.Name, &h.Shares); err != nil {
+		if err := rows.Scan(&h.ID, &h.ChatID, &h.PortfolioID, &h.Symbol, &h.Name, &h.Shares, &h.AddedAt); err != nil {
 			return nil, err
 		}
 		holdings = append(holdings, h)
@@ -90,11 +94,45 @@ func (r *Repository) GetHoldings(chatID int64) ([]Holding, error) {
 	return holdings, rows.Err()
 }
 
-// DeleteHolding removes a specific holding for a user.
-func (r *Repository) DeleteHolding(chatID int64, symbol string) error {
-	_, err := r.db.Exec(`
-		DELETE FROM holdings WHERE chat_id = ? AND symbol = ?`, chatID, symbol)
-	return err
+// GetUserSymbols returns the symbols a user holds or watches in any portfolio.
+func (r *Repository) GetUserSymbols(chatID int64) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT symbol FROM holdings WHERE chat_id = ? ORDER BY symbol`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("query symbols %d: %w", chatID, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var symbols []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, s)
+	}
+	return symbols, rows.Err()
+}
+
+// DeleteHolding removes a holding from one of a user's portfolios together with its
+// ledger, so the gains realized on its earlier sales are gone too. Its split
+// adjustments go as well, so splits are applied afresh if the symbol is added again.
+// To close a position and keep its history, record a sale instead.
+func (r *Repository) DeleteHolding(chatID, portfolioID int64, symbol string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, table := range []string{"transactions", "holding_adjustments", "holdings"} {
+		if _, err := tx.Exec(`
+			DELETE FROM `+table+` WHERE portfolio_id = ? AND chat_id = ? AND symbol = ?`,
+			portfolioID, chatID, symbol); err != nil {
+			return fmt.Errorf("delete %s: %w", table, err)
+		}
+	}
+	return tx.Commit()
 }
 
 // GetAllActiveUsers returns chat IDs of all users who have at least one holding.
@@ -117,30 +155,6 @@ func (r *Repository) GetAllActiveUsers() ([]int64, error) {
 	return ids, rows.Err()
 }
 
-// SaveReport records a balance report in the history table.
-func (r *Repository) SaveReport(chatID int64, totalUSD float64) error {
-	_, err := r.db.Exec(`
-		INSERT INTO history (chat_id, total_usd) VALUES (?, ?)`,
-		chatID, totalUSD,
-	)
-	return err
-}
-
-// GetLastReport returns the most recent historical total for a user.
-// Returns 0, nil if no previous report exists.
-func (r *Repository) GetLastReport(chatID int64) (float64, error) {
-	var total float64
-	err := r.db.QueryRow(`
-		SELECT total_usd FROM history
-		WHERE chat_id = ?
-		ORDER BY reported_at DESC
-		LIMIT 1`, chatID).Scan(&total)
-	if err == sql.ErrNoRows {
-		return 0, nil
-	}
-	return total, err
-}
-
 // GetDistinctSymbols returns all unique ticker symbols across all users.
 func (r *Repository) GetDistinctSymbols() ([]string, error) {
 	rows, err := r.db.Query(`
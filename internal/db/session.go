@@ -0,0 +1,48 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// YahooSession is the persisted Yahoo cookie/crumb pair. Only one row is kept.
+type YahooSession struct {
+	Cookie    string
+	Crumb     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// SaveYahooSession replaces the stored session.
+func (r *Repository) SaveYahooSession(s YahooSession) error {
+	_, err := r.db.Exec(`
+		INSERT INTO yahoo_session (id, cookie, crumb, created_at, expires_at)
+		VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			cookie     = excluded.cookie,
+			crumb      = excluded.crumb,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at`,
+		s.Cookie, s.Crumb, s.CreatedAt.UTC(), s.ExpiresAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("save yahoo session: %w", err)
+	}
+	return nil
+}
+
+// GetYahooSession returns the stored session, or nil if there is none.
+func (r *Repository) GetYahooSession() (*YahooSession, error) {
+	var s YahooSession
+	err := r.db.QueryRow(`
+		SELECT cookie, crumb, created_at, expires_at
+		FROM yahoo_session WHERE id = 1`).Scan(&s.Cookie, &s.Crumb, &s.CreatedAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get yahoo session: %w", err)
+	}
+	return &s, nil
+}
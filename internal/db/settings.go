@@ -0,0 +1,58 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// GetUserSetting returns the value of a per-user setting, or "" if it is not set.
+func (r *Repository) GetUserSetting(chatID int64, key string) (string, error) {
+	var value string
+	err := r.db.QueryRow(`
+		SELECT value FROM user_settings WHERE chat_id = ? AND key = ?`, chatID, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get setting %s for %d: %w", key, chatID, err)
+	}
+	return value, nil
+}
+
+// SetUserSetting stores a per-user setting. An empty value deletes it.
+func (r *Repository) SetUserSetting(chatID int64, key, value string) error {
+	var err error
+	if value == "" {
+		_, err = r.db.Exec(`
+			DELETE FROM user_settings WHERE chat_id = ? AND key = ?`, chatID, key)
+	} else {
+		_, err = r.db.Exec(`
+			INSERT INTO user_settings (chat_id, key, value) VALUES (?, ?, ?)
+			ON CONFLICT(chat_id, key) DO UPDATE SET value = excluded.value`,
+			chatID, key, value)
+	}
+	if err != nil {
+		return fmt.Errorf("set setting %s for %d: %w", key, chatID, err)
+	}
+	return nil
+}
+
+// GetDistinctSettingValues returns every value stored for key across all users.
+func (r *Repository) GetDistinctSettingValues(key string) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT value FROM user_settings WHERE key = ? ORDER BY value`, key)
+	if err != nil {
+		return nil, fmt.Errorf("query setting values %s: %w", key, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
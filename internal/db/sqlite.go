@@ -7,37 +7,6 @@ import (
 	_ "modernc.org/sqlite"
 )
 
-const schema = `
-CREATE TABLE IF NOT EXISTS users (
-    chat_id     INTEGER PRIMARY KEY,
-    username    TEXT,
-    state       TEXT DEFAULT 'idle',
-    state_data  TEXT DEFAULT '',
-    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE IF NOT EXISTS holdings (
-    id          INTEGER PRIMARY KEY AUTOINCREMENT,
-    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
-    symbol      TEXT NOT NULL,
-    name        TEXT NOT NULL,
-    shares      REAL NOT NULL,
-    added_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
-    UNIQUE(chat_id, symbol)
-);
-
-CREATE INDEX IF NOT EXISTS idx_holdings_chat ON holdings(chat_id);
-
-CREATE TABLE IF NOT EXISTS history (
-    id          INTEGER PRIMARY KEY AUTOINCREMENT,
-    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
-    total_usd   REAL NOT NULL,
-    reported_at DATETIME DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE INDEX IF NOT EXISTS idx_history_chat ON history(chat_id);
-`
-
 // DB wraps a sql.DB with SQLite-specific setup.
 type DB struct {
 	*sql.DB
@@ -53,7 +22,8 @@ func New(path string) (*DB, error) {
 	// SQLite performs best with a single writer connection.
 	sqlDB.SetMaxOpenConns(1)
 
-	if _, err := sqlDB.Exec(schema); err != nil {
+	if err := migrate(sqlDB); err != nil {
+		_ = sqlDB.Close()
 		return nil, fmt.Errorf("migrate schema: %w", err)
 	}
 
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// SymbolInfo is the descriptive data stored for a symbol, used to group reports.
+type SymbolInfo struct {
+	Symbol    string
+	Name      string
+	QuoteType string
+	Sector    string
+	Industry  string
+	Country   string
+	UpdatedAt time.Time
+}
+
+// SaveSymbolInfo inserts or replaces the stored info for a symbol.
+func (r *Repository) SaveSymbolInfo(info SymbolInfo) error {
+	_, err := r.db.Exec(`
+		INSERT INTO symbol_info (symbol, name, quote_type, sector, industry, country, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(symbol) DO UPDATE SET
+			name       = excluded.name,
+			quote_type = excluded.quote_type,
+			sector     = excluded.sector,
+			industry   = excluded.industry,
+			country    = excluded.country,
+			updated_at = excluded.updated_at`,
+		info.Symbol, info.Name, info.QuoteType, info.Sector, info.Industry, info.Country, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("save symbol info %s: %w", info.Symbol, err)
+	}
+	return nil
+}
+
+// GetSymbolInfo returns the stored info for symbols, keyed by symbol.
+// Symbols without stored info are absent from the map.
+func (r *Repository) GetSymbolInfo(symbols []string) (map[string]SymbolInfo, error) {
+	infos := make(map[string]SymbolInfo, len(symbols))
+	if len(symbols) == 0 {
+		return infos, nil
+	}
+
+	query, args := inClause(`
+		SELECT symbol, name, quote_type, sector, industry, country, updated_at
+		FROM symbol_info
+		WHERE symbol IN (%s)`, symbols)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query symbol info: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var info SymbolInfo
+		if err := rows.Scan(&info.Symbol, &info.Name, &info.QuoteType, &info.Sector,
+			&info.Industry, &info.Country, &info.UpdatedAt); err != nil {
+			return nil, err
+		}
+		infos[info.Symbol] = info
+	}
+	return infos, rows.Err()
+}
@@ -0,0 +1,43 @@
+-- Schema of a database created by the bot before this series (users, holdings and
+-- history only, with REAL shares and totals), with a few rows to carry through every
+-- migration.
+CREATE TABLE IF NOT EXISTS users (
+    chat_id     INTEGER PRIMARY KEY,
+    username    TEXT,
+    state       TEXT DEFAULT 'idle',
+    state_data  TEXT DEFAULT '',
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS holdings (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
+    symbol      TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    shares      REAL NOT NULL,
+    added_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chat_id, symbol)
+);
+
+CREATE INDEX IF NOT EXISTS idx_holdings_chat ON holdings(chat_id);
+
+CREATE TABLE IF NOT EXISTS history (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
+    total_usd   REAL NOT NULL,
+    reported_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_chat ON history(chat_id);
+
+INSERT INTO users (chat_id, username) VALUES (1, 'alice'), (2, 'bob');
+
+INSERT INTO holdings (chat_id, symbol, name, shares, added_at) VALUES
+    (1, 'AAPL', 'Apple Inc.', 10.1, '2024-01-02 10:00:00'),
+    (1, 'MSFT', 'Microsoft Corporation', 0.5, '2024-02-03 10:00:00'),
+    (2, 'VOW3.DE', 'Volkswagen AG', 3, '2024-03-04 10:00:00');
+
+INSERT INTO history (chat_id, total_usd, reported_at) VALUES
+    (1, 1999.99, '2024-03-01 12:00:00'),
+    (1, 2100.5, '2024-03-02 12:00:00'),
+    (2, 0.1, '2024-03-01 12:00:00');
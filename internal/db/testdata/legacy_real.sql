@@ -0,0 +1,129 @@
+-- Schema of a database created just before versioned migrations, with every table the
+-- bot had by then and shares and totals still REAL, and a few rows for
+-- migrateDecimalColumns to convert.
+CREATE TABLE IF NOT EXISTS users (
+    chat_id     INTEGER PRIMARY KEY,
+    username    TEXT,
+    state       TEXT DEFAULT 'idle',
+    state_data  TEXT DEFAULT '',
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS holdings (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
+    symbol      TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    shares      REAL NOT NULL,
+    added_at    DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chat_id, symbol)
+);
+
+CREATE INDEX IF NOT EXISTS idx_holdings_chat ON holdings(chat_id);
+
+CREATE TABLE IF NOT EXISTS history (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
+    total_usd   REAL NOT NULL,
+    reported_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_chat ON history(chat_id);
+
+CREATE TABLE IF NOT EXISTS quotes (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol      TEXT NOT NULL,
+    price       REAL NOT NULL,
+    currency    TEXT NOT NULL,
+    source      TEXT NOT NULL DEFAULT '',
+    fetched_at  DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_quotes_symbol_time ON quotes(symbol, fetched_at);
+
+CREATE TABLE IF NOT EXISTS dividends (
+    symbol      TEXT NOT NULL,
+    ex_date     DATETIME NOT NULL,
+    amount      REAL NOT NULL,
+    currency    TEXT NOT NULL,
+    PRIMARY KEY (symbol, ex_date)
+);
+
+CREATE TABLE IF NOT EXISTS splits (
+    symbol      TEXT NOT NULL,
+    split_date  DATETIME NOT NULL,
+    numerator   REAL NOT NULL,
+    denominator REAL NOT NULL,
+    PRIMARY KEY (symbol, split_date)
+);
+
+CREATE TABLE IF NOT EXISTS symbol_info (
+    symbol      TEXT PRIMARY KEY,
+    name        TEXT NOT NULL DEFAULT '',
+    quote_type  TEXT NOT NULL DEFAULT '',
+    sector      TEXT NOT NULL DEFAULT '',
+    industry    TEXT NOT NULL DEFAULT '',
+    country     TEXT NOT NULL DEFAULT '',
+    updated_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
+    key         TEXT NOT NULL,
+    value       TEXT NOT NULL,
+    PRIMARY KEY (chat_id, key)
+);
+
+CREATE TABLE IF NOT EXISTS news_subscriptions (
+    chat_id     INTEGER PRIMARY KEY REFERENCES users(chat_id),
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS news_sent (
+    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
+    uuid        TEXT NOT NULL,
+    sent_at     DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chat_id, uuid)
+);
+
+CREATE TABLE IF NOT EXISTS yahoo_session (
+    id          INTEGER PRIMARY KEY CHECK (id = 1),
+    cookie      TEXT NOT NULL,
+    crumb       TEXT NOT NULL,
+    created_at  DATETIME NOT NULL,
+    expires_at  DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS holding_adjustments (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id     INTEGER NOT NULL REFERENCES users(chat_id),
+    symbol      TEXT NOT NULL,
+    reason      TEXT NOT NULL,
+    event_date  DATETIME NOT NULL,
+    detail      TEXT NOT NULL DEFAULT '',
+    old_shares  REAL NOT NULL,
+    new_shares  REAL NOT NULL,
+    created_at  DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(chat_id, symbol, reason, event_date)
+);
+
+CREATE TABLE IF NOT EXISTS fx_history (
+    from_currency TEXT NOT NULL,
+    to_currency   TEXT NOT NULL,
+    rate_date     DATETIME NOT NULL,
+    rate          REAL NOT NULL,
+    PRIMARY KEY (from_currency, to_currency, rate_date)
+);
+INSERT INTO users (chat_id, username) VALUES (1, 'alice'), (2, 'bob');
+
+INSERT INTO holdings (chat_id, symbol, name, shares, added_at) VALUES
+    (1, 'AAPL', 'Apple Inc.', 10.1, '2024-01-02 10:00:00'),
+    (1, 'BTC-USD', 'Bitcoin USD', 0.00012345, '2024-02-03 10:00:00'),
+    (2, 'VOW3.DE', 'Volkswagen AG', 3, '2024-03-04 10:00:00');
+
+INSERT INTO history (chat_id, total_usd, reported_at) VALUES
+    (1, 1999.99, '2024-03-01 12:00:00'),
+    (2, 0.1, '2024-03-01 12:00:00');
+
+INSERT INTO holding_adjustments (chat_id, symbol, reason, event_date, detail, old_shares, new_shares) VALUES
+    (1, 'AAPL', 'split', '2020-08-31 00:00:00', '4:1', 2.525, 10.1);
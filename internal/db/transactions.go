@@ -0,0 +1,259 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"stock-portfolio-bot/internal/money"
+)
+
+// Transaction kinds.
+const (
+	TxBuy   = "buy"
+	TxSell  = "sell"
+	TxSplit = "split" // shares added (or, for a reverse split, removed) by a stock split
+)
+
+// ErrInsufficientShares is returned when a transaction would leave a position with
+// fewer than zero shares at some point in its history.
+var ErrInsufficientShares = errors.New("not enough shares")
+
+// Transaction is one entry in a position's ledger. A holding's shares are the sum of
+// its transactions' quantities.
+type Transaction struct {
+	ID          int64
+	ChatID      int64
+	PortfolioID int64
+	Symbol      string
+	Kind        string
+	Date        time.Time
+	Quantity    money.Decimal  // signed change in shares: positive for buys, negative for sells
+	Price       *money.Decimal // per unit, in Currency; nil if unknown (opening balances, splits)
+	Currency    string
+	Fees        money.Decimal // in Currency
+}
+
+// RecordTransaction appends t to the ledger and updates the holding it belongs to,
+// creating it under name if needed, all in one transaction. It returns the new
+// transaction's ID and the shares held afterwards. A transaction that would take the
+// position below zero at any point is rejected with ErrInsufficientShares.
+func (r *Repository) RecordTransaction(t Transaction, name string) (int64, money.Decimal, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, money.Zero, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := insertTransaction(tx, t)
+	if err != nil {
+		return 0, money.Zero, err
+	}
+	shares, err := syncHolding(tx, t.ChatID, t.PortfolioID, t.Symbol, name)
+	if err != nil {
+		return 0, money.Zero, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, money.Zero, err
+	}
+	return id, shares, nil
+}
+
+// GetTransactions returns a position's ledger, oldest first.
+func (r *Repository) GetTransactions(portfolioID int64, symbol string) ([]Transaction, error) {
+	return queryTransactions(r.db, portfolioID, symbol)
+}
+
+// GetPortfolioTransactions returns the ledgers of all positions in a portfolio, open
+// and closed, keyed by symbol, each oldest first.
+func (r *Repository) GetPortfolioTransactions(portfolioID int64) (map[string][]Transaction, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT symbol FROM transactions WHERE portfolio_id = ?`, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("query traded symbols in portfolio %d: %w", portfolioID, err)
+	}
+	var symbols []string
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		symbols = append(symbols, symbol)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	ledgers := make(map[string][]Transaction, len(symbols))
+	for _, symbol := range symbols {
+		txs, err := queryTransactions(r.db, portfolioID, symbol)
+		if err != nil {
+			return nil, err
+		}
+		ledgers[symbol] = txs
+	}
+	return ledgers, nil
+}
+
+func insertTransaction(tx *sql.Tx, t Transaction) (int64, error) {
+	res, err := tx.Exec(`
+		INSERT INTO transactions (chat_id, portfolio_id, symbol, kind, trade_date, quantity, price, currency, fees)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ChatID, t.PortfolioID, t.Symbol, t.Kind, t.Date.UTC(), t.Quantity, t.Price, t.Currency, t.Fees,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("insert transaction %d %s: %w", t.ChatID, t.Symbol, err)
+	}
+	return res.LastInsertId()
+}
+
+// querier is satisfied by *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func queryTransactions(q querier, portfolioID int64, symbol string) ([]Transaction, error) {
+	rows, err := q.Query(`
+		SELECT id, chat_id, portfolio_id, symbol, kind, trade_date, quantity, price, currency, fees
+		FROM transactions
+		WHERE portfolio_id = ? AND symbol = ?`, portfolioID, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("query transactions %d %s: %w", portfolioID, symbol, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var txs []Transaction
+	for rows.Next() {
+		var t Transaction
+		if err := rows.Scan(&t.ID, &t.ChatID, &t.PortfolioID, &t.Symbol, &t.Kind, &t.Date,
+			&t.Quantity, &t.Price, &t.Currency, &t.Fees); err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Sorted here rather than in SQL: opening balances carry SQLite's timestamp
+	// format, which does not order reliably against Go's.
+	sort.SliceStable(txs, func(i, j int) bool {
+		if !txs[i].Date.Equal(txs[j].Date) {
+			return txs[i].Date.Before(txs[j].Date)
+		}
+		return txs[i].ID < txs[j].ID
+	})
+	return txs, nil
+}
+
+// syncHolding recomputes a position's holding row from its ledger: the shares held
+// and the date the current run of holding them began. Split entries are re-derived
+// first. A position sold down to zero has its row removed; the ledger is kept. An
+// empty name keeps the stored one.
+func syncHolding(tx *sql.Tx, chatID, portfolioID int64, symbol, name string) (money.Decimal, error) {
+	txs, err := queryTransactions(tx, portfolioID, symbol)
+	if err != nil {
+		return money.Zero, err
+	}
+	if err := rederiveSplits(tx, symbol, txs); err != nil {
+		return money.Zero, err
+	}
+
+	shares := money.Zero
+	var since time.Time
+	for _, t := range txs {
+		if shares.IsZero() {
+			since = t.Date
+		}
+		shares = shares.Add(t.Quantity)
+		if shares.Sign() < 0 {
+			return money.Zero, fmt.Errorf("%w: %s on %s", ErrInsufficientShares, symbol, t.Date.Format("2006-01-02"))
+		}
+	}
+
+	if shares.IsZero() {
+		if _, err := tx.Exec(`
+			DELETE FROM holdings WHERE portfolio_id = ? AND symbol = ?`, portfolioID, symbol); err != nil {
+			return money.Zero, fmt.Errorf("close holding %d %s: %w", portfolioID, symbol, err)
+		}
+		return shares, nil
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO holdings (chat_id, portfolio_id, symbol, name, shares, added_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(portfolio_id, symbol) DO UPDATE SET
+			name     = COALESCE(NULLIF(excluded.name, ''), holdings.name),
+			shares   = excluded.shares,
+			added_at = excluded.added_at`,
+		chatID, portfolioID, symbol, name, shares, since.UTC(),
+	); err != nil {
+		return money.Zero, fmt.Errorf("update holding %d %s: %w", portfolioID, symbol, err)
+	}
+	return shares, nil
+}
+
+// rederiveSplits recomputes the quantity of each split entry in a position's ledger
+// (oldest first) from the split's ratio and the shares held before it, and stores the
+// ones that changed. A trade recorded after a split was applied but dated before it
+// is scaled this way too. Split entries with no stored ratio are left as they are.
+func rederiveSplits(tx *sql.Tx, symbol string, txs []Transaction) error {
+	hasSplit := false
+	for _, t := range txs {
+		hasSplit = hasSplit || t.Kind == TxSplit
+	}
+	if !hasSplit {
+		return nil
+	}
+
+	rows, err := tx.Query(`
+		SELECT symbol, split_date, numerator, denominator FROM splits WHERE symbol = ?`, symbol)
+	if err != nil {
+		return fmt.Errorf("query splits %s: %w", symbol, err)
+	}
+	splits, err := scanSplits(rows)
+	if err != nil {
+		return err
+	}
+
+	for i, t := range txs {
+		if t.Kind != TxSplit {
+			continue
+		}
+		var split *SplitRecord
+		for j := range splits {
+			if splits[j].Date.Equal(t.Date) {
+				split = &splits[j]
+			}
+		}
+		if split == nil || split.Numerator <= 0 || split.Denominator <= 0 {
+			continue
+		}
+
+		// Held before the split: everything dated earlier, as ApplySplit counts it.
+		held := money.Zero
+		for _, prev := range txs {
+			if prev.Date.Before(t.Date) {
+				held = held.Add(prev.Quantity)
+			}
+		}
+		delta := money.Zero
+		if held.Sign() > 0 {
+			after, err := held.MulDiv(money.FromFloat(split.Numerator), money.FromFloat(split.Denominator))
+			if err != nil {
+				return fmt.Errorf("split %s: %w", symbol, err)
+			}
+			delta = after.Sub(held)
+		}
+		if delta == t.Quantity {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE transactions SET quantity = ? WHERE id = ?`, delta, t.ID); err != nil {
+			return fmt.Errorf("update split %s %s: %w", symbol, t.Date.Format("2006-01-02"), err)
+		}
+		txs[i].Quantity = delta
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the upstream while its circuit is open.
+var ErrCircuitOpen = errors.New("circuit open: provider unavailable")
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+)
+
+// BreakerConfig tunes a provider's circuit breaker. Zero fields use the defaults.
+type BreakerConfig struct {
+	FailureThreshold int           // consecutive failures that open the circuit
+	OpenTimeout      time.Duration // how long the circuit stays open before a trial call
+}
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // calls pass through
+	CircuitOpen     CircuitState = "open"      // calls fail fast with ErrCircuitOpen
+	CircuitHalfOpen CircuitState = "half-open" // one trial call decides whether to close
+)
+
+// ProviderHealth is a snapshot of one provider's circuit and call outcomes.
+type ProviderHealth struct {
+	Name                string
+	State               CircuitState
+	ConsecutiveFailures int
+	OpenedAt            time.Time // zero unless open or half-open
+	Successes           int       // calls since start
+	Failures            int
+	Rejected            int // calls failed fast while open
+	LastError           string
+	LastErrorAt         time.Time
+	Session             *SessionState // Yahoo only
+}
+
+// HealthReporter is implemented by providers that track their upstream health.
+type HealthReporter interface {
+	Health() []ProviderHealth
+}
+
+// circuitBreaker stops calling an upstream after repeated failures and lets a
+// single trial call through once OpenTimeout has passed.
+type circuitBreaker struct {
+	name string
+	cfg  BreakerConfig
+
+	mu          sync.Mutex
+	state       CircuitState
+	failures    int // consecutive
+	openedAt    time.Time
+	trialActive bool
+
+	successes, totalFailures, rejected int
+	lastError                          string
+	lastErrorAt                        time.Time
+}
+
+func newCircuitBreaker(name string, cfg BreakerConfig) *circuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaultOpenTimeout
+	}
+	return &circuitBreaker{name: name, cfg: cfg, state: CircuitClosed}
+}
+
+// do runs fn unless the circuit is open, and records its outcome.
+func (cb *circuitBreaker) do(fn func() error) error {
+	if err := cb.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	cb.record(err)
+	return err
+}
+
+// rejecting reports whether a call made now would fail fast, without counting it.
+func (cb *circuitBreaker) rejecting() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case CircuitOpen:
+		return time.Since(cb.openedAt) < cb.cfg.OpenTimeout
+	case CircuitHalfOpen:
+		return cb.trialActive
+	}
+	return false
+}
+
+func (cb *circuitBreaker) allow() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitOpen:
+		if time.Since(cb.openedAt) < cb.cfg.OpenTimeout {
+			cb.rejected++
+			return fmt.Errorf("%s: %w", cb.name, ErrCircuitOpen)
+		}
+		cb.transition(CircuitHalfOpen)
+		cb.trialActive = true
+	case CircuitHalfOpen:
+		if cb.trialActive {
+			cb.rejected++
+			return fmt.Errorf("%s: %w", cb.name, ErrCircuitOpen)
+		}
+		cb.trialActive = true
+	}
+	return nil
+}
+
+func (cb *circuitBreaker) record(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == CircuitHalfOpen {
+		cb.trialActive = false
+	}
+
+	if !isUpstreamFailure(err) {
+		cb.successes++
+		cb.failures = 0
+		if cb.state != CircuitClosed {
+			cb.transition(CircuitClosed)
+		}
+		return
+	}
+
+	cb.totalFailures++
+	cb.failures++
+	cb.lastError = err.Error()
+	cb.lastErrorAt = time.Now()
+	if cb.state == CircuitHalfOpen || cb.failures >= cb.cfg.FailureThreshold {
+		cb.openedAt = time.Now()
+		if cb.state != CircuitOpen {
+			cb.transition(CircuitOpen)
+		}
+	}
+}
+
+// transition changes state and logs it. The caller must hold mu.
+func (cb *circuitBreaker) transition(to CircuitState) {
+	from := cb.state
+	cb.state = to
+	switch to {
+	case CircuitOpen:
+		log.Printf("circuit %s: %s -> open after %d consecutive failures, retry in %s (last error: %s)",
+			cb.name, from, cb.failures, cb.cfg.OpenTimeout, cb.lastError)
+	default:
+		log.Printf("circuit %s: %s -> %s", cb.name, from, to)
+	}
+	if to == CircuitClosed {
+		cb.openedAt = time.Time{}
+	}
+}
+
+func (cb *circuitBreaker) health() ProviderHealth {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return ProviderHealth{
+		Name:                cb.name,
+		State:               cb.state,
+		ConsecutiveFailures: cb.failures,
+		OpenedAt:            cb.openedAt,
+		Successes:           cb.successes,
+		Failures:            cb.totalFailures,
+		Rejected:            cb.rejected,
+		LastError:           cb.lastError,
+		LastErrorAt:         cb.lastErrorAt,
+	}
+}
+
+// isUpstreamFailure reports whether err means the upstream itself is unhealthy.
+// Answers about a particular symbol and caller cancellations do not count.
+func isUpstreamFailure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrSymbolNotFound),
+		errors.Is(err, ErrNoPrice),
+		errors.Is(err, ErrUnsupported),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
+
+// Health reports the state of every provider in the chain that tracks it.
+func (fp *FallbackProvider) Health() []ProviderHealth {
+	var health []ProviderHealth
+	for _, p := range fp.providers {
+		if hr, ok := p.(HealthReporter); ok {
+			health = append(health, hr.Health()...)
+		}
+	}
+	return health
+}
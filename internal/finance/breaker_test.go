@@ -0,0 +1,75 @@
+package finance
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	cb := newCircuitBreaker("test", BreakerConfig{FailureThreshold: 3, OpenTimeout: 20 * time.Millisecond})
+	down := errors.New("503 service unavailable")
+	fail := func() error { return down }
+	succeed := func() error { return nil }
+
+	expect := func(step string, want CircuitState) {
+		t.Helper()
+		if got := cb.health().State; got != want {
+			t.Fatalf("%s: state %s, want %s", step, got, want)
+		}
+	}
+
+	// Answers about a symbol are not the upstream failing.
+	for range 5 {
+		_ = cb.do(func() error { return fmt.Errorf("NOPE: %w", ErrSymbolNotFound) })
+	}
+	expect("after not-found answers", CircuitClosed)
+
+	for range 2 {
+		_ = cb.do(fail)
+	}
+	expect("below the threshold", CircuitClosed)
+	_ = cb.do(fail)
+	expect("at the threshold", CircuitOpen)
+
+	called := false
+	if err := cb.do(func() error { called = true; return nil }); !errors.Is(err, ErrCircuitOpen) || called {
+		t.Fatalf("call while open: err %v, called %v; want ErrCircuitOpen without calling", err, called)
+	}
+	if !cb.rejecting() {
+		t.Error("rejecting() = false while open")
+	}
+
+	// After the timeout a single trial goes through; a failing one reopens at once.
+	time.Sleep(25 * time.Millisecond)
+	if cb.rejecting() {
+		t.Error("rejecting() = true once the timeout has passed")
+	}
+	err := cb.do(func() error {
+		expect("during the trial", CircuitHalfOpen)
+		if err := cb.do(succeed); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("second call during the trial: err %v, want ErrCircuitOpen", err)
+		}
+		return down
+	})
+	if !errors.Is(err, down) {
+		t.Fatalf("trial err = %v, want the upstream error", err)
+	}
+	expect("after a failed trial", CircuitOpen)
+
+	// A successful trial closes the circuit.
+	time.Sleep(25 * time.Millisecond)
+	if err := cb.do(succeed); err != nil {
+		t.Fatalf("trial: %v", err)
+	}
+	expect("after a successful trial", CircuitClosed)
+
+	h := cb.health()
+	if h.ConsecutiveFailures != 0 || !h.OpenedAt.IsZero() {
+		t.Errorf("closed health = %+v, want no consecutive failures or open time", h)
+	}
+	if h.Failures != 4 || h.Rejected != 2 || h.LastError != down.Error() {
+		t.Errorf("health = %+v, want 4 failures, 2 rejected calls and the last error", h)
+	}
+}
@@ -1,29 +1,46 @@
 package finance
 
 import (
+	"context"
+	"log"
+	"strings"
 	"sync"
 	"time"
 )
 
+// CacheStore persists cache writes so prices and rates survive restarts.
+// FX rates are saved as <FROM><TO>=X quotes priced in the To currency.
+type CacheStore interface {
+	SaveQuotes(quotes []Quote) error
+}
+
 // PriceCache is a thread-safe in-memory cache for stock quotes with TTL expiry.
 // It is shared across all users so each symbol is fetched at most once per TTL window.
+// Expired entries are retained as last-known prices for when a refresh fails.
 type PriceCache struct {
-	mu    sync.RWMutex
+	mu    sync.Mutex
 	items map[string]cachedQuote
 	ttl   time.Duration
+	store CacheStore
+
+	flights flightGroup[Quote]
 }
 
 type cachedQuote struct {
 	quote     Quote
 	fetchedAt time.Time
+	hot       bool // read at least once since it was stored
 }
 
-// ExchangeRateCache is a thread-safe in-memory cache for FX rates with TTL expiry.
-// Rates are keyed by currency code and represent USD->currency conversions.
+// ExchangeRateCache is a thread-safe in-memory cache for FX rates with TTL expiry,
+// keyed by currency pair.
 type ExchangeRateCache struct {
 	mu    sync.RWMutex
-	items map[string]cachedRate
+	items map[CurrencyPair]cachedRate
 	ttl   time.Duration
+	store CacheStore
+
+	flights flightGroup[float64]
 }
 
 type cachedRate struct {
@@ -31,6 +48,19 @@ type cachedRate struct {
 	fetchedAt time.Time
 }
 
+// HistoryCache is a thread-safe in-memory cache for candle series with TTL expiry,
+// keyed by symbol, range and interval.
+type HistoryCache struct {
+	mu    sync.RWMutex
+	items map[string]cachedSeries
+	ttl   time.Duration
+}
+
+type cachedSeries struct {
+	series    *CandleSeries
+	fetchedAt time.Time
+}
+
 // NewPriceCache creates a PriceCache with the given TTL.
 func NewPriceCache(ttl time.Duration) *PriceCache {
 	return &PriceCache{
@@ -41,38 +71,50 @@ func NewPriceCache(ttl time.Duration) *PriceCache {
 
 // Get returns a cached quote if it exists and has not expired.
 func (pc *PriceCache) Get(symbol string) (Quote, bool) {
-	pc.mu.RLock()
-	defer pc.mu.RUnlock()
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
 
 	item, ok := pc.items[symbol]
 	if !ok || time.Since(item.fetchedAt) > pc.ttl {
 		return Quote{}, false
 	}
+	item.hot = true
+	pc.items[symbol] = item
 	return item.quote, true
 }
 
-// Set stores a quote with the current timestamp.
-func (pc *PriceCache) Set(symbol string, q Quote) {
+// GetStale returns the last known quote for symbol regardless of age, marked as stale.
+func (pc *PriceCache) GetStale(symbol string) (Quote, bool) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
-	pc.items[symbol] = cachedQuote{
-		quote:     q,
-		fetchedAt: time.Now(),
+	item, ok := pc.items[symbol]
+	if !ok {
+		return Quote{}, false
 	}
+	q := item.quote
+	q.Stale = true
+	return q, true
+}
+
+// Set stores a quote, timestamped with q.AsOf or the current time if unset.
+func (pc *PriceCache) Set(symbol string, q Quote) {
+	pc.SetMulti(map[string]Quote{symbol: q})
 }
 
 // GetMulti performs a bulk cache lookup.
 // Returns the cached quotes that are still fresh and the list of symbols that need fetching.
 func (pc *PriceCache) GetMulti(symbols []string) (found map[string]Quote, missing []string) {
-	pc.mu.RLock()
-	defer pc.mu.RUnlock()
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
 
 	found = make(map[string]Quote)
 	for _, sym := range symbols {
 		item, ok := pc.items[sym]
 		if ok && time.Since(item.fetchedAt) <= pc.ttl {
 			found[sym] = item.quote
+			item.hot = true
+			pc.items[sym] = item
 		} else {
 			missing = append(missing, sym)
 		}
@@ -80,31 +122,127 @@ func (pc *PriceCache) GetMulti(symbols []string) (found map[string]Quote, missin
 	return
 }
 
-// SetMulti stores multiple quotes at once.
+// SetMulti stores multiple quotes at once, each timestamped with its AsOf or the
+// current time if unset. Stale quotes are ignored so they never count as fresh.
+// Stored quotes are written through to the CacheStore, if any.
 func (pc *PriceCache) SetMulti(quotes map[string]Quote) {
+	stored := pc.setMulti(quotes)
+	if pc.store != nil && len(stored) > 0 {
+		if err := pc.store.SaveQuotes(stored); err != nil {
+			log.Printf("price cache: persist %d quotes: %v", len(stored), err)
+		}
+	}
+}
+
+// SetStore enables write-through persistence. It must be called before the cache is shared.
+func (pc *PriceCache) SetStore(store CacheStore) {
+	pc.store = store
+}
+
+// Restore loads previously persisted quotes without writing them back to the store.
+// Quotes keep their AsOf time, so old ones are only served as stale.
+func (pc *PriceCache) Restore(quotes []Quote) {
+	m := make(map[string]Quote, len(quotes))
+	for _, q := range quotes {
+		m[q.Symbol] = q
+	}
+	pc.setMulti(m)
+}
+
+func (pc *PriceCache) setMulti(quotes map[string]Quote) []Quote {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
 	now := time.Now()
+	stored := make([]Quote, 0, len(quotes))
 	for sym, q := range quotes {
-		pc.items[sym] = cachedQuote{quote: q, fetchedAt: now}
+		if q.Stale {
+			continue
+		}
+		if q.AsOf.IsZero() {
+			q.AsOf = now
+		}
+		if existing, ok := pc.items[sym]; ok && existing.fetchedAt.After(q.AsOf) {
+			continue
+		}
+		pc.items[sym] = cachedQuote{quote: q, fetchedAt: q.AsOf}
+		stored = append(stored, q)
 	}
+	return stored
+}
+
+// GetOrFetch returns fresh cached quotes and fetches the rest. Concurrent callers
+// missing the same symbol share a single in-flight fetch, whose results are stored
+// in the cache. Symbols that fail to refresh are served from their last known
+// quote with Stale set; fetch's error is still returned so callers can log it.
+func (pc *PriceCache) GetOrFetch(ctx context.Context, symbols []string,
+	fetch func(ctx context.Context, symbols []string) (map[string]Quote, error),
+) (map[string]Quote, error) {
+	found, missing := pc.GetMulti(symbols)
+	if len(missing) == 0 {
+		return found, nil
+	}
+
+	fetched, err := pc.flights.do(ctx, missing, func(ctx context.Context, keys []string) (map[string]Quote, error) {
+		// Another flight may have filled some keys since our lookup.
+		fresh, stillMissing := pc.GetMulti(keys)
+		if len(stillMissing) == 0 {
+			return fresh, nil
+		}
+		quotes, err := fetch(ctx, stillMissing)
+		if quotes == nil {
+			quotes = make(map[string]Quote, len(fresh))
+		}
+		pc.SetMulti(quotes)
+		for sym, q := range fresh {
+			quotes[sym] = q
+		}
+		return quotes, err
+	})
+	for sym, q := range fetched {
+		found[sym] = q
+	}
+	for _, sym := range missing {
+		if _, ok := found[sym]; ok {
+			continue
+		}
+		if q, ok := pc.GetStale(sym); ok {
+			found[sym] = q
+		}
+	}
+	return found, err
+}
+
+// Expiring returns the symbols that were read since they were stored and will
+// expire within d, i.e. the ones worth refreshing ahead of time.
+func (pc *PriceCache) Expiring(d time.Duration) []string {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	var symbols []string
+	for sym, item := range pc.items {
+		age := time.Since(item.fetchedAt)
+		if item.hot && age <= pc.ttl && age > pc.ttl-d {
+			symbols = append(symbols, sym)
+		}
+	}
+	return symbols
 }
 
 // NewExchangeRateCache creates an ExchangeRateCache with the given TTL.
 func NewExchangeRateCache(ttl time.Duration) *ExchangeRateCache {
 	return &ExchangeRateCache{
-		items: make(map[string]cachedRate),
+		items: make(map[CurrencyPair]cachedRate),
 		ttl:   ttl,
 	}
 }
 
 // Get returns a cached rate if it exists and has not expired.
-func (rc *ExchangeRateCache) Get(currency string) (float64, bool) {
+func (rc *ExchangeRateCache) Get(pair CurrencyPair) (float64, bool) {
 	rc.mu.RLock()
 	defer rc.mu.RUnlock()
 
-	item, ok := rc.items[currency]
+	item, ok := rc.items[pair]
 	if !ok || time.Since(item.fetchedAt) > rc.ttl {
 		return 0, false
 	}
@@ -112,41 +250,312 @@ func (rc *ExchangeRateCache) Get(currency string) (float64, bool) {
 }
 
 // Set stores a rate with the current timestamp.
-func (rc *ExchangeRateCache) Set(currency string, rate float64) {
-	rc.mu.Lock()
-	defer rc.mu.Unlock()
-
-	rc.items[currency] = cachedRate{
-		rate:      rate,
-		fetchedAt: time.Now(),
-	}
+func (rc *ExchangeRateCache) Set(pair CurrencyPair, rate float64) {
+	rc.SetMulti(map[CurrencyPair]float64{pair: rate})
 }
 
 // GetMulti performs a bulk cache lookup.
-// Returns the cached rates that are still fresh and the list of currency codes that need fetching.
-func (rc *ExchangeRateCache) GetMulti(currencies []string) (found map[string]float64, missing []string) {
+// Returns the cached rates that are still fresh and the list of pairs that need fetching.
+func (rc *ExchangeRateCache) GetMulti(pairs []CurrencyPair) (found map[CurrencyPair]float64, missing []CurrencyPair) {
 	rc.mu.RLock()
 	defer rc.mu.RUnlock()
 
-	found = make(map[string]float64)
-	for _, currency := range currencies {
-		item, ok := rc.items[currency]
+	found = make(map[CurrencyPair]float64)
+	for _, pair := range pairs {
+		item, ok := rc.items[pair]
 		if ok && time.Since(item.fetchedAt) <= rc.ttl {
-			found[currency] = item.rate
+			found[pair] = item.rate
 		} else {
-			missing = append(missing, currency)
+			missing = append(missing, pair)
 		}
 	}
 	return
 }
 
-// SetMulti stores multiple rates at once.
-func (rc *ExchangeRateCache) SetMulti(rates map[string]float64) {
+// SetMulti stores multiple rates at once and writes them through to the CacheStore, if any.
+func (rc *ExchangeRateCache) SetMulti(rates map[CurrencyPair]float64) {
+	rc.mu.Lock()
+	now := time.Now()
+	for pair, rate := range rates {
+		rc.items[pair] = cachedRate{rate: rate, fetchedAt: now}
+	}
+	rc.mu.Unlock()
+
+	if rc.store == nil || len(rates) == 0 {
+		return
+	}
+	quotes := make([]Quote, 0, len(rates))
+	for pair, rate := range rates {
+		if pair.From == pair.To || rate <= 0 {
+			continue
+		}
+		quotes = append(quotes, Quote{
+			Symbol:   pair.Symbol(),
+			Price:    rate,
+			Currency: pair.To,
+			Source:   "fx",
+			AsOf:     now,
+		})
+	}
+	if err := rc.store.SaveQuotes(quotes); err != nil {
+		log.Printf("rate cache: persist %d rates: %v", len(quotes), err)
+	}
+}
+
+// SetStore enables write-through persistence. It must be called before the cache is shared.
+func (rc *ExchangeRateCache) SetStore(store CacheStore) {
+	rc.store = store
+}
+
+// Restore loads previously persisted <FROM><TO>=X quotes as rates, keeping their
+// fetch time. Quotes for other symbols are ignored.
+func (rc *ExchangeRateCache) Restore(quotes []Quote) {
 	rc.mu.Lock()
 	defer rc.mu.Unlock()
 
+	for _, q := range quotes {
+		pair, ok := ParseFXSymbol(q.Symbol)
+		if !ok || q.Price <= 0 {
+			continue
+		}
+		if existing, ok := rc.items[pair]; ok && existing.fetchedAt.After(q.AsOf) {
+			continue
+		}
+		rc.items[pair] = cachedRate{rate: q.Price, fetchedAt: q.AsOf}
+	}
+}
+
+// GetOrFetch returns fresh cached rates and fetches the rest. Concurrent callers
+// missing the same pair share a single in-flight fetch, whose results are stored
+// in the cache. Pairs that fail to refresh fall back to their last known rate;
+// fetch's error is still returned so callers can log it.
+func (rc *ExchangeRateCache) GetOrFetch(ctx context.Context, pairs []CurrencyPair,
+	fetch func(ctx context.Context, pairs []CurrencyPair) (map[CurrencyPair]float64, error),
+) (map[CurrencyPair]float64, error) {
+	found, missing := rc.GetMulti(pairs)
+	if len(missing) == 0 {
+		return found, nil
+	}
+
+	keys := make([]string, len(missing))
+	for i, pair := range missing {
+		keys[i] = pair.Symbol()
+	}
+	fetched, err := rc.flights.do(ctx, keys, func(ctx context.Context, keys []string) (map[string]float64, error) {
+		want := make([]CurrencyPair, 0, len(keys))
+		for _, key := range keys {
+			if pair, ok := ParseFXSymbol(key); ok {
+				want = append(want, pair)
+			}
+		}
+		fresh, stillMissing := rc.GetMulti(want)
+		rates := make(map[string]float64, len(want))
+		for pair, rate := range fresh {
+			rates[pair.Symbol()] = rate
+		}
+		if len(stillMissing) == 0 {
+			return rates, nil
+		}
+		got, err := fetch(ctx, stillMissing)
+		rc.SetMulti(got)
+		for pair, rate := range got {
+			rates[pair.Symbol()] = rate
+		}
+		return rates, err
+	})
+	for _, pair := range missing {
+		if rate, ok := fetched[pair.Symbol()]; ok {
+			found[pair] = rate
+			continue
+		}
+		if rate, ok := rc.getStale(pair); ok {
+			found[pair] = rate
+		}
+	}
+	return found, err
+}
+
+// getStale returns the last known rate for pair regardless of age.
+func (rc *ExchangeRateCache) getStale(pair CurrencyPair) (float64, bool) {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+
+	item, ok := rc.items[pair]
+	return item.rate, ok
+}
+
+// NewHistoryCache creates a HistoryCache with the given TTL.
+func NewHistoryCache(ttl time.Duration) *HistoryCache {
+	return &HistoryCache{
+		items: make(map[string]cachedSeries),
+		ttl:   ttl,
+	}
+}
+
+func historyKey(symbol, rng, interval string) string {
+	return symbol + "|" + rng + "|" + interval
+}
+
+// Get returns a cached series if it exists and has not expired.
+func (hc *HistoryCache) Get(symbol, rng, interval string) (*CandleSeries, bool) {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	item, ok := hc.items[historyKey(symbol, rng, interval)]
+	if !ok || time.Since(item.fetchedAt) > hc.ttl {
+		return nil, false
+	}
+	return item.series, true
+}
+
+// Set stores a series with the current timestamp.
+func (hc *HistoryCache) Set(symbol, rng, interval string, series *CandleSeries) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.items[historyKey(symbol, rng, interval)] = cachedSeries{
+		series:    series,
+		fetchedAt: time.Now(),
+	}
+}
+
+// FundamentalsCache is a thread-safe in-memory cache for fundamentals with TTL expiry.
+type FundamentalsCache struct {
+	mu    sync.RWMutex
+	items map[string]cachedFundamentals
+	ttl   time.Duration
+}
+
+type cachedFundamentals struct {
+	fundamentals *Fundamentals
+	fetchedAt    time.Time
+}
+
+// NewFundamentalsCache creates a FundamentalsCache with the given TTL.
+func NewFundamentalsCache(ttl time.Duration) *FundamentalsCache {
+	return &FundamentalsCache{
+		items: make(map[string]cachedFundamentals),
+		ttl:   ttl,
+	}
+}
+
+// Get returns cached fundamentals if they exist and have not expired.
+func (fc *FundamentalsCache) Get(symbol string) (*Fundamentals, bool) {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	item, ok := fc.items[symbol]
+	if !ok || time.Since(item.fetchedAt) > fc.ttl {
+		return nil, false
+	}
+	return item.fundamentals, true
+}
+
+// Set stores fundamentals with the current timestamp.
+func (fc *FundamentalsCache) Set(symbol string, f *Fundamentals) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	fc.items[symbol] = cachedFundamentals{
+		fundamentals: f,
+		fetchedAt:    time.Now(),
+	}
+}
+
+// NewsCache is a thread-safe in-memory cache for per-symbol headlines with TTL expiry.
+type NewsCache struct {
+	mu    sync.RWMutex
+	items map[string]cachedNews
+	ttl   time.Duration
+}
+
+type cachedNews struct {
+	items     []NewsItem
+	fetchedAt time.Time
+}
+
+// NewNewsCache creates a NewsCache with the given TTL.
+func NewNewsCache(ttl time.Duration) *NewsCache {
+	return &NewsCache{
+		items: make(map[string]cachedNews),
+		ttl:   ttl,
+	}
+}
+
+// Get returns cached headlines for symbol if they exist and have not expired.
+func (nc *NewsCache) Get(symbol string) ([]NewsItem, bool) {
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+
+	item, ok := nc.items[symbol]
+	if !ok || time.Since(item.fetchedAt) > nc.ttl {
+		return nil, false
+	}
+	return item.items, true
+}
+
+// Set stores headlines for symbol with the current timestamp.
+func (nc *NewsCache) Set(symbol string, items []NewsItem) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	nc.items[symbol] = cachedNews{
+		items:     items,
+		fetchedAt: time.Now(),
+	}
+}
+
+// SearchCache is a thread-safe in-memory cache for search results with TTL expiry,
+// keyed by the case-folded query.
+type SearchCache struct {
+	mu    sync.RWMutex
+	items map[string]cachedSearch
+	ttl   time.Duration
+}
+
+type cachedSearch struct {
+	results   []TickerResult
+	fetchedAt time.Time
+}
+
+// NewSearchCache creates a SearchCache with the given TTL.
+func NewSearchCache(ttl time.Duration) *SearchCache {
+	return &SearchCache{
+		items: make(map[string]cachedSearch),
+		ttl:   ttl,
+	}
+}
+
+func searchKey(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// Get returns cached results for query if they exist and have not expired.
+func (sc *SearchCache) Get(query string) ([]TickerResult, bool) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	item, ok := sc.items[searchKey(query)]
+	if !ok || time.Since(item.fetchedAt) > sc.ttl {
+		return nil, false
+	}
+	return item.results, true
+}
+
+// Set stores results for query with the current timestamp. Expired entries are
+// dropped on write, since the set of queries is unbounded.
+func (sc *SearchCache) Set(query string, results []TickerResult) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
 	now := time.Now()
-	for currency, rate := range rates {
-		rc.items[currency] = cachedRate{rate: rate, fetchedAt: now}
+	for key, item := range sc.items {
+		if now.Sub(item.fetchedAt) > sc.ttl {
+			delete(sc.items, key)
+		}
+	}
+	sc.items[searchKey(query)] = cachedSearch{
+		results:   results,
+		fetchedAt: now,
 	}
 }
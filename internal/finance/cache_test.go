@@ -0,0 +1,43 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPriceCacheServesStaleWhenRefreshFails(t *testing.T) {
+	pc := NewPriceCache(20 * time.Millisecond)
+	pc.Set("AAPL", Quote{Symbol: "AAPL", Price: 190})
+	pc.Set("MSFT", Quote{Symbol: "MSFT", Price: 410})
+	time.Sleep(30 * time.Millisecond)
+
+	down := errors.New("upstream down")
+	quotes, err := pc.GetOrFetch(context.Background(), []string{"AAPL", "MSFT", "NVDA"},
+		func(_ context.Context, symbols []string) (map[string]Quote, error) {
+			// MSFT refreshes; AAPL and NVDA fail.
+			return map[string]Quote{"MSFT": {Symbol: "MSFT", Price: 420}}, down
+		})
+	if !errors.Is(err, down) {
+		t.Errorf("err = %v, want the fetch error passed on", err)
+	}
+	if q := quotes["AAPL"]; !q.Stale || q.Price != 190 {
+		t.Errorf("AAPL = %+v, want the last known 190 marked stale", q)
+	}
+	if q := quotes["MSFT"]; q.Stale || q.Price != 420 {
+		t.Errorf("MSFT = %+v, want the refreshed 420", q)
+	}
+	if _, ok := quotes["NVDA"]; ok {
+		t.Error("NVDA, never fetched, returned a quote")
+	}
+
+	// A stale quote served is not stored as fresh, so the next read fetches again.
+	pc.SetMulti(map[string]Quote{"AAPL": quotes["AAPL"]})
+	if _, ok := pc.Get("AAPL"); ok {
+		t.Error("stale AAPL became fresh by being stored")
+	}
+	if q, ok := pc.GetStale("AAPL"); !ok || !q.Stale || q.Price != 190 {
+		t.Errorf("GetStale(AAPL) = %+v, %v; want 190 marked stale", q, ok)
+	}
+}
@@ -0,0 +1,117 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sentinel errors returned (wrapped) by providers. Match them with errors.Is.
+var (
+	ErrUnsupported    = errors.New("operation not supported by provider")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrSymbolNotFound = errors.New("symbol not found")
+	ErrNoPrice        = errors.New("no price data")
+)
+
+const (
+	maxRateLimitRetries = 3
+	baseBackoff         = 500 * time.Millisecond
+	maxBackoff          = 30 * time.Second
+)
+
+// HTTPError is a non-200 response from an upstream endpoint.
+// It matches ErrUnauthorized, ErrRateLimited and ErrSymbolNotFound by status code.
+type HTTPError struct {
+	StatusCode int
+	RetryAfter time.Duration // parsed Retry-After header, zero if absent
+}
+
+func newHTTPError(resp *http.Response) *HTTPError {
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.StatusCode)
+}
+
+// Is maps status codes onto the package sentinel errors.
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrSymbolNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// SymbolError attaches the symbol a fetch failed for to the underlying error.
+type SymbolError struct {
+	Symbol string
+	Err    error
+}
+
+func (e *SymbolError) Error() string { return e.Symbol + ": " + e.Err.Error() }
+
+func (e *SymbolError) Unwrap() error { return e.Err }
+
+// parseRetryAfter accepts both delta-seconds and HTTP-date forms.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// withRateLimitRetry calls fn, retrying with exponential backoff and jitter while it
+// fails with ErrRateLimited. A Retry-After hint from the server takes precedence.
+func withRateLimitRetry(ctx context.Context, fn func() error) error {
+	err := fn()
+	for attempt := 0; attempt < maxRateLimitRetries && errors.Is(err, ErrRateLimited); attempt++ {
+		var retryAfter time.Duration
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			retryAfter = httpErr.RetryAfter
+		}
+
+		timer := time.NewTimer(backoffDelay(attempt, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = fn()
+	}
+	return err
+}
+
+// backoffDelay returns the wait before retry number attempt (0-based): the server's
+// Retry-After if given, otherwise baseBackoff·2^attempt with ±50% jitter, capped at maxBackoff.
+func backoffDelay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, maxBackoff)
+	}
+	d := baseBackoff << attempt
+	jitter := time.Duration(rand.Int64N(int64(d))) - d/2
+	return min(d+jitter, maxBackoff)
+}
@@ -0,0 +1,115 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"7", 7 * time.Second},
+		{"0", 0},
+		{"-3", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0}, // already passed
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+
+	// HTTP dates have whole-second precision.
+	date := time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 88*time.Second || got > 90*time.Second {
+		t.Errorf("parseRetryAfter(%q) = %v, want about 90s", date, got)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	if got := backoffDelay(0, 2*time.Second); got != 2*time.Second {
+		t.Errorf("with Retry-After 2s: %v, want 2s", got)
+	}
+	if got := backoffDelay(0, time.Hour); got != maxBackoff {
+		t.Errorf("with Retry-After 1h: %v, want the %v cap", got, maxBackoff)
+	}
+	for attempt := range 3 {
+		base := baseBackoff << attempt
+		for range 20 {
+			if got := backoffDelay(attempt, 0); got < base/2 || got >= base*3/2 {
+				t.Fatalf("attempt %d: %v, want within ±50%% of %v", attempt, got, base)
+			}
+		}
+	}
+	if got := backoffDelay(10, 0); got > maxBackoff {
+		t.Errorf("attempt 10: %v, want at most %v", got, maxBackoff)
+	}
+}
+
+func TestWithRateLimitRetry(t *testing.T) {
+	limited := &HTTPError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second}
+
+	t.Run("honours Retry-After", func(t *testing.T) {
+		calls := 0
+		start := time.Now()
+		err := withRateLimitRetry(context.Background(), func() error {
+			if calls++; calls == 1 {
+				return limited
+			}
+			return nil
+		})
+		if err != nil || calls != 2 {
+			t.Fatalf("err %v after %d calls, want success on the retry", err, calls)
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("retried after %v, want the server's 1s", elapsed)
+		}
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		calls := 0
+		notFound := &HTTPError{StatusCode: http.StatusNotFound}
+		err := withRateLimitRetry(context.Background(), func() error { calls++; return notFound })
+		if !errors.Is(err, ErrSymbolNotFound) || calls != 1 {
+			t.Errorf("err %v after %d calls, want ErrSymbolNotFound after one", err, calls)
+		}
+	})
+
+	t.Run("stops when the context ends", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		calls := 0
+		err := withRateLimitRetry(ctx, func() error { calls++; return limited })
+		if !errors.Is(err, ErrRateLimited) || calls != 1 {
+			t.Errorf("err %v after %d calls, want ErrRateLimited without retrying", err, calls)
+		}
+	})
+}
+
+func TestHTTPErrorIs(t *testing.T) {
+	tests := []struct {
+		status int
+		target error
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusNotFound, ErrSymbolNotFound},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if err := newHTTPError(resp); !errors.Is(err, tt.target) {
+			t.Errorf("HTTP %d does not match %v", tt.status, tt.target)
+		}
+	}
+	if err := (&HTTPError{StatusCode: http.StatusBadGateway}); errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnauthorized) {
+		t.Errorf("HTTP 502 matches a sentinel")
+	}
+}
@@ -0,0 +1,147 @@
+package finance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Dividend is a cash distribution keyed by its ex-dividend date.
+type Dividend struct {
+	Symbol   string
+	ExDate   time.Time
+	Amount   float64 // per share, in Currency (subunits already converted)
+	Currency string
+}
+
+// Split is a stock split; a 4:1 split has Numerator 4 and Denominator 1.
+type Split struct {
+	Symbol      string
+	Date        time.Time
+	Numerator   float64
+	Denominator float64
+}
+
+// Factor is the number of new shares per old share.
+func (s Split) Factor() float64 {
+	if s.Denominator == 0 {
+		return 1
+	}
+	return s.Numerator / s.Denominator
+}
+
+// Ratio formats the split as "4:1".
+func (s Split) Ratio() string {
+	return fmt.Sprintf("%g:%g", s.Numerator, s.Denominator)
+}
+
+// Events holds the corporate actions reported for a symbol, oldest first.
+type Events struct {
+	Dividends []Dividend
+	Splits    []Split
+}
+
+// EventProvider is implemented by providers that report dividends and splits.
+type EventProvider interface {
+	GetEvents(ctx context.Context, symbol, rng string) (*Events, error)
+}
+
+// GetEvents returns dividends and splits for symbol over rng (e.g. "1y", "5y", "max").
+func (yc *YahooClient) GetEvents(ctx context.Context, symbol, rng string) (*Events, error) {
+	if _, ok := validRanges[rng]; !ok {
+		return nil, fmt.Errorf("unsupported events range %q", rng)
+	}
+
+	params := url.Values{}
+	params.Set("range", rng)
+	params.Set("interval", "1mo") // events are independent of bar size; keep the payload small
+	params.Set("events", "div,split")
+
+	body, err := yc.fetchChartWithRetry(ctx, symbol, params)
+	if err != nil {
+		return nil, fmt.Errorf("fetch events %s: %w", symbol, err)
+	}
+	return parseChartEvents(body, symbol)
+}
+
+// GetEvents asks the first provider that supports events.
+func (fp *FallbackProvider) GetEvents(ctx context.Context, symbol, rng string) (*Events, error) {
+	for _, p := range fp.providers {
+		if ep, ok := p.(EventProvider); ok {
+			return ep.GetEvents(ctx, symbol, rng)
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+func parseChartEvents(body []byte, symbol string) (*Events, error) {
+	var payload struct {
+		Chart struct {
+			Result []struct {
+				Meta struct {
+					Currency string `json:"currency"`
+				} `json:"meta"`
+				Events struct {
+					Dividends map[string]struct {
+						Amount float64 `json:"amount"`
+						Date   int64   `json:"date"`
+					} `json:"dividends"`
+					Splits map[string]struct {
+						Date        int64   `json:"date"`
+						Numerator   float64 `json:"numerator"`
+						Denominator float64 `json:"denominator"`
+					} `json:"splits"`
+				} `json:"events"`
+			} `json:"result"`
+			Error *chartError `json:"error"`
+		} `json:"chart"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("parse chart response: %w", err)
+	}
+	if payload.Chart.Error != nil {
+		return nil, payload.Chart.Error.err()
+	}
+	if len(payload.Chart.Result) == 0 {
+		return nil, fmt.Errorf("no chart result for %s: %w", symbol, ErrSymbolNotFound)
+	}
+
+	result := payload.Chart.Result[0]
+	currency, divisor := normalizeYahooCurrency(result.Meta.Currency)
+	if divisor <= 0 {
+		divisor = 1
+	}
+
+	events := &Events{}
+	for _, d := range result.Events.Dividends {
+		events.Dividends = append(events.Dividends, Dividend{
+			Symbol:   symbol,
+			ExDate:   time.Unix(d.Date, 0).UTC(),
+			Amount:   d.Amount / divisor,
+			Currency: currency,
+		})
+	}
+	for _, s := range result.Events.Splits {
+		if s.Numerator <= 0 || s.Denominator <= 0 {
+			continue
+		}
+		events.Splits = append(events.Splits, Split{
+			Symbol:      symbol,
+			Date:        time.Unix(s.Date, 0).UTC(),
+			Numerator:   s.Numerator,
+			Denominator: s.Denominator,
+		})
+	}
+
+	sort.Slice(events.Dividends, func(i, j int) bool {
+		return events.Dividends[i].ExDate.Before(events.Dividends[j].ExDate)
+	})
+	sort.Slice(events.Splits, func(i, j int) bool {
+		return events.Splits[i].Date.Before(events.Splits[j].Date)
+	})
+	return events, nil
+}
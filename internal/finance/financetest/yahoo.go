@@ -0,0 +1,414 @@
+// Package financetest provides an in-process stand-in for the Yahoo Finance
+// endpoints used by finance.YahooClient, for integration tests and local runs.
+package financetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"stock-portfolio-bot/internal/finance"
+)
+
+const cookieName = "A3"
+
+// Quote is the data the fake chart endpoint reports for a symbol.
+type Quote struct {
+	Price          float64
+	PreviousClose  float64
+	Currency       string // Yahoo code, e.g. "USD" or "GBp"
+	InstrumentType string // e.g. finance.TypeEquity; defaults to EQUITY
+	ExchangeTZ     string // IANA name; defaults to America/New_York
+}
+
+// YahooServer fakes search, chart, quoteSummary, consent and crumb. Sessions are
+// issued by /consent and /v1/test/getcrumb and checked on every chart and
+// quoteSummary request, so session refresh paths run exactly as against Yahoo.
+type YahooServer struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	quotes   map[string]Quote
+	searches map[string][]finance.TickerResult
+	sessions map[string]string // cookie value → crumb
+	nextID   int
+
+	failStatus     int           // status for the next failCount chart/search requests
+	failCount      int           // -1 fails until ClearFailures
+	retryAfter     time.Duration // Retry-After sent with 429 failures
+	delay          time.Duration // added to every request, to provoke client timeouts
+	failConsent    bool
+	consentHits    int
+	crumbHits      int
+	chartHits      map[string]int
+	unauthorized   int // chart/quoteSummary requests rejected for a bad session
+	searchRequests int
+}
+
+// NewYahooServer starts a fake Yahoo server. Close it when done.
+func NewYahooServer() *YahooServer {
+	ys := &YahooServer{
+		quotes:    make(map[string]Quote),
+		searches:  make(map[string][]finance.TickerResult),
+		sessions:  make(map[string]string),
+		chartHits: make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/consent", ys.handleConsent)
+	mux.HandleFunc("/v1/test/getcrumb", ys.handleCrumb)
+	mux.HandleFunc("/v1/finance/search", ys.handleSearch)
+	mux.HandleFunc("/v8/finance/chart/", ys.handleChart)
+	mux.HandleFunc("/v10/finance/quoteSummary/", ys.handleQuoteSummary)
+	ys.srv = httptest.NewServer(ys.withDelay(mux))
+	return ys
+}
+
+// URL is the server's base URL.
+func (ys *YahooServer) URL() string { return ys.srv.URL }
+
+// Endpoints returns YahooClient endpoints pointing at this server.
+func (ys *YahooServer) Endpoints() finance.YahooEndpoints {
+	return finance.YahooEndpointsAt(ys.srv.URL)
+}
+
+// Close shuts the server down.
+func (ys *YahooServer) Close() { ys.srv.Close() }
+
+// SetQuote makes the chart endpoint report q for symbol. FX pairs use Yahoo
+// symbols, e.g. SetQuote("EURUSD=X", Quote{Price: 1.08, Currency: "USD"}).
+func (ys *YahooServer) SetQuote(symbol string, q Quote) {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	ys.quotes[symbol] = q
+}
+
+// RemoveQuote makes the chart endpoint answer 404 Not Found for symbol.
+func (ys *YahooServer) RemoveQuote(symbol string) {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	delete(ys.quotes, symbol)
+}
+
+// SetSearchResults makes the search endpoint return results for query (case-insensitive).
+func (ys *YahooServer) SetSearchResults(query string, results []finance.TickerResult) {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	ys.searches[strings.ToLower(query)] = results
+}
+
+// ExpireSessions invalidates every issued cookie and crumb, so the next chart
+// request answers 401 until the client fetches a new session.
+func (ys *YahooServer) ExpireSessions() {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	ys.sessions = make(map[string]string)
+}
+
+// FailNext makes the next n chart, quoteSummary and search requests answer status.
+// A negative n fails every request until ClearFailures. For 429, retryAfter is
+// sent as the Retry-After header if positive.
+func (ys *YahooServer) FailNext(n, status int, retryAfter time.Duration) {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	ys.failCount = n
+	ys.failStatus = status
+	ys.retryAfter = retryAfter
+}
+
+// FailConsent makes the consent endpoint return no cookie while fail is true.
+func (ys *YahooServer) FailConsent(fail bool) {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	ys.failConsent = fail
+}
+
+// SetDelay delays every response by d.
+func (ys *YahooServer) SetDelay(d time.Duration) {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	ys.delay = d
+}
+
+// ClearFailures turns off FailNext, FailConsent and SetDelay.
+func (ys *YahooServer) ClearFailures() {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	ys.failCount = 0
+	ys.failConsent = false
+	ys.delay = 0
+}
+
+// Stats counts the requests the server has handled.
+type Stats struct {
+	Consent      int
+	Crumb        int
+	Search       int
+	Chart        map[string]int // per symbol
+	Unauthorized int            // requests rejected for a missing or expired session
+}
+
+// Stats returns request counts since the server started.
+func (ys *YahooServer) Stats() Stats {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	chart := make(map[string]int, len(ys.chartHits))
+	for sym, n := range ys.chartHits {
+		chart[sym] = n
+	}
+	return Stats{
+		Consent:      ys.consentHits,
+		Crumb:        ys.crumbHits,
+		Search:       ys.searchRequests,
+		Chart:        chart,
+		Unauthorized: ys.unauthorized,
+	}
+}
+
+// --- handlers ---
+
+func (ys *YahooServer) withDelay(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ys.mu.Lock()
+		d := ys.delay
+		ys.mu.Unlock()
+		if d > 0 {
+			select {
+			case <-time.After(d):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (ys *YahooServer) handleConsent(w http.ResponseWriter, r *http.Request) {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	ys.consentHits++
+	if ys.failConsent {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	ys.nextID++
+	cookie := fmt.Sprintf("session-%d", ys.nextID)
+	ys.sessions[cookie] = ""
+	http.SetCookie(w, &http.Cookie{Name: cookieName, Value: cookie, Path: "/"})
+	// Yahoo answers the consent request with a 404 page that still sets the cookie.
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func (ys *YahooServer) handleCrumb(w http.ResponseWriter, r *http.Request) {
+	ys.mu.Lock()
+	defer ys.mu.Unlock()
+	ys.crumbHits++
+
+	cookie, ok := ys.sessionCookie(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	crumb := "crumb-" + strings.TrimPrefix(cookie, "session-")
+	ys.sessions[cookie] = crumb
+	_, _ = w.Write([]byte(crumb))
+}
+
+func (ys *YahooServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	ys.mu.Lock()
+	ys.searchRequests++
+	if ys.injectFailure(w) {
+		ys.mu.Unlock()
+		return
+	}
+	results := ys.searches[strings.ToLower(r.URL.Query().Get("q"))]
+	ys.mu.Unlock()
+
+	type quote struct {
+		Symbol    string `json:"symbol"`
+		Shortname string `json:"shortname"`
+		Exchange  string `json:"exchange"`
+		ExchDisp  string `json:"exchDisp"`
+		QuoteType string `json:"quoteType"`
+	}
+	payload := struct {
+		Quotes []quote `json:"quotes"`
+		News   []any   `json:"news"`
+	}{Quotes: []quote{}, News: []any{}}
+	for _, res := range results {
+		payload.Quotes = append(payload.Quotes, quote{
+			Symbol:    res.Symbol,
+			Shortname: res.Name,
+			Exchange:  res.Exchange,
+			ExchDisp:  res.ExchangeName,
+			QuoteType: res.Type,
+		})
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
+
+func (ys *YahooServer) handleChart(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.TrimPrefix(r.URL.Path, "/v8/finance/chart/")
+
+	ys.mu.Lock()
+	ys.chartHits[symbol]++
+	if !ys.authorized(r) {
+		ys.unauthorized++
+		ys.mu.Unlock()
+		writeJSON(w, http.StatusUnauthorized, chartError("Unauthorized", "Invalid Crumb"))
+		return
+	}
+	if ys.injectFailure(w) {
+		ys.mu.Unlock()
+		return
+	}
+	q, ok := ys.quotes[symbol]
+	ys.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, chartError("Not Found", "No data found, symbol may be delisted"))
+		return
+	}
+
+	if q.InstrumentType == "" {
+		q.InstrumentType = finance.TypeEquity
+	}
+	if q.ExchangeTZ == "" {
+		q.ExchangeTZ = "America/New_York"
+	}
+	now := time.Now().Unix()
+	meta := map[string]any{
+		"symbol":               symbol,
+		"currency":             q.Currency,
+		"instrumentType":       q.InstrumentType,
+		"exchangeTimezoneName": q.ExchangeTZ,
+		"regularMarketPrice":   q.Price,
+		"chartPreviousClose":   q.PreviousClose,
+		"previousClose":        q.PreviousClose,
+		"regularMarketDayHigh": q.Price,
+		"regularMarketDayLow":  q.Price,
+		"currentTradingPeriod": map[string]any{
+			"regular": map[string]int64{"start": now - 3600, "end": now + 3600},
+		},
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"chart": map[string]any{
+			"result": []any{map[string]any{
+				"meta":      meta,
+				"timestamp": []int64{now},
+				"indicators": map[string]any{
+					"quote": []any{map[string]any{
+						"open":   []float64{q.Price},
+						"high":   []float64{q.Price},
+						"low":    []float64{q.Price},
+						"close":  []float64{q.Price},
+						"volume": []int64{0},
+					}},
+				},
+			}},
+			"error": nil,
+		},
+	})
+}
+
+func (ys *YahooServer) handleQuoteSummary(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.TrimPrefix(r.URL.Path, "/v10/finance/quoteSummary/")
+
+	ys.mu.Lock()
+	if !ys.authorized(r) {
+		ys.unauthorized++
+		ys.mu.Unlock()
+		writeJSON(w, http.StatusUnauthorized, map[string]any{
+			"quoteSummary": map[string]any{"result": nil, "error": map[string]string{
+				"code": "Unauthorized", "description": "Invalid Crumb",
+			}},
+		})
+		return
+	}
+	if ys.injectFailure(w) {
+		ys.mu.Unlock()
+		return
+	}
+	q, ok := ys.quotes[symbol]
+	ys.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"quoteSummary": map[string]any{"result": nil, "error": map[string]string{
+				"code": "Not Found", "description": "Quote not found for symbol: " + symbol,
+			}},
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"quoteSummary": map[string]any{
+			"result": []any{map[string]any{
+				"price": map[string]any{
+					"symbol":    symbol,
+					"shortName": symbol,
+					"quoteType": q.InstrumentType,
+					"currency":  q.Currency,
+				},
+			}},
+			"error": nil,
+		},
+	})
+}
+
+// --- helpers (callers hold mu) ---
+
+// sessionCookie returns the request's session cookie if the server issued it.
+func (ys *YahooServer) sessionCookie(r *http.Request) (string, bool) {
+	c, err := r.Cookie(cookieName)
+	if err != nil {
+		return "", false
+	}
+	_, ok := ys.sessions[c.Value]
+	return c.Value, ok
+}
+
+// authorized reports whether the request carries a live cookie and its crumb.
+func (ys *YahooServer) authorized(r *http.Request) bool {
+	cookie, ok := ys.sessionCookie(r)
+	if !ok {
+		return false
+	}
+	crumb := ys.sessions[cookie]
+	return crumb != "" && r.URL.Query().Get("crumb") == crumb
+}
+
+// injectFailure writes the configured failure response and reports whether it did.
+func (ys *YahooServer) injectFailure(w http.ResponseWriter) bool {
+	if ys.failCount == 0 {
+		return false
+	}
+	if ys.failCount > 0 {
+		ys.failCount--
+	}
+	if ys.failStatus == http.StatusTooManyRequests && ys.retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(ys.retryAfter.Seconds())))
+	}
+	w.WriteHeader(ys.failStatus)
+	return true
+}
+
+func chartError(code, description string) map[string]any {
+	return map[string]any{
+		"chart": map[string]any{
+			"result": nil,
+			"error":  map[string]string{"code": code, "description": description},
+		},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,150 @@
+package finance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// fundamentalsTTL is how long fundamentals are cached; they change at most daily.
+const fundamentalsTTL = 12 * time.Hour
+
+// Fundamentals is company-level data for a symbol. Zero values mean Yahoo did not
+// report the field (e.g. P/E for loss-making companies, sector for ETFs).
+type Fundamentals struct {
+	Symbol        string
+	Name          string
+	Type          string // quote type, e.g. TypeEquity
+	Currency      string
+	MarketCap     float64 // in Currency
+	TrailingPE    float64
+	ForwardPE     float64
+	DividendYield float64 // fraction, 0.005 = 0.5%
+	Beta          float64
+	Sector        string
+	Industry      string
+	Country       string
+}
+
+// FundamentalsProvider is implemented by providers that report company fundamentals.
+type FundamentalsProvider interface {
+	GetFundamentals(ctx context.Context, symbol string) (*Fundamentals, error)
+}
+
+// GetFundamentals returns market cap, valuation ratios, dividend yield, sector and
+// industry for symbol from the quoteSummary endpoint. Results are cached; the returned
+// value is shared and must not be modified.
+func (yc *YahooClient) GetFundamentals(ctx context.Context, symbol string) (*Fundamentals, error) {
+	if f, ok := yc.fundamentals.Get(symbol); ok {
+		return f, nil
+	}
+
+	params := url.Values{}
+	params.Set("modules", "price,summaryDetail,defaultKeyStatistics,assetProfile")
+
+	body, err := yc.fetchWithRetry(ctx, "quoteSummary "+symbol, yc.endpoints.QuoteSummary+"/"+url.PathEscape(symbol), params)
+	if err != nil {
+		return nil, fmt.Errorf("fetch fundamentals %s: %w", symbol, err)
+	}
+
+	f, err := parseQuoteSummary(body, symbol)
+	if err != nil {
+		return nil, err
+	}
+	yc.fundamentals.Set(symbol, f)
+	return f, nil
+}
+
+// GetFundamentals asks the first provider that supports fundamentals.
+func (fp *FallbackProvider) GetFundamentals(ctx context.Context, symbol string) (*Fundamentals, error) {
+	for _, p := range fp.providers {
+		if fundp, ok := p.(FundamentalsProvider); ok {
+			return fundp.GetFundamentals(ctx, symbol)
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+// rawValue is the {"raw": 1.5, "fmt": "1.50"} wrapper quoteSummary uses for numbers.
+type rawValue struct {
+	Raw float64 `json:"raw"`
+}
+
+func parseQuoteSummary(body []byte, symbol string) (*Fundamentals, error) {
+	var payload struct {
+		QuoteSummary struct {
+			Result []struct {
+				Price struct {
+					Symbol    string   `json:"symbol"`
+					ShortName string   `json:"shortName"`
+					LongName  string   `json:"longName"`
+					QuoteType string   `json:"quoteType"`
+					Currency  string   `json:"currency"`
+					MarketCap rawValue `json:"marketCap"`
+				} `json:"price"`
+				SummaryDetail struct {
+					TrailingPE    rawValue `json:"trailingPE"`
+					ForwardPE     rawValue `json:"forwardPE"`
+					DividendYield rawValue `json:"dividendYield"`
+					Yield         rawValue `json:"yield"` // funds report yield instead
+					Beta          rawValue `json:"beta"`
+				} `json:"summaryDetail"`
+				DefaultKeyStatistics struct {
+					ForwardPE rawValue `json:"forwardPE"`
+				} `json:"defaultKeyStatistics"`
+				AssetProfile struct {
+					Sector   string `json:"sector"`
+					Industry string `json:"industry"`
+					Country  string `json:"country"`
+				} `json:"assetProfile"`
+			} `json:"result"`
+			Error *chartError `json:"error"`
+		} `json:"quoteSummary"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("parse quoteSummary response: %w", err)
+	}
+	if payload.QuoteSummary.Error != nil {
+		return nil, payload.QuoteSummary.Error.err()
+	}
+	if len(payload.QuoteSummary.Result) == 0 {
+		return nil, fmt.Errorf("no quoteSummary result for %s: %w", symbol, ErrSymbolNotFound)
+	}
+
+	r := payload.QuoteSummary.Result[0]
+	currency, divisor := normalizeYahooCurrency(r.Price.Currency)
+	if divisor <= 0 {
+		divisor = 1
+	}
+
+	f := &Fundamentals{
+		Symbol:        r.Price.Symbol,
+		Name:          r.Price.LongName,
+		Type:          r.Price.QuoteType,
+		Currency:      currency,
+		MarketCap:     r.Price.MarketCap.Raw / divisor,
+		TrailingPE:    r.SummaryDetail.TrailingPE.Raw,
+		ForwardPE:     r.SummaryDetail.ForwardPE.Raw,
+		DividendYield: r.SummaryDetail.DividendYield.Raw,
+		Beta:          r.SummaryDetail.Beta.Raw,
+		Sector:        r.AssetProfile.Sector,
+		Industry:      r.AssetProfile.Industry,
+		Country:       r.AssetProfile.Country,
+	}
+	if f.Symbol == "" {
+		f.Symbol = symbol
+	}
+	if f.Name == "" {
+		f.Name = r.Price.ShortName
+	}
+	if f.ForwardPE == 0 {
+		f.ForwardPE = r.DefaultKeyStatistics.ForwardPE.Raw
+	}
+	if f.DividendYield == 0 {
+		f.DividendYield = r.SummaryDetail.Yield.Raw
+	}
+	return f, nil
+}
@@ -0,0 +1,69 @@
+package finance
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseQuoteSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Fundamentals
+	}{
+		{
+			name: "equity",
+			body: `{"quoteSummary":{"result":[{
+				"price":{"symbol":"AAPL","shortName":"Apple","longName":"Apple Inc.","quoteType":"EQUITY",
+					"currency":"USD","marketCap":{"raw":3.0e12,"fmt":"3T"}},
+				"summaryDetail":{"trailingPE":{"raw":30.5},"forwardPE":{"raw":28},"dividendYield":{"raw":0.005},"beta":{"raw":1.2}},
+				"assetProfile":{"sector":"Technology","industry":"Consumer Electronics","country":"United States"}}],
+				"error":null}}`,
+			want: Fundamentals{
+				Symbol: "AAPL", Name: "Apple Inc.", Type: "EQUITY", Currency: "USD", MarketCap: 3.0e12,
+				TrailingPE: 30.5, ForwardPE: 28, DividendYield: 0.005, Beta: 1.2,
+				Sector: "Technology", Industry: "Consumer Electronics", Country: "United States",
+			},
+		},
+		{
+			name: "fund in pence with fallbacks",
+			body: `{"quoteSummary":{"result":[{
+				"price":{"shortName":"Some Trust","quoteType":"ETF","currency":"GBp","marketCap":{"raw":150000}},
+				"summaryDetail":{"yield":{"raw":0.031}},
+				"defaultKeyStatistics":{"forwardPE":{"raw":12}}}]}}`,
+			want: Fundamentals{
+				Symbol: "TRST.L", Name: "Some Trust", Type: "ETF", Currency: "GBP", MarketCap: 1500,
+				ForwardPE: 12, DividendYield: 0.031,
+			},
+		},
+	}
+	for _, tt := range tests {
+		symbol := tt.want.Symbol
+		got, err := parseQuoteSummary([]byte(tt.body), symbol)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestParseQuoteSummaryErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error // nil means any error
+	}{
+		{"not found", `{"quoteSummary":{"result":null,"error":{"code":"Not Found","description":"Quote not found"}}}`, ErrSymbolNotFound},
+		{"empty result", `{"quoteSummary":{"result":[],"error":null}}`, ErrSymbolNotFound},
+		{"malformed", `{"quoteSummary":`, nil},
+	}
+	for _, tt := range tests {
+		_, err := parseQuoteSummary([]byte(tt.body), "X")
+		if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pivotCurrency is the currency cross rates are triangulated through when a
+// provider has no direct quote for a pair.
+const pivotCurrency = "USD"
+
+// CurrencyPair is a conversion from one currency to another. Its rate is how many
+// units of To equal one unit of From.
+type CurrencyPair struct {
+	From string
+	To   string
+}
+
+// NewCurrencyPair returns the pair with both codes normalised to upper case.
+func NewCurrencyPair(from, to string) CurrencyPair {
+	return CurrencyPair{From: NormalizeCurrency(from), To: NormalizeCurrency(to)}
+}
+
+// Symbol is the Yahoo forex symbol for the pair, e.g. "EURGBP=X".
+func (p CurrencyPair) Symbol() string { return p.From + p.To + "=X" }
+
+// String renders the pair as "EUR/GBP".
+func (p CurrencyPair) String() string { return p.From + "/" + p.To }
+
+// ParseFXSymbol parses a Yahoo forex symbol such as "EURGBP=X".
+func ParseFXSymbol(symbol string) (CurrencyPair, bool) {
+	codes, ok := strings.CutSuffix(strings.ToUpper(symbol), "=X")
+	if !ok || len(codes) != 6 {
+		return CurrencyPair{}, false
+	}
+	return CurrencyPair{From: codes[:3], To: codes[3:]}, true
+}
+
+// NormalizeCurrency upper-cases a currency code and maps Yahoo's minor-unit codes
+// (GBp, ILA, ZAc) to the major currency.
+func NormalizeCurrency(code string) string {
+	currency, _ := normalizeYahooCurrency(strings.TrimSpace(code))
+	return strings.ToUpper(currency)
+}
+
+// pairRates implements GetRates on top of a quote fetcher that understands
+// Yahoo-style <FROM><TO>=X forex symbols. Each pair is quoted directly if the
+// provider lists it; the rest are triangulated through USD from <CCY>USD=X legs.
+func pairRates(ctx context.Context, pairs []CurrencyPair,
+	fetch func(ctx context.Context, symbols []string) (map[string]Quote, error),
+) (map[CurrencyPair]float64, error) {
+	rates := make(map[CurrencyPair]float64, len(pairs))
+	var wanted []CurrencyPair
+	seen := make(map[CurrencyPair]struct{}, len(pairs))
+	for _, p := range pairs {
+		p = NewCurrencyPair(p.From, p.To)
+		if p.From == "" || p.To == "" {
+			return nil, fmt.Errorf("currency code cannot be empty")
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		if p.From == p.To {
+			rates[p] = 1
+			continue
+		}
+		wanted = append(wanted, p)
+	}
+	if len(wanted) == 0 {
+		return rates, nil
+	}
+
+	prices := make(map[string]float64)
+	var fetchErrs []error
+	fetchPrices := func(symbols []string) {
+		quotes, err := fetch(ctx, symbols)
+		if err != nil {
+			fetchErrs = append(fetchErrs, err)
+		}
+		for _, sym := range symbols {
+			if q, ok := quotes[sym]; ok && q.Price > 0 {
+				prices[sym] = q.Price
+			}
+		}
+	}
+
+	// 1. Direct quotes.
+	direct := make([]string, len(wanted))
+	for i, p := range wanted {
+		direct[i] = p.Symbol()
+	}
+	fetchPrices(direct)
+
+	var crosses []CurrencyPair
+	for _, p := range wanted {
+		if price, ok := prices[p.Symbol()]; ok {
+			rates[p] = price
+			continue
+		}
+		crosses = append(crosses, p)
+	}
+	if len(crosses) == 0 {
+		return rates, nil
+	}
+
+	// 2. Triangulate the rest: FROM/TO = (FROM/USD) / (TO/USD).
+	leg := func(currency string) string { return currency + pivotCurrency + "=X" }
+	var legs []string
+	legSeen := make(map[string]struct{})
+	for _, p := range crosses {
+		for _, currency := range []string{p.From, p.To} {
+			if currency == pivotCurrency {
+				continue
+			}
+			sym := leg(currency)
+			if _, ok := prices[sym]; ok {
+				continue
+			}
+			if _, ok := legSeen[sym]; ok {
+				continue
+			}
+			legSeen[sym] = struct{}{}
+			legs = append(legs, sym)
+		}
+	}
+	if len(legs) > 0 {
+		fetchPrices(legs)
+	}
+	legPrice := func(currency string) (float64, bool) {
+		if currency == pivotCurrency {
+			return 1, true
+		}
+		price, ok := prices[leg(currency)]
+		return price, ok
+	}
+
+	var missing []string
+	for _, p := range crosses {
+		from, okFrom := legPrice(p.From)
+		to, okTo := legPrice(p.To)
+		if !okFrom || !okTo {
+			missing = append(missing, p.String())
+			continue
+		}
+		rates[p] = from / to
+	}
+
+	if len(missing) == 0 {
+		return rates, nil
+	}
+	sort.Strings(missing)
+	if len(fetchErrs) > 0 {
+		return rates, fmt.Errorf("missing forex quotes for pairs %v: %w", missing, errors.Join(fetchErrs...))
+	}
+	return rates, fmt.Errorf("missing forex quotes for pairs %v", missing)
+}
+
+// FXRate is a pair's daily closing rate on one trading day.
+type FXRate struct {
+	Date time.Time // midnight UTC of the trading day
+	Rate float64
+}
+
+// FXHistoryProvider is implemented by providers that report daily FX rate history.
+type FXHistoryProvider interface {
+	// GetFXHistory returns daily rates for pair over rng (e.g. "1y", "max"), oldest first.
+	GetFXHistory(ctx context.Context, pair CurrencyPair, rng string) ([]FXRate, error)
+}
+
+// GetFXHistory returns daily closes for pair from the chart endpoint. Pairs Yahoo does
+// not list are triangulated through USD, aligning each leg on the nearest prior day.
+func (yc *YahooClient) GetFXHistory(ctx context.Context, pair CurrencyPair, rng string) ([]FXRate, error) {
+	pair = NewCurrencyPair(pair.From, pair.To)
+	if pair.From == "" || pair.To == "" {
+		return nil, fmt.Errorf("currency code cannot be empty")
+	}
+	if pair.From == pair.To {
+		return nil, fmt.Errorf("fx history %s: pair has identical currencies", pair)
+	}
+
+	rates, err := yc.fxSeries(ctx, pair.Symbol(), rng)
+	if err == nil && len(rates) > 0 {
+		return rates, nil
+	}
+	if err != nil && !errors.Is(err, ErrSymbolNotFound) {
+		return nil, err
+	}
+
+	from, err := yc.fxLeg(ctx, pair.From, rng)
+	if err != nil {
+		return nil, fmt.Errorf("fx history %s: %w", pair, err)
+	}
+	to, err := yc.fxLeg(ctx, pair.To, rng)
+	if err != nil {
+		return nil, fmt.Errorf("fx history %s: %w", pair, err)
+	}
+	return crossRates(from, to), nil
+}
+
+// fxLeg returns currency/USD history; nil means USD itself (a constant 1).
+func (yc *YahooClient) fxLeg(ctx context.Context, currency, rng string) ([]FXRate, error) {
+	if currency == pivotCurrency {
+		return nil, nil
+	}
+	return yc.fxSeries(ctx, currency+pivotCurrency+"=X", rng)
+}
+
+// fxSeries fetches daily closes for a forex symbol through GetHistory.
+func (yc *YahooClient) fxSeries(ctx context.Context, symbol, rng string) ([]FXRate, error) {
+	series, err := yc.GetHistory(ctx, symbol, rng, "1d")
+	if err != nil {
+		return nil, err
+	}
+	rates := make([]FXRate, 0, len(series.Candles))
+	for _, c := range series.Candles {
+		day := barDay(c.Time)
+		// Yahoo can emit the current day twice (daily bar and live tick); keep the later.
+		if n := len(rates); n > 0 && rates[n-1].Date.Equal(day) {
+			rates[n-1].Rate = c.Close
+			continue
+		}
+		rates = append(rates, FXRate{Date: day, Rate: c.Close})
+	}
+	return rates, nil
+}
+
+// crossRates divides two USD legs day by day. A nil leg is USD (rate 1). Days are
+// taken from whichever leg is present, from when both legs have data.
+func crossRates(from, to []FXRate) []FXRate {
+	days := from
+	if days == nil {
+		days = to
+	}
+	var rates []FXRate
+	for _, d := range days {
+		f, t := 1.0, 1.0
+		if from != nil {
+			r, ok := RateOn(from, d.Date)
+			if !ok {
+				continue
+			}
+			f = r.Rate
+		}
+		if to != nil {
+			r, ok := RateOn(to, d.Date)
+			if !ok {
+				continue
+			}
+			t = r.Rate
+		}
+		if t > 0 {
+			rates = append(rates, FXRate{Date: d.Date, Rate: f / t})
+		}
+	}
+	return rates
+}
+
+// RateOn returns the rate for date's calendar day or, if there was no trading that
+// day, the nearest earlier one. rates must be sorted oldest first. ok is false if date
+// precedes them all.
+func RateOn(rates []FXRate, date time.Time) (FXRate, bool) {
+	day := CalendarDay(date)
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].Date.After(day) })
+	if i == 0 {
+		return FXRate{}, false
+	}
+	return rates[i-1], true
+}
+
+// CalendarDay returns midnight UTC of t's date in t's own location, the key FX rates
+// are stored under.
+func CalendarDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// barDay maps a daily bar's timestamp to its trading day. Daily FX bars are stamped at
+// midnight London time, which is 23:00 UTC the day before in summer, so the timestamp
+// is rounded to the nearest UTC midnight rather than truncated.
+func barDay(t time.Time) time.Time {
+	return t.UTC().Add(12 * time.Hour).Truncate(24 * time.Hour)
+}
+
+// GetFXHistory asks the first provider that supports FX history.
+func (fp *FallbackProvider) GetFXHistory(ctx context.Context, pair CurrencyPair, rng string) ([]FXRate, error) {
+	for _, p := range fp.providers {
+		if hp, ok := p.(FXHistoryProvider); ok {
+			return hp.GetFXHistory(ctx, pair, rng)
+		}
+	}
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,32 @@
+package finance
+
+import "testing"
+
+func TestNormalizeYahooCurrency(t *testing.T) {
+	tests := []struct {
+		raw      string
+		currency string
+		divisor  float64
+	}{
+		{"USD", "USD", 1},
+		{"eur", "EUR", 1},
+		{"GBp", "GBP", 100},
+		{"GBX", "GBP", 100},
+		{"ZAc", "ZAR", 100},
+		{"ZAC", "ZAR", 100},
+		{"ILA", "ILS", 100},
+		{"USX", "USD", 100},
+		{"RUR", "RUB", 1},
+		{" GBP ", "GBP", 1}, // pounds, not pence
+		{"", "", 1},
+	}
+	for _, tt := range tests {
+		currency, divisor := normalizeYahooCurrency(tt.raw)
+		if currency != tt.currency || divisor != tt.divisor {
+			t.Errorf("normalizeYahooCurrency(%q) = %s, %g, want %s, %g", tt.raw, currency, divisor, tt.currency, tt.divisor)
+		}
+		if got := NormalizeCurrency(tt.raw); got != tt.currency {
+			t.Errorf("NormalizeCurrency(%q) = %s, want %s", tt.raw, got, tt.currency)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package finance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// validRanges and validIntervals are the values accepted by the v8/chart endpoint.
+var (
+	validRanges = map[string]struct{}{
+		"1d": {}, "5d": {}, "1mo": {}, "3mo": {}, "6mo": {},
+		"1y": {}, "2y": {}, "5y": {}, "10y": {}, "ytd": {}, "max": {},
+	}
+	validIntervals = map[string]struct{}{
+		"1m": {}, "2m": {}, "5m": {}, "15m": {}, "30m": {}, "60m": {}, "90m": {},
+		"1h": {}, "1d": {}, "5d": {}, "1wk": {}, "1mo": {}, "3mo": {},
+	}
+)
+
+// Candle is a single OHLCV bar. Prices are in the series currency (subunits already converted).
+type Candle struct {
+	Time     time.Time
+	Open     float64
+	High     float64
+	Low      float64
+	Close    float64
+	AdjClose float64 // close adjusted for splits and dividends; equals Close when unavailable
+	Volume   int64
+}
+
+// CandleSeries is the price history of one symbol for a given range and interval.
+type CandleSeries struct {
+	Symbol   string
+	Currency string
+	Range    string
+	Interval string
+	Candles  []Candle // oldest first
+}
+
+// Last returns the most recent candle, or false if the series is empty.
+func (cs *CandleSeries) Last() (Candle, bool) {
+	if len(cs.Candles) == 0 {
+		return Candle{}, false
+	}
+	return cs.Candles[len(cs.Candles)-1], true
+}
+
+// GetHistory returns OHLC candles for symbol, e.g. GetHistory(ctx, "AAPL", "1mo", "1d").
+// Results are cached per symbol, range and interval; the returned series is shared
+// and must not be modified.
+func (yc *YahooClient) GetHistory(ctx context.Context, symbol, rng, interval string) (*CandleSeries, error) {
+	if _, ok := validRanges[rng]; !ok {
+		return nil, fmt.Errorf("unsupported history range %q", rng)
+	}
+	if _, ok := validIntervals[interval]; !ok {
+		return nil, fmt.Errorf("unsupported history interval %q", interval)
+	}
+
+	if series, ok := yc.history.Get(symbol, rng, interval); ok {
+		return series, nil
+	}
+
+	params := url.Values{}
+	params.Set("range", rng)
+	params.Set("interval", interval)
+	params.Set("includeAdjustedClose", "true")
+
+	body, err := yc.fetchChartWithRetry(ctx, symbol, params)
+	if err != nil {
+		return nil, fmt.Errorf("fetch history %s: %w", symbol, err)
+	}
+
+	series, err := parseChartHistory(body, symbol)
+	if err != nil {
+		return nil, err
+	}
+	series.Range = rng
+	series.Interval = interval
+
+	yc.history.Set(symbol, rng, interval, series)
+	return series, nil
+}
+
+// fetchChartWithRetry performs a v8/chart request for symbol through fetchWithRetry.
+func (yc *YahooClient) fetchChartWithRetry(ctx context.Context, symbol string, params url.Values) ([]byte, error) {
+	return yc.fetchWithRetry(ctx, "chart "+symbol, yc.endpoints.Chart+"/"+url.PathEscape(symbol), params)
+}
+
+// fetchWithRetry performs an authenticated request through the circuit breaker,
+// refreshing the session once on ErrUnauthorized and backing off while the endpoint
+// answers ErrRateLimited.
+func (yc *YahooClient) fetchWithRetry(ctx context.Context, what, endpoint string, params url.Values) ([]byte, error) {
+	var body []byte
+	err := yc.breaker.do(func() error {
+		var err error
+		body, err = yc.fetchWithSession(ctx, what, endpoint, params)
+		return err
+	})
+	return body, err
+}
+
+func (yc *YahooClient) fetchWithSession(ctx context.Context, what, endpoint string, params url.Values) ([]byte, error) {
+	sess, err := yc.getSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get yahoo session: %w", err)
+	}
+
+	var body []byte
+	fetch := func() error {
+		body, err = yc.fetchAuthed(ctx, what, endpoint, params, sess)
+		return err
+	}
+
+	err = withRateLimitRetry(ctx, fetch)
+	if errors.Is(err, ErrUnauthorized) {
+		yc.invalidateSession(sess)
+		sess, err = yc.getSession(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("refresh yahoo session: %w", err)
+		}
+		err = withRateLimitRetry(ctx, fetch)
+	}
+	return body, err
+}
+
+func parseChartHistory(body []byte, symbol string) (*CandleSeries, error) {
+	var payload struct {
+		Chart struct {
+			Result []struct {
+				Meta struct {
+					Symbol   string `json:"symbol"`
+					Currency string `json:"currency"`
+				} `json:"meta"`
+				Timestamp  []int64 `json:"timestamp"`
+				Indicators struct {
+					Quote []struct {
+						Open   []*float64 `json:"open"`
+						High   []*float64 `json:"high"`
+						Low    []*float64 `json:"low"`
+						Close  []*float64 `json:"close"`
+						Volume []*int64   `json:"volume"`
+					} `json:"quote"`
+					AdjClose []struct {
+						AdjClose []*float64 `json:"adjclose"`
+					} `json:"adjclose"`
+				} `json:"indicators"`
+			} `json:"result"`
+			Error *chartError `json:"error"`
+		} `json:"chart"`
+	}
+
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("parse chart response: %w", err)
+	}
+	if payload.Chart.Error != nil {
+		return nil, payload.Chart.Error.err()
+	}
+	if len(payload.Chart.Result) == 0 {
+		return nil, fmt.Errorf("no chart result for %s: %w", symbol, ErrSymbolNotFound)
+	}
+
+	result := payload.Chart.Result[0]
+	currency, divisor := normalizeYahooCurrency(result.Meta.Currency)
+	if divisor <= 0 {
+		divisor = 1
+	}
+
+	series := &CandleSeries{
+		Symbol:   result.Meta.Symbol,
+		Currency: currency,
+		Candles:  make([]Candle, 0, len(result.Timestamp)),
+	}
+	if series.Symbol == "" {
+		series.Symbol = symbol
+	}
+	if len(result.Indicators.Quote) == 0 {
+		return series, nil
+	}
+
+	q := result.Indicators.Quote[0]
+	var adj []*float64
+	if len(result.Indicators.AdjClose) > 0 {
+		adj = result.Indicators.AdjClose[0].AdjClose
+	}
+
+	for i, ts := range result.Timestamp {
+		closePrice := valueAt(q.Close, i)
+		if closePrice == 0 {
+			continue // Yahoo emits null bars for halted or not-yet-traded periods.
+		}
+		c := Candle{
+			Time:     time.Unix(ts, 0).UTC(),
+			Open:     valueAt(q.Open, i) / divisor,
+			High:     valueAt(q.High, i) / divisor,
+			Low:      valueAt(q.Low, i) / divisor,
+			Close:    closePrice / divisor,
+			AdjClose: closePrice / divisor,
+		}
+		if a := valueAt(adj, i); a != 0 {
+			c.AdjClose = a / divisor
+		}
+		if i < len(q.Volume) && q.Volume[i] != nil {
+			c.Volume = *q.Volume[i]
+		}
+		series.Candles = append(series.Candles, c)
+	}
+	return series, nil
+}
+
+// valueAt returns values[i], treating missing indices and JSON nulls as zero.
+func valueAt(values []*float64, i int) float64 {
+	if i >= len(values) || values[i] == nil {
+		return 0
+	}
+	return *values[i]
+}
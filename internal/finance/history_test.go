@@ -0,0 +1,77 @@
+package finance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryCacheKeysByRangeAndInterval(t *testing.T) {
+	hc := NewHistoryCache(50 * time.Millisecond)
+	month := &CandleSeries{Symbol: "AAPL", Range: "1mo", Interval: "1d"}
+	year := &CandleSeries{Symbol: "AAPL", Range: "1y", Interval: "1wk"}
+	hc.Set("AAPL", "1mo", "1d", month)
+	hc.Set("AAPL", "1y", "1wk", year)
+
+	tests := []struct {
+		symbol, rng, interval string
+		want                  *CandleSeries
+	}{
+		{"AAPL", "1mo", "1d", month},
+		{"AAPL", "1y", "1wk", year},
+		{"AAPL", "1mo", "1wk", nil}, // same range, other interval
+		{"AAPL", "1y", "1d", nil},
+		{"MSFT", "1mo", "1d", nil},
+	}
+	for _, tt := range tests {
+		got, ok := hc.Get(tt.symbol, tt.rng, tt.interval)
+		if ok != (tt.want != nil) || got != tt.want {
+			t.Errorf("Get(%s, %s, %s) = %p, %v; want %p", tt.symbol, tt.rng, tt.interval, got, ok, tt.want)
+		}
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := hc.Get("AAPL", "1mo", "1d"); ok {
+		t.Error("series still served after its TTL")
+	}
+}
+
+func TestParseChartHistory(t *testing.T) {
+	body := []byte(`{"chart": {"result": [{
+		"meta": {"symbol": "VOD.L", "currency": "GBp"},
+		"timestamp": [1704268800, 1704355200, 1704441600],
+		"indicators": {
+			"quote": [{
+				"open":   [7000, null, 7100],
+				"high":   [7200, null, 7300],
+				"low":    [6900, null, 7050],
+				"close":  [7150, null, 7250],
+				"volume": [1000, null, 2000]
+			}],
+			"adjclose": [{"adjclose": [7050, null, null]}]
+		}
+	}], "error": null}}`)
+
+	series, err := parseChartHistory(body, "VOD.L")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if series.Symbol != "VOD.L" || series.Currency != "GBP" {
+		t.Errorf("series is %s in %s, want VOD.L in GBP", series.Symbol, series.Currency)
+	}
+	want := []Candle{
+		{Time: time.Unix(1704268800, 0).UTC(), Open: 70, High: 72, Low: 69, Close: 71.5, AdjClose: 70.5, Volume: 1000},
+		// The null bar is skipped; a missing adjusted close falls back to the close.
+		{Time: time.Unix(1704441600, 0).UTC(), Open: 71, High: 73, Low: 70.5, Close: 72.5, AdjClose: 72.5, Volume: 2000},
+	}
+	if len(series.Candles) != len(want) {
+		t.Fatalf("candles = %+v, want %d", series.Candles, len(want))
+	}
+	for i := range want {
+		if series.Candles[i] != want[i] {
+			t.Errorf("candle %d = %+v, want %+v", i, series.Candles[i], want[i])
+		}
+	}
+	if last, ok := series.Last(); !ok || last != want[1] {
+		t.Errorf("Last() = %+v, %v; want the newest candle", last, ok)
+	}
+}
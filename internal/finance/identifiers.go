@@ -0,0 +1,139 @@
+package finance
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalidIdentifier is returned when a query has the shape of an ISIN or CUSIP
+	// but its check digit does not match.
+	ErrInvalidIdentifier = errors.New("invalid security identifier")
+
+	// ErrUnsupportedIdentifier is returned for a valid identifier that cannot be
+	// looked up, such as a CINS number.
+	ErrUnsupportedIdentifier = errors.New("unsupported security identifier")
+)
+
+// IdentifierKind classifies a search query.
+type IdentifierKind int
+
+const (
+	IdentNone  IdentifierKind = iota // a ticker or company name
+	IdentISIN                        // 12 characters: country code, 9 alphanumerics, check digit
+	IdentCUSIP                       // 9 characters: 8 alphanumerics, check digit
+)
+
+// ParseIdentifier reports whether query looks like an ISIN or CUSIP and returns it
+// normalised to upper case. It returns ErrInvalidIdentifier if the shape matches but
+// the checksum does not. Queries of any other shape are returned with IdentNone.
+func ParseIdentifier(query string) (string, IdentifierKind, error) {
+	id := strings.ToUpper(strings.Join(strings.Fields(query), ""))
+
+	switch {
+	case len(id) == 12 && isLetter(id[0]) && isLetter(id[1]) && isAlnum(id[2:11]) && isDigit(id[11]):
+		if !validISIN(id) {
+			return id, IdentISIN, ErrInvalidIdentifier
+		}
+		return id, IdentISIN, nil
+	case len(id) == 9 && isAlnum(id[:8]) && isDigit(id[8]) && hasDigit(id[:3]):
+		// Requiring a digit in the issuer prefix keeps 9-letter words and tickers out.
+		if !validCUSIP(id) {
+			return id, IdentCUSIP, ErrInvalidIdentifier
+		}
+		return id, IdentCUSIP, nil
+	}
+	return query, IdentNone, nil
+}
+
+// CUSIPToISINs returns the ISINs a CUSIP may have, most likely first. CUSIPs proper
+// (starting with a digit) are issued to both US and Canadian securities, whose ISINs
+// are US or CA + CUSIP + check digit, and nothing in the number says which. CINS
+// numbers (starting with a letter) belong to issuers elsewhere whose ISIN country is
+// not encoded in the number, so they have none.
+func CUSIPToISINs(cusip string) []string {
+	if len(cusip) != 9 || !isDigit(cusip[0]) {
+		return nil
+	}
+	isins := make([]string, 0, 2)
+	for _, country := range []string{"US", "CA"} {
+		body := country + cusip
+		isins = append(isins, body+string(rune('0'+isinCheckDigit(body))))
+	}
+	return isins
+}
+
+// validISIN checks the Luhn check digit over the ISIN with letters expanded to numbers.
+func validISIN(isin string) bool {
+	return int(isin[11]-'0') == isinCheckDigit(isin[:11])
+}
+
+// isinCheckDigit computes the ISIN check digit for the first 11 characters: letters
+// become two digits (A=10 … Z=35) and the Luhn algorithm runs over the result.
+func isinCheckDigit(body string) int {
+	var digits []int
+	for i := 0; i < len(body); i++ {
+		v := alnumValue(body[i])
+		if v >= 10 {
+			digits = append(digits, v/10, v%10)
+		} else {
+			digits = append(digits, v)
+		}
+	}
+
+	sum := 0
+	// The rightmost digit of the body is doubled, since the check digit follows it.
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-1-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// validCUSIP checks the modulus-10 "double-add-double" check digit.
+func validCUSIP(cusip string) bool {
+	sum := 0
+	for i := 0; i < 8; i++ {
+		v := alnumValue(cusip[i])
+		if i%2 == 1 {
+			v *= 2
+		}
+		sum += v/10 + v%10
+	}
+	return int(cusip[8]-'0') == (10-sum%10)%10
+}
+
+// alnumValue maps 0-9 to 0-9 and A-Z to 10-35.
+func alnumValue(c byte) int {
+	if isDigit(c) {
+		return int(c - '0')
+	}
+	return int(c-'A') + 10
+}
+
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+func isLetter(c byte) bool { return c >= 'A' && c <= 'Z' }
+
+func isAlnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) && !isLetter(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasDigit(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if isDigit(s[i]) {
+			return true
+		}
+	}
+	return false
+}
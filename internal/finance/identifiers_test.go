@@ -0,0 +1,57 @@
+package finance
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseIdentifier(t *testing.T) {
+	tests := []struct {
+		query string
+		id    string
+		kind  IdentifierKind
+		err   error
+	}{
+		{"US0378331005", "US0378331005", IdentISIN, nil},   // Apple
+		{"de 000716 4600", "DE0007164600", IdentISIN, nil}, // SAP, spaced and lower case
+		{"CA82509L1076", "CA82509L1076", IdentISIN, nil},   // Shopify
+		{"US0378331006", "US0378331006", IdentISIN, ErrInvalidIdentifier},
+		{"037833100", "037833100", IdentCUSIP, nil}, // Apple
+		{"88160R101", "88160R101", IdentCUSIP, nil}, // Tesla
+		{"G1151C101", "G1151C101", IdentCUSIP, nil}, // Accenture, a CINS
+		{"037833101", "037833101", IdentCUSIP, ErrInvalidIdentifier},
+		{"AAPL", "AAPL", IdentNone, nil},
+		{"MICROSOFT", "MICROSOFT", IdentNone, nil}, // nine letters, not a CUSIP
+	}
+	for _, tt := range tests {
+		id, kind, err := ParseIdentifier(tt.query)
+		if (kind != IdentNone && id != tt.id) || kind != tt.kind || !errors.Is(err, tt.err) {
+			t.Errorf("ParseIdentifier(%q) = %q, %v, %v; want %q, %v, %v", tt.query, id, kind, err, tt.id, tt.kind, tt.err)
+		}
+	}
+}
+
+func TestCUSIPToISINs(t *testing.T) {
+	tests := []struct {
+		cusip string
+		want  []string
+	}{
+		{"037833100", []string{"US0378331005", "CA0378331007"}}, // Apple
+		{"594918104", []string{"US5949181045", "CA5949181047"}}, // Microsoft
+		{"780087102", []string{"US7800871029", "CA7800871021"}}, // Royal Bank of Canada
+		{"82509L107", []string{"US82509L1070", "CA82509L1076"}}, // Shopify
+		{"G1151C101", nil}, // CINS: the ISIN country is not in the number
+	}
+	for _, tt := range tests {
+		got := CUSIPToISINs(tt.cusip)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("CUSIPToISINs(%s) = %v, want %v", tt.cusip, got, tt.want)
+		}
+		for _, isin := range got {
+			if !validISIN(isin) {
+				t.Errorf("CUSIPToISINs(%s) produced %s with a bad check digit", tt.cusip, isin)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package finance
+
+import "strings"
+
+// Yahoo quote types accepted by SearchTickers.
+const (
+	TypeEquity     = "EQUITY"
+	TypeETF        = "ETF"
+	TypeMutualFund = "MUTUALFUND"
+	TypeCrypto     = "CRYPTOCURRENCY"
+	TypeIndex      = "INDEX"
+	TypeFuture     = "FUTURE"
+	TypeCurrency   = "CURRENCY"
+)
+
+// typeLabels are the short names shown to users for each supported quote type.
+var typeLabels = map[string]string{
+	TypeEquity:     "Stock",
+	TypeETF:        "ETF",
+	TypeMutualFund: "Fund",
+	TypeCrypto:     "Crypto",
+	TypeIndex:      "Index",
+	TypeFuture:     "Future",
+	TypeCurrency:   "FX",
+}
+
+// SupportedType reports whether holdings of quote type t can be tracked.
+func SupportedType(t string) bool {
+	_, ok := typeLabels[t]
+	return ok
+}
+
+// TypeLabel returns a short user-facing name for quote type t.
+func TypeLabel(t string) string {
+	if label, ok := typeLabels[t]; ok {
+		return label
+	}
+	return t
+}
+
+// UnitLabel names what a holding's quantity counts for quote type t.
+func UnitLabel(t string) string {
+	switch t {
+	case TypeFuture:
+		return "contracts"
+	case TypeCrypto, TypeCurrency:
+		return "units"
+	default:
+		return "shares"
+	}
+}
+
+// GuessType infers the quote type from Yahoo symbol conventions, for quotes from
+// providers that do not report one. Plain tickers are assumed to be equities.
+func GuessType(symbol string) string {
+	sym := strings.ToUpper(strings.TrimSpace(symbol))
+	switch {
+	case strings.HasPrefix(sym, "^"):
+		return TypeIndex
+	case strings.HasSuffix(sym, "=F"):
+		return TypeFuture
+	case strings.HasSuffix(sym, "=X"):
+		return TypeCurrency
+	case strings.HasSuffix(sym, "-USD"), strings.HasSuffix(sym, "-EUR"), strings.HasSuffix(sym, "-USDT"):
+		return TypeCrypto
+	default:
+		return TypeEquity
+	}
+}
+
+// WatchOnly reports whether instruments of quote type t cannot be owned and are
+// tracked for their price only.
+func WatchOnly(t string) bool {
+	return t == TypeIndex
+}
+
+// futureMultipliers is the contract size of common futures, keyed by the Yahoo
+// symbol root (GC for GC=F). Prices are quoted per unit; a contract is worth
+// price × multiplier.
+var futureMultipliers = map[string]float64{
+	// Metals
+	"GC":  100,   // gold, troy oz
+	"MGC": 10,    // micro gold
+	"SI":  5000,  // silver, troy oz
+	"PL":  50,    // platinum
+	"PA":  100,   // palladium
+	"HG":  25000, // copper, lb
+	// Energy
+	"CL":  1000,  // WTI crude, bbl
+	"MCL": 100,   // micro WTI
+	"BZ":  1000,  // Brent crude, bbl
+	"NG":  10000, // natural gas, MMBtu
+	"RB":  42000, // RBOB gasoline, gal
+	"HO":  42000, // heating oil, gal
+	// Grains, quoted in US cents (USX) per bushel
+	"ZC": 5000,
+	"ZS": 5000,
+	"ZW": 5000,
+	// Equity indices
+	"ES":  50,
+	"MES": 5,
+	"NQ":  20,
+	"MNQ": 2,
+	"YM":  5,
+	"MYM": 0.5,
+	"RTY": 50,
+	// Treasuries
+	"ZB": 1000,
+	"ZN": 1000,
+	"ZF": 1000,
+	"ZT": 2000,
+	// Crypto
+	"BTC": 5,
+	"MBT": 0.1,
+	"ETH": 50,
+}
+
+// ContractMultiplier returns the number of units one contract of symbol covers.
+// It is 1 for anything that is not a known future; ok is false for futures
+// whose contract size is unknown.
+func ContractMultiplier(symbol string) (mult float64, ok bool) {
+	root, isFuture := strings.CutSuffix(strings.ToUpper(strings.TrimSpace(symbol)), "=F")
+	if !isFuture {
+		return 1, true
+	}
+	if mult, ok := futureMultipliers[root]; ok {
+		return mult, true
+	}
+	return 1, false
+}
@@ -0,0 +1,105 @@
+package finance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// newsCount is how many headlines are requested per symbol.
+	newsCount = 10
+
+	// newsTTL is how long headlines are cached per symbol.
+	newsTTL = 15 * time.Minute
+)
+
+// NewsItem is a single headline related to a symbol.
+type NewsItem struct {
+	UUID           string
+	Title          string
+	Publisher      string
+	Link           string
+	PublishedAt    time.Time
+	RelatedTickers []string
+}
+
+// NewsProvider is implemented by providers that report headlines for a symbol.
+type NewsProvider interface {
+	GetNews(ctx context.Context, symbol string) ([]NewsItem, error)
+}
+
+// GetNews returns recent headlines for symbol, newest first. Results are cached per
+// symbol, so callers serving many users share one upstream request; the returned
+// slice is shared and must not be modified.
+func (yc *YahooClient) GetNews(ctx context.Context, symbol string) ([]NewsItem, error) {
+	if items, ok := yc.news.Get(symbol); ok {
+		return items, nil
+	}
+
+	params := url.Values{}
+	params.Set("q", symbol)
+	params.Set("quotesCount", "0")
+	params.Set("newsCount", strconv.Itoa(newsCount))
+
+	body, err := yc.search(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("fetch news %s: %w", symbol, err)
+	}
+
+	items, err := parseNews(body)
+	if err != nil {
+		return nil, err
+	}
+	yc.news.Set(symbol, items)
+	return items, nil
+}
+
+// GetNews asks the first provider that supports news.
+func (fp *FallbackProvider) GetNews(ctx context.Context, symbol string) ([]NewsItem, error) {
+	for _, p := range fp.providers {
+		if np, ok := p.(NewsProvider); ok {
+			return np.GetNews(ctx, symbol)
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+func parseNews(body []byte) ([]NewsItem, error) {
+	var payload struct {
+		News []struct {
+			UUID                string   `json:"uuid"`
+			Title               string   `json:"title"`
+			Publisher           string   `json:"publisher"`
+			Link                string   `json:"link"`
+			ProviderPublishTime int64    `json:"providerPublishTime"`
+			RelatedTickers      []string `json:"relatedTickers"`
+		} `json:"news"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("parse news response: %w", err)
+	}
+
+	items := make([]NewsItem, 0, len(payload.News))
+	for _, n := range payload.News {
+		if n.UUID == "" || n.Title == "" || n.Link == "" {
+			continue
+		}
+		items = append(items, NewsItem{
+			UUID:           n.UUID,
+			Title:          n.Title,
+			Publisher:      n.Publisher,
+			Link:           n.Link,
+			PublishedAt:    time.Unix(n.ProviderPublishTime, 0).UTC(),
+			RelatedTickers: n.RelatedTickers,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].PublishedAt.After(items[j].PublishedAt)
+	})
+	return items, nil
+}
@@ -0,0 +1,76 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseNews(t *testing.T) {
+	body := []byte(`{"quotes": [], "news": [
+		{"uuid": "a", "title": "Older", "publisher": "Reuters", "link": "https://example.com/a",
+		 "providerPublishTime": 1704268800, "relatedTickers": ["AAPL"]},
+		{"uuid": "b", "title": "Newer", "publisher": "Bloomberg", "link": "https://example.com/b",
+		 "providerPublishTime": 1704355200, "relatedTickers": ["AAPL", "MSFT"]},
+		{"uuid": "c", "title": "", "link": "https://example.com/c", "providerPublishTime": 1704441600},
+		{"uuid": "", "title": "No id", "link": "https://example.com/d", "providerPublishTime": 1704441600}
+	]}`)
+
+	items, err := parseNews(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("items = %+v, want the 2 complete headlines", items)
+	}
+	if items[0].UUID != "b" || items[1].UUID != "a" {
+		t.Errorf("order = %s, %s; want newest first", items[0].UUID, items[1].UUID)
+	}
+	b := items[0]
+	if b.Publisher != "Bloomberg" || !b.PublishedAt.Equal(time.Unix(1704355200, 0)) || len(b.RelatedTickers) != 2 {
+		t.Errorf("headline = %+v", b)
+	}
+
+	if _, err := parseNews([]byte(`<html>`)); err == nil {
+		t.Error("malformed body parsed")
+	}
+}
+
+// newsProvider is a fakeProvider that also reports headlines.
+type newsProvider struct {
+	fakeProvider
+	items []NewsItem
+}
+
+func (p *newsProvider) GetNews(ctx context.Context, symbol string) ([]NewsItem, error) {
+	return p.items, nil
+}
+
+func TestFallbackProviderGetNews(t *testing.T) {
+	plain := &fakeProvider{name: "stooq"}
+	news := &newsProvider{fakeProvider: fakeProvider{name: "yahoo"}, items: []NewsItem{{UUID: "a"}}}
+
+	items, err := NewFallbackProvider(plain, news).GetNews(context.Background(), "AAPL")
+	if err != nil || len(items) != 1 || items[0].UUID != "a" {
+		t.Errorf("GetNews = %v, %v; want the headline from the provider with news", items, err)
+	}
+	if _, err := NewFallbackProvider(plain).GetNews(context.Background(), "AAPL"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("no provider with news: error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestNewsCacheExpires(t *testing.T) {
+	nc := NewNewsCache(20 * time.Millisecond)
+	nc.Set("AAPL", []NewsItem{{UUID: "a"}})
+	if items, ok := nc.Get("AAPL"); !ok || len(items) != 1 {
+		t.Errorf("Get = %v, %v; want the cached headline", items, ok)
+	}
+	if _, ok := nc.Get("MSFT"); ok {
+		t.Error("MSFT served without being cached")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := nc.Get("AAPL"); ok {
+		t.Error("headlines still served after their TTL")
+	}
+}
@@ -0,0 +1,193 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// QuoteProvider is a source of ticker search results, quotes and FX rates.
+type QuoteProvider interface {
+	// Name returns a short identifier used in logs and configuration (e.g. "yahoo").
+	Name() string
+	SearchTickers(ctx context.Context, query string) ([]TickerResult, error)
+	// GetQuotes may return partial results together with a non-nil error.
+	GetQuotes(ctx context.Context, symbols []string) (map[string]Quote, error)
+	// GetRates returns how many units of pair.To equal one unit of pair.From, keyed
+	// by the normalised pair. It may return partial results together with a non-nil error.
+	GetRates(ctx context.Context, pairs []CurrencyPair) (map[CurrencyPair]float64, error)
+}
+
+// FallbackProvider queries providers in order, asking each subsequent provider
+// only for the symbols (or currencies) the previous ones could not resolve.
+type FallbackProvider struct {
+	providers []QuoteProvider
+	cache     *PriceCache
+}
+
+// NewFallbackProvider creates a FallbackProvider that tries providers in the given order.
+func NewFallbackProvider(providers ...QuoteProvider) *FallbackProvider {
+	return &FallbackProvider{providers: providers}
+}
+
+// SetCache puts cache in front of GetQuotes, so quotes from every provider in the
+// chain are cached, coalesced and persisted alike. Call it before first use.
+func (fp *FallbackProvider) SetCache(cache *PriceCache) {
+	fp.cache = cache
+}
+
+// Name returns the provider chain, e.g. "yahoo>stooq".
+func (fp *FallbackProvider) Name() string {
+	names := make([]string, len(fp.providers))
+	for i, p := range fp.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ">")
+}
+
+// SearchTickers returns the results of the first provider that answers without error.
+func (fp *FallbackProvider) SearchTickers(ctx context.Context, query string) ([]TickerResult, error) {
+	var errs []error
+	for _, p := range fp.providers {
+		results, err := p.SearchTickers(ctx, query)
+		if err == nil {
+			return results, nil
+		}
+		if !errors.Is(err, ErrUnsupported) {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		}
+	}
+	if len(errs) == 0 {
+		return nil, ErrUnsupported
+	}
+	return nil, errors.Join(errs...)
+}
+
+// GetQuotes resolves each symbol with the first provider able to price it, using
+// the cache (if set) where fresh. A stale quote is only used if no later provider
+// returns a fresh one.
+func (fp *FallbackProvider) GetQuotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	if fp.cache == nil {
+		return fp.fetchQuotes(ctx, symbols)
+	}
+	if len(symbols) == 0 {
+		return map[string]Quote{}, nil
+	}
+	return fp.cache.GetOrFetch(ctx, symbols, fp.fetchQuotes)
+}
+
+// RunRefresh re-fetches recently read quotes shortly before they expire so hot
+// symbols are always served from cache. It blocks until ctx is cancelled, and
+// returns at once if no cache is set.
+func (fp *FallbackProvider) RunRefresh(ctx context.Context) {
+	if fp.cache == nil {
+		return
+	}
+	window := fp.cache.ttl / 5
+	if window <= 0 {
+		return
+	}
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			symbols := fp.cache.Expiring(window)
+			if len(symbols) == 0 {
+				continue
+			}
+			quotes, err := fp.fetchQuotes(ctx, symbols)
+			fp.cache.SetMulti(quotes)
+			if err != nil {
+				log.Printf("%s: refresh %d hot symbols: %v", fp.Name(), len(symbols), err)
+			}
+		}
+	}
+}
+
+// fetchQuotes asks the providers in turn, bypassing the cache.
+func (fp *FallbackProvider) fetchQuotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	quotes := make(map[string]Quote, len(symbols))
+	stale := make(map[string]Quote)
+	remaining := symbols
+
+	var errs []error
+	for _, p := range fp.providers {
+		if len(remaining) == 0 {
+			break
+		}
+		got, err := p.GetQuotes(ctx, remaining)
+		if err != nil && !errors.Is(err, ErrUnsupported) {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		}
+
+		var next []string
+		for _, sym := range remaining {
+			q, ok := got[sym]
+			if ok && !q.Stale {
+				quotes[sym] = q
+				continue
+			}
+			if _, seen := stale[sym]; ok && !seen {
+				stale[sym] = q
+			}
+			next = append(next, sym)
+		}
+		remaining = next
+	}
+
+	var unresolved []string
+	for _, sym := range remaining {
+		if q, ok := stale[sym]; ok {
+			quotes[sym] = q
+			continue
+		}
+		unresolved = append(unresolved, sym)
+	}
+	if len(unresolved) > 0 {
+		errs = append(errs, fmt.Errorf("no provider returned quotes for %v", unresolved))
+	}
+	return quotes, errors.Join(errs...)
+}
+
+// GetRates resolves each pair with the first provider able to quote it.
+func (fp *FallbackProvider) GetRates(ctx context.Context, pairs []CurrencyPair) (map[CurrencyPair]float64, error) {
+	rates := make(map[CurrencyPair]float64, len(pairs))
+	remaining := make([]CurrencyPair, len(pairs))
+	for i, p := range pairs {
+		remaining[i] = NewCurrencyPair(p.From, p.To)
+	}
+
+	var errs []error
+	for _, p := range fp.providers {
+		if len(remaining) == 0 {
+			break
+		}
+		got, err := p.GetRates(ctx, remaining)
+		if err != nil && !errors.Is(err, ErrUnsupported) {
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		}
+
+		var next []CurrencyPair
+		for _, pair := range remaining {
+			if rate, ok := got[pair]; ok {
+				rates[pair] = rate
+				continue
+			}
+			next = append(next, pair)
+		}
+		remaining = next
+	}
+
+	if len(remaining) > 0 {
+		errs = append(errs, fmt.Errorf("no provider returned rates for %v", remaining))
+		return rates, errors.Join(errs...)
+	}
+	return rates, nil
+}
@@ -0,0 +1,185 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeProvider prices the symbols in quotes and the pairs in rates, and records what
+// it was asked for.
+type fakeProvider struct {
+	name    string
+	quotes  map[string]Quote
+	rates   map[CurrencyPair]float64
+	search  []TickerResult
+	err     error // returned with every answer
+	asked   [][]string
+	askedFX [][]CurrencyPair
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) SearchTickers(ctx context.Context, query string) ([]TickerResult, error) {
+	return f.search, f.err
+}
+
+func (f *fakeProvider) GetQuotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	f.asked = append(f.asked, symbols)
+	got := make(map[string]Quote)
+	for _, sym := range symbols {
+		if q, ok := f.quotes[sym]; ok {
+			got[sym] = q
+		}
+	}
+	return got, f.err
+}
+
+func (f *fakeProvider) GetRates(ctx context.Context, pairs []CurrencyPair) (map[CurrencyPair]float64, error) {
+	f.askedFX = append(f.askedFX, pairs)
+	got := make(map[CurrencyPair]float64)
+	for _, p := range pairs {
+		if r, ok := f.rates[p]; ok {
+			got[p] = r
+		}
+	}
+	return got, f.err
+}
+
+func TestFallbackProviderGetQuotes(t *testing.T) {
+	first := &fakeProvider{name: "first", err: errors.New("partial outage"), quotes: map[string]Quote{
+		"AAPL": {Price: 190, Source: "first"},
+		"SAP":  {Price: 170, Source: "first", Stale: true},
+		"VOD":  {Price: 0.7, Source: "first", Stale: true},
+	}}
+	second := &fakeProvider{name: "second", quotes: map[string]Quote{
+		"AAPL": {Price: 999, Source: "second"},
+		"SAP":  {Price: 171, Source: "second"},
+		"MSFT": {Price: 410, Source: "second"},
+	}}
+	third := &fakeProvider{name: "third"}
+	fp := NewFallbackProvider(first, second, third)
+
+	quotes, err := fp.GetQuotes(context.Background(), []string{"AAPL", "SAP", "MSFT", "VOD", "NOPE"})
+	if err == nil {
+		t.Error("no error for NOPE")
+	}
+
+	tests := []struct {
+		symbol string
+		source string
+		stale  bool
+	}{
+		{"AAPL", "first", false},  // first fresh answer wins
+		{"SAP", "second", false},  // a fresh quote beats an earlier stale one
+		{"MSFT", "second", false}, // missing from the first provider
+		{"VOD", "first", true},    // stale only if nobody has a fresh one
+	}
+	for _, tt := range tests {
+		q, ok := quotes[tt.symbol]
+		if !ok || q.Source != tt.source || q.Stale != tt.stale {
+			t.Errorf("%s = %+v (present %v), want from %s, stale %v", tt.symbol, q, ok, tt.source, tt.stale)
+		}
+	}
+	if _, ok := quotes["NOPE"]; ok {
+		t.Error("NOPE returned a quote")
+	}
+
+	// Each provider is only asked for what the ones before it left unresolved.
+	wantAsked := map[*fakeProvider][]string{
+		first:  {"AAPL", "SAP", "MSFT", "VOD", "NOPE"},
+		second: {"SAP", "MSFT", "VOD", "NOPE"},
+		third:  {"VOD", "NOPE"},
+	}
+	for p, want := range wantAsked {
+		if len(p.asked) != 1 || !slices.Equal(p.asked[0], want) {
+			t.Errorf("%s asked for %v, want %v", p.name, p.asked, want)
+		}
+	}
+}
+
+func TestFallbackProviderStopsWhenResolved(t *testing.T) {
+	first := &fakeProvider{name: "first", quotes: map[string]Quote{"AAPL": {Price: 190}}}
+	second := &fakeProvider{name: "second"}
+	fp := NewFallbackProvider(first, second)
+
+	if _, err := fp.GetQuotes(context.Background(), []string{"AAPL"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(second.asked) != 0 {
+		t.Errorf("second provider asked for %v after everything was resolved", second.asked)
+	}
+}
+
+// savedQuotes is a CacheStore that records the symbols persisted.
+type savedQuotes []string
+
+func (s *savedQuotes) SaveQuotes(quotes []Quote) error {
+	for _, q := range quotes {
+		*s = append(*s, q.Symbol)
+	}
+	return nil
+}
+
+func TestFallbackProviderCachesEveryProvider(t *testing.T) {
+	first := &fakeProvider{name: "first", quotes: map[string]Quote{"AAPL": {Symbol: "AAPL", Price: 190}}}
+	second := &fakeProvider{name: "second", quotes: map[string]Quote{"CDR.PL": {Symbol: "CDR.PL", Price: 120}}}
+	var saved savedQuotes
+	cache := NewPriceCache(time.Minute)
+	cache.SetStore(&saved)
+	fp := NewFallbackProvider(first, second)
+	fp.SetCache(cache)
+
+	for range 2 {
+		quotes, err := fp.GetQuotes(context.Background(), []string{"AAPL", "CDR.PL"})
+		if err != nil || quotes["AAPL"].Price != 190 || quotes["CDR.PL"].Price != 120 {
+			t.Fatalf("GetQuotes = %v, %v; want both quotes", quotes, err)
+		}
+	}
+	if len(first.asked) != 1 || len(second.asked) != 1 {
+		t.Errorf("providers asked %v and %v, want once each with the second call served from cache",
+			first.asked, second.asked)
+	}
+	slices.Sort(saved)
+	if !slices.Equal(saved, []string{"AAPL", "CDR.PL"}) {
+		t.Errorf("persisted %v, want the quotes of both providers", saved)
+	}
+}
+
+func TestFallbackProviderGetRates(t *testing.T) {
+	eurUSD, gbpUSD, chfUSD := NewCurrencyPair("EUR", "USD"), NewCurrencyPair("GBP", "USD"), NewCurrencyPair("CHF", "USD")
+	first := &fakeProvider{name: "first", rates: map[CurrencyPair]float64{eurUSD: 1.08}}
+	second := &fakeProvider{name: "second", rates: map[CurrencyPair]float64{eurUSD: 2, gbpUSD: 1.27}}
+	fp := NewFallbackProvider(first, second)
+
+	rates, err := fp.GetRates(context.Background(), []CurrencyPair{{From: "eur", To: "usd"}, gbpUSD, chfUSD})
+	if err == nil {
+		t.Error("no error for CHF/USD")
+	}
+	if rates[eurUSD] != 1.08 || rates[gbpUSD] != 1.27 {
+		t.Errorf("rates = %v, want EUR/USD from first and GBP/USD from second", rates)
+	}
+	if _, ok := rates[chfUSD]; ok {
+		t.Error("CHF/USD returned a rate")
+	}
+	if len(second.askedFX) != 1 || !slices.Equal(second.askedFX[0], []CurrencyPair{gbpUSD, chfUSD}) {
+		t.Errorf("second asked for %v, want GBP/USD and CHF/USD", second.askedFX)
+	}
+}
+
+func TestFallbackProviderSearchTickers(t *testing.T) {
+	unsupported := &fakeProvider{name: "stooq", err: ErrUnsupported}
+	failing := &fakeProvider{name: "down", err: errors.New("timeout")}
+	working := &fakeProvider{name: "yahoo", search: []TickerResult{{Symbol: "AAPL"}}}
+
+	results, err := NewFallbackProvider(unsupported, failing, working).SearchTickers(context.Background(), "apple")
+	if err != nil || len(results) != 1 || results[0].Symbol != "AAPL" {
+		t.Errorf("SearchTickers = %v, %v; want AAPL from the first provider that answers", results, err)
+	}
+
+	if _, err := NewFallbackProvider(unsupported).SearchTickers(context.Background(), "apple"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("only unsupported providers: error = %v, want ErrUnsupported", err)
+	}
+}
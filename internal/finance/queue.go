@@ -0,0 +1,228 @@
+package finance
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFetchWorkers bounds how many upstream requests run at the same time.
+const defaultFetchWorkers = 4
+
+// BatchError reports per-symbol failures from a partially successful batch fetch.
+// Quotes for the remaining symbols are returned alongside it.
+type BatchError struct {
+	Failures map[string]error
+}
+
+func (e *BatchError) Error() string {
+	symbols := make([]string, 0, len(e.Failures))
+	for sym := range e.Failures {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	parts := make([]string, len(symbols))
+	for i, sym := range symbols {
+		parts[i] = "fetch " + sym + ": " + e.Failures[sym].Error()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Unwrap exposes the individual failures, as *SymbolError, to errors.Is and errors.As.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for sym, err := range e.Failures {
+		errs = append(errs, &SymbolError{Symbol: sym, Err: err})
+	}
+	return errs
+}
+
+// FetchQueue runs per-symbol fetches on a bounded worker pool behind a token-bucket
+// rate limiter. A symbol requested by several callers while it is queued or in
+// flight is fetched once and the result is shared.
+type FetchQueue struct {
+	limiter *tokenBucket
+	jobs    chan *fetchJob
+
+	mu      sync.Mutex
+	pending map[string]*fetchJob
+}
+
+type fetchJob struct {
+	ctx    context.Context
+	symbol string
+	fetch  func(ctx context.Context, symbol string) (Quote, error)
+
+	waiters int // callers sharing the job besides the one that queued it; guarded by FetchQueue.mu
+
+	done  chan struct{}
+	quote Quote
+	err   error
+}
+
+// NewFetchQueue creates a FetchQueue allowing ratePerSec requests per second
+// across workers concurrent fetches. The workers live for the life of the process.
+func NewFetchQueue(ratePerSec float64, workers int) *FetchQueue {
+	if workers <= 0 {
+		workers = defaultFetchWorkers
+	}
+	fq := &FetchQueue{
+		limiter: newTokenBucket(ratePerSec),
+		jobs:    make(chan *fetchJob, 256),
+		pending: make(map[string]*fetchJob),
+	}
+	for range workers {
+		go fq.worker()
+	}
+	return fq
+}
+
+// Fetch resolves all symbols through fetch and returns the quotes that succeeded
+// together with a map of per-symbol failures.
+func (fq *FetchQueue) Fetch(ctx context.Context, symbols []string,
+	fetch func(ctx context.Context, symbol string) (Quote, error),
+) (map[string]Quote, map[string]error) {
+	jobs := make(map[string]*fetchJob, len(symbols))
+	for _, sym := range symbols {
+		if _, ok := jobs[sym]; ok {
+			continue
+		}
+		job, err := fq.enqueue(ctx, sym, fetch)
+		if err != nil {
+			// Context cancelled while the queue was full; report it for this symbol.
+			job = &fetchJob{done: make(chan struct{}), err: err}
+			close(job.done)
+		}
+		jobs[sym] = job
+	}
+
+	quotes := make(map[string]Quote, len(jobs))
+	failures := make(map[string]error)
+	for sym, job := range jobs {
+		select {
+		case <-job.done:
+			if job.err != nil {
+				failures[sym] = job.err
+				continue
+			}
+			quotes[sym] = job.quote
+		case <-ctx.Done():
+			failures[sym] = ctx.Err()
+		}
+	}
+	return quotes, failures
+}
+
+// enqueue returns the pending job for symbol, creating and queueing one if needed.
+func (fq *FetchQueue) enqueue(ctx context.Context, symbol string,
+	fetch func(ctx context.Context, symbol string) (Quote, error),
+) (*fetchJob, error) {
+	fq.mu.Lock()
+	if job, ok := fq.pending[symbol]; ok {
+		job.waiters++
+		fq.mu.Unlock()
+		return job, nil
+	}
+	job := &fetchJob{
+		// The fetch is shared, so one caller giving up must not cancel it for the others.
+		ctx:    context.WithoutCancel(ctx),
+		symbol: symbol,
+		fetch:  fetch,
+		done:   make(chan struct{}),
+	}
+	fq.pending[symbol] = job
+	fq.mu.Unlock()
+
+	select {
+	case fq.jobs <- job:
+		return job, nil
+	case <-ctx.Done():
+	}
+
+	// The caller gave up while the queue was full. Callers that joined the job still
+	// want it, so it is queued for them; otherwise it is dropped.
+	fq.mu.Lock()
+	shared := job.waiters > 0
+	if !shared {
+		delete(fq.pending, symbol)
+	}
+	fq.mu.Unlock()
+	if shared {
+		go func() { fq.jobs <- job }()
+	} else {
+		job.err = ctx.Err()
+		close(job.done)
+	}
+	return nil, ctx.Err()
+}
+
+func (fq *FetchQueue) worker() {
+	for job := range fq.jobs {
+		if err := fq.limiter.Wait(job.ctx); err != nil {
+			job.err = err
+		} else {
+			job.quote, job.err = job.fetch(job.ctx, job.symbol)
+		}
+		fq.finish(job)
+	}
+}
+
+func (fq *FetchQueue) finish(job *fetchJob) {
+	fq.mu.Lock()
+	if fq.pending[job.symbol] == job {
+		delete(fq.pending, job.symbol)
+	}
+	fq.mu.Unlock()
+	close(job.done)
+}
+
+// tokenBucket is a simple token-bucket rate limiter. A non-positive rate disables limiting.
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // tokens added per second
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(ratePerSec float64) *tokenBucket {
+	capacity := math.Max(1, math.Floor(ratePerSec))
+	return &tokenBucket{
+		rate:     ratePerSec,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (tb *tokenBucket) Wait(ctx context.Context) error {
+	if tb.rate <= 0 {
+		return nil
+	}
+	for {
+		tb.mu.Lock()
+		now := time.Now()
+		tb.tokens = math.Min(tb.capacity, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+		tb.last = now
+		if tb.tokens >= 1 {
+			tb.tokens--
+			tb.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+		tb.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
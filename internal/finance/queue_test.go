@@ -0,0 +1,137 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func quoteFetch(_ context.Context, symbol string) (Quote, error) {
+	return Quote{Symbol: symbol, Price: 100, Currency: "USD"}, nil
+}
+
+// waitForPending blocks until symbol has a pending job in fq.
+func waitForPending(t *testing.T, fq *FetchQueue, symbol string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		fq.mu.Lock()
+		_, ok := fq.pending[symbol]
+		fq.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no job pending for %s", symbol)
+}
+
+// fullQueue returns a FetchQueue whose jobs cannot be queued until a worker starts.
+func fullQueue() *FetchQueue {
+	return &FetchQueue{
+		limiter: newTokenBucket(0),
+		jobs:    make(chan *fetchJob),
+		pending: make(map[string]*fetchJob),
+	}
+}
+
+func TestFetchQueueOwnerCancelKeepsSharedJob(t *testing.T) {
+	fq := fullQueue()
+	ownerCtx, cancel := context.WithCancel(context.Background())
+	ownerErr := make(chan error, 1)
+	go func() {
+		_, err := fq.enqueue(ownerCtx, "AAPL", quoteFetch)
+		ownerErr <- err
+	}()
+	waitForPending(t, fq, "AAPL")
+
+	job, err := fq.enqueue(context.Background(), "AAPL", quoteFetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := <-ownerErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("owner err = %v, want context.Canceled", err)
+	}
+
+	go fq.worker()
+	select {
+	case <-job.done:
+	case <-time.After(time.Second):
+		t.Fatal("shared job never ran")
+	}
+	if job.err != nil || job.quote.Price != 100 {
+		t.Errorf("waiter got %+v, %v; want the quote", job.quote, job.err)
+	}
+}
+
+func TestFetchQueueOwnerCancelDropsUnsharedJob(t *testing.T) {
+	fq := fullQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := fq.enqueue(ctx, "AAPL", quoteFetch); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if len(fq.pending) != 0 {
+		t.Errorf("pending = %v, want the cancelled job dropped", fq.pending)
+	}
+}
+
+func TestFetchQueuePerSymbolErrors(t *testing.T) {
+	errNotFound := errors.New("not found")
+	fq := NewFetchQueue(0, 2)
+	quotes, failures := fq.Fetch(context.Background(), []string{"AAPL", "NOPE", "AAPL", "MSFT"},
+		func(ctx context.Context, symbol string) (Quote, error) {
+			if symbol == "NOPE" {
+				return Quote{}, errNotFound
+			}
+			return quoteFetch(ctx, symbol)
+		})
+
+	if len(quotes) != 2 || quotes["AAPL"].Symbol != "AAPL" || quotes["MSFT"].Symbol != "MSFT" {
+		t.Errorf("quotes = %v, want AAPL and MSFT", quotes)
+	}
+	if len(failures) != 1 || !errors.Is(failures["NOPE"], errNotFound) {
+		t.Errorf("failures = %v, want NOPE only", failures)
+	}
+
+	var symErr *SymbolError
+	if err := (&BatchError{Failures: failures}); !errors.As(err, &symErr) || symErr.Symbol != "NOPE" {
+		t.Errorf("BatchError does not unwrap to NOPE's SymbolError: %v", err)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	tb := newTokenBucket(20)
+	ctx := context.Background()
+
+	// A full bucket lets a burst of its capacity through at once.
+	start := time.Now()
+	for range 20 {
+		if err := tb.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("burst took %v, want no waiting", elapsed)
+	}
+
+	// Then it refills at the rate: 2 more tokens take about 100ms.
+	start = time.Now()
+	for range 2 {
+		if err := tb.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("2 tokens past the burst took %v, want about 100ms", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := tb.Wait(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait on an empty bucket with a cancelled context = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,169 @@
+package finance
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// sessionRefreshMargin is how long before expiry RunSessionRefresh replaces the session.
+const sessionRefreshMargin = 5 * time.Minute
+
+// StoredSession is a Yahoo cookie/crumb pair as persisted by a SessionStore.
+type StoredSession struct {
+	Cookie    string
+	Crumb     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// SessionStore persists the Yahoo session so restarts can skip the consent and
+// crumb requests. LoadSession returns nil, nil if nothing is stored.
+type SessionStore interface {
+	LoadSession() (*StoredSession, error)
+	SaveSession(StoredSession) error
+}
+
+// SessionSource says where the current session came from.
+type SessionSource string
+
+const (
+	SessionFetched   SessionSource = "fetched"   // consent + crumb requests
+	SessionRestored  SessionSource = "restored"  // loaded from the SessionStore
+	SessionRefreshed SessionSource = "refreshed" // replaced before expiry by RunSessionRefresh
+)
+
+// SessionState describes the Yahoo session for diagnostics. It never includes
+// the cookie or crumb.
+type SessionState struct {
+	Valid         bool
+	Source        SessionSource
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	Fetches       int       // successful consent + crumb exchanges since start
+	FailedFetches int       // failed exchanges since start
+	LastError     string    // most recent fetch error, "" once a fetch succeeds
+	LastErrorAt   time.Time // when LastError happened
+}
+
+// sessionStats counts session fetches; guarded by YahooClient.sessionMu.
+type sessionStats struct {
+	source      SessionSource
+	fetches     int
+	failed      int
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// SetSessionStore makes the client persist every new session to store and reuses
+// the stored session if it has not expired yet.
+func (yc *YahooClient) SetSessionStore(store SessionStore) error {
+	yc.sessionMu.Lock()
+	defer yc.sessionMu.Unlock()
+
+	yc.sessionStore = store
+	stored, err := store.LoadSession()
+	if err != nil || stored == nil {
+		return err
+	}
+	if stored.Cookie == "" || stored.Crumb == "" || !time.Now().Before(stored.ExpiresAt) {
+		return nil
+	}
+
+	yc.session = &yahooSession{
+		cookie:    stored.Cookie,
+		crumb:     stored.Crumb,
+		createdAt: stored.CreatedAt,
+		expiresAt: stored.ExpiresAt,
+	}
+	yc.sessionStats.source = SessionRestored
+	log.Printf("yahoo: restored session valid until %s", stored.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// SessionState reports the current session for diagnostics.
+func (yc *YahooClient) SessionState() SessionState {
+	yc.sessionMu.Lock()
+	defer yc.sessionMu.Unlock()
+
+	state := SessionState{
+		Source:        yc.sessionStats.source,
+		Fetches:       yc.sessionStats.fetches,
+		FailedFetches: yc.sessionStats.failed,
+		LastError:     yc.sessionStats.lastError,
+		LastErrorAt:   yc.sessionStats.lastErrorAt,
+	}
+	if yc.session != nil {
+		state.CreatedAt = yc.session.createdAt
+		state.ExpiresAt = yc.session.expiresAt
+		state.Valid = time.Now().Before(yc.session.expiresAt)
+	}
+	return state
+}
+
+// RunSessionRefresh replaces the session shortly before it expires, so requests
+// rarely wait on the consent and crumb exchange. It blocks until ctx is cancelled.
+func (yc *YahooClient) RunSessionRefresh(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !yc.sessionExpiringWithin(sessionRefreshMargin) {
+				continue
+			}
+			// Fetch without holding sessionMu so requests keep using the current session.
+			sess, err := yc.fetchNewSession(ctx)
+			yc.sessionMu.Lock()
+			yc.recordSessionFetch(err)
+			if err != nil {
+				yc.sessionMu.Unlock()
+				log.Printf("yahoo: refresh session: %v", err)
+				continue
+			}
+			yc.setSessionLocked(sess, SessionRefreshed)
+			yc.sessionMu.Unlock()
+		}
+	}
+}
+
+// sessionExpiringWithin reports whether there is a session that expires within d.
+// Without a session there is nothing to refresh: the next request fetches one.
+func (yc *YahooClient) sessionExpiringWithin(d time.Duration) bool {
+	yc.sessionMu.Lock()
+	defer yc.sessionMu.Unlock()
+	return yc.session != nil && time.Until(yc.session.expiresAt) < d
+}
+
+// setSessionLocked installs sess and persists it. The caller must hold sessionMu.
+func (yc *YahooClient) setSessionLocked(sess *yahooSession, source SessionSource) {
+	yc.session = sess
+	yc.sessionStats.source = source
+	if yc.sessionStore == nil {
+		return
+	}
+	err := yc.sessionStore.SaveSession(StoredSession{
+		Cookie:    sess.cookie,
+		Crumb:     sess.crumb,
+		CreatedAt: sess.createdAt,
+		ExpiresAt: sess.expiresAt,
+	})
+	if err != nil {
+		log.Printf("yahoo: save session: %v", err)
+	}
+}
+
+// recordSessionFetch updates the fetch counters. The caller must hold sessionMu.
+func (yc *YahooClient) recordSessionFetch(err error) {
+	if err != nil {
+		yc.sessionStats.failed++
+		yc.sessionStats.lastError = err.Error()
+		yc.sessionStats.lastErrorAt = time.Now()
+		return
+	}
+	yc.sessionStats.fetches++
+	yc.sessionStats.lastError = ""
+}
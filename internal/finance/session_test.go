@@ -0,0 +1,80 @@
+package finance
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// memorySessionStore is a SessionStore holding one session in memory.
+type memorySessionStore struct {
+	stored *StoredSession
+	saves  int
+}
+
+func (s *memorySessionStore) LoadSession() (*StoredSession, error) { return s.stored, nil }
+
+func (s *memorySessionStore) SaveSession(sess StoredSession) error {
+	s.stored = &sess
+	s.saves++
+	return nil
+}
+
+func TestSetSessionStoreRestoresUnexpiredSession(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		stored  *StoredSession
+		restore bool
+	}{
+		{"nothing stored", nil, false},
+		{"valid", &StoredSession{Cookie: "A3=x", Crumb: "c", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}, true},
+		{"expired", &StoredSession{Cookie: "A3=x", Crumb: "c", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Minute)}, false},
+		{"no crumb", &StoredSession{Cookie: "A3=x", ExpiresAt: now.Add(time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		yc := &YahooClient{}
+		if err := yc.SetSessionStore(&memorySessionStore{stored: tt.stored}); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		state := yc.SessionState()
+		if state.Valid != tt.restore || (state.Source == SessionRestored) != tt.restore {
+			t.Errorf("%s: state %+v, want restored %v", tt.name, state, tt.restore)
+		}
+	}
+}
+
+func TestSessionRefreshBookkeeping(t *testing.T) {
+	store := &memorySessionStore{}
+	yc := &YahooClient{}
+	if err := yc.SetSessionStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a session there is nothing to refresh ahead of time.
+	if yc.sessionExpiringWithin(sessionRefreshMargin) {
+		t.Error("no session reported as expiring")
+	}
+
+	now := time.Now()
+	yc.sessionMu.Lock()
+	yc.recordSessionFetch(errors.New("no cookie"))
+	yc.recordSessionFetch(nil)
+	yc.setSessionLocked(&yahooSession{cookie: "A3=x", crumb: "c", createdAt: now, expiresAt: now.Add(3 * time.Minute)}, SessionFetched)
+	yc.sessionMu.Unlock()
+
+	if store.saves != 1 || store.stored.Crumb != "c" || !store.stored.ExpiresAt.Equal(now.Add(3*time.Minute)) {
+		t.Errorf("stored %+v after %d saves, want the new session saved once", store.stored, store.saves)
+	}
+	if !yc.sessionExpiringWithin(sessionRefreshMargin) {
+		t.Error("session expiring in 3m not due for refresh")
+	}
+	if yc.sessionExpiringWithin(time.Minute) {
+		t.Error("session expiring in 3m due within 1m")
+	}
+
+	state := yc.SessionState()
+	if !state.Valid || state.Source != SessionFetched || state.Fetches != 1 || state.FailedFetches != 1 || state.LastError != "" {
+		t.Errorf("state = %+v, want a valid fetched session after one failure and one success", state)
+	}
+}
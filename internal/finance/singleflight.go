@@ -0,0 +1,121 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// flightGroup coalesces concurrent fetches for the same keys: the first caller to
+// miss a key fetches it, later callers wait for and share that result.
+type flightGroup[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall[V]
+}
+
+type flightCall[V any] struct {
+	done chan struct{}
+	val  V
+	ok   bool
+	err  error
+}
+
+// do returns values for keys, fetching only the keys no other caller is already
+// fetching. fetch may return partial results together with an error; keys missing
+// from its result report that error to every waiter, or ErrSymbolNotFound if it
+// returned none. The fetch is shared, so it runs without the owner's cancellation:
+// a caller that gives up gets its context's error while the others keep waiting.
+func (g *flightGroup[V]) do(ctx context.Context, keys []string,
+	fetch func(ctx context.Context, keys []string) (map[string]V, error),
+) (map[string]V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall[V])
+	}
+	owned := make(map[string]*flightCall[V])
+	waiting := make(map[string]*flightCall[V])
+	var ownedKeys []string
+	for _, key := range keys {
+		if _, ok := owned[key]; ok {
+			continue
+		}
+		if c, ok := g.calls[key]; ok {
+			waiting[key] = c
+			continue
+		}
+		c := &flightCall[V]{done: make(chan struct{})}
+		g.calls[key] = c
+		owned[key] = c
+		ownedKeys = append(ownedKeys, key)
+	}
+	g.mu.Unlock()
+
+	results := make(map[string]V, len(keys))
+	var errs []error
+	addErr := func(err error) {
+		for _, e := range errs {
+			if e == err {
+				return
+			}
+		}
+		errs = append(errs, err)
+	}
+
+	if len(ownedKeys) > 0 {
+		flight := make(chan struct{})
+		var fetchErr error
+		go func() {
+			defer close(flight)
+			vals, err := fetch(context.WithoutCancel(ctx), ownedKeys)
+			fetchErr = err
+
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			for key, c := range owned {
+				c.val, c.ok = vals[key]
+				if !c.ok {
+					c.err = err
+					if c.err == nil {
+						c.err = fmt.Errorf("%w: %s", ErrSymbolNotFound, key)
+					}
+				}
+				delete(g.calls, key)
+				close(c.done)
+			}
+		}()
+
+		select {
+		case <-flight:
+			// A fetch error is reported even with partial results, for the caller to log.
+			if fetchErr != nil {
+				addErr(fetchErr)
+			}
+		case <-ctx.Done():
+			addErr(ctx.Err())
+		}
+		// Owned keys are read like waited-for ones; after cancellation the select
+		// below returns at once.
+		for key, c := range owned {
+			waiting[key] = c
+		}
+	}
+
+	for key, c := range waiting {
+		select {
+		case <-c.done:
+			if c.ok {
+				results[key] = c.val
+			} else if c.err != nil {
+				addErr(c.err)
+			}
+		case <-ctx.Done():
+			addErr(ctx.Err())
+		}
+	}
+
+	if len(errs) == 1 {
+		return results, errs[0]
+	}
+	return results, errors.Join(errs...)
+}
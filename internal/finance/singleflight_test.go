@@ -0,0 +1,141 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingFetch is a fake provider that counts its calls and blocks each one until
+// release is closed.
+type countingFetch struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (f *countingFetch) fetch(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	f.calls.Add(1)
+	<-f.release
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	quotes := make(map[string]Quote, len(symbols))
+	for _, sym := range symbols {
+		if sym == "GONE" {
+			continue // not returned, no error
+		}
+		quotes[sym] = Quote{Symbol: sym, Price: 100, Currency: "USD"}
+	}
+	return quotes, nil
+}
+
+// waitForFlight blocks until key has an in-flight fetch in g.
+func waitForFlight[V any](t *testing.T, g *flightGroup[V], key string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		g.mu.Lock()
+		_, ok := g.calls[key]
+		g.mu.Unlock()
+		if ok {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no flight started for %s", key)
+}
+
+func TestPriceCacheCoalescesConcurrentMisses(t *testing.T) {
+	const callers = 20
+	pc := NewPriceCache(time.Minute)
+	f := &countingFetch{release: make(chan struct{})}
+
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	results := make([]map[string]Quote, callers)
+	start := func(i int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = pc.GetOrFetch(context.Background(), []string{"AAPL"}, f.fetch)
+		}()
+	}
+
+	// The first caller starts the flight; the rest join it while it is blocked.
+	start(0)
+	waitForFlight(t, &pc.flights, "AAPL")
+	for i := 1; i < callers; i++ {
+		start(i)
+	}
+	// Give the joiners time to reach the flight before it lands.
+	time.Sleep(20 * time.Millisecond)
+	close(f.release)
+	wg.Wait()
+
+	if got := f.calls.Load(); got != 1 {
+		t.Errorf("upstream calls = %d, want 1", got)
+	}
+	for i := range results {
+		if errs[i] != nil {
+			t.Errorf("caller %d: %v", i, errs[i])
+		}
+		if q, ok := results[i]["AAPL"]; !ok || q.Price != 100 {
+			t.Errorf("caller %d got %+v, want AAPL at 100", i, results[i])
+		}
+	}
+}
+
+func TestFlightSurvivesOwnerCancel(t *testing.T) {
+	var g flightGroup[Quote]
+	f := &countingFetch{release: make(chan struct{})}
+
+	ownerCtx, cancel := context.WithCancel(context.Background())
+	ownerErr := make(chan error, 1)
+	go func() {
+		_, err := g.do(ownerCtx, []string{"AAPL"}, f.fetch)
+		ownerErr <- err
+	}()
+	waitForFlight(t, &g, "AAPL")
+
+	waiterDone := make(chan map[string]Quote, 1)
+	go func() {
+		vals, err := g.do(context.Background(), []string{"AAPL"}, f.fetch)
+		if err != nil {
+			t.Errorf("waiter: %v", err)
+		}
+		waiterDone <- vals
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-ownerErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("owner error = %v, want context.Canceled", err)
+	}
+	close(f.release)
+	if vals := <-waiterDone; vals["AAPL"].Price != 100 {
+		t.Errorf("waiter got %+v, want AAPL at 100", vals)
+	}
+	if got := f.calls.Load(); got != 1 {
+		t.Errorf("upstream calls = %d, want 1", got)
+	}
+}
+
+func TestFlightMissingKeyIsNotFound(t *testing.T) {
+	var g flightGroup[Quote]
+	f := &countingFetch{release: make(chan struct{})}
+	close(f.release)
+
+	vals, err := g.do(context.Background(), []string{"AAPL", "GONE"}, f.fetch)
+	if !errors.Is(err, ErrSymbolNotFound) {
+		t.Errorf("error = %v, want ErrSymbolNotFound", err)
+	}
+	if _, ok := vals["AAPL"]; !ok {
+		t.Error("AAPL missing from partial results")
+	}
+	if _, ok := vals["GONE"]; ok {
+		t.Error("GONE returned a value")
+	}
+}
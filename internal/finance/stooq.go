@@ -0,0 +1,201 @@
+package finance
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const stooqURL = "https://stooq.com/q/l/"
+
+// stooqMarket maps a Yahoo symbol suffix to the Stooq suffix and quote currency.
+type stooqMarket struct {
+	suffix   string
+	currency string
+}
+
+// stooqMarkets lists the Yahoo exchange suffixes Stooq can price.
+// Currencies use Yahoo codes so normalizeYahooCurrency handles subunits (GBp).
+var stooqMarkets = map[string]stooqMarket{
+	"":    {suffix: ".us", currency: "USD"},
+	".L":  {suffix: ".uk", currency: "GBp"},
+	".DE": {suffix: ".de", currency: "EUR"},
+	".T":  {suffix: ".jp", currency: "JPY"},
+	".HK": {suffix: ".hk", currency: "HKD"},
+}
+
+// StooqClient fetches delayed quotes from Stooq's CSV endpoint.
+// It has no search API and is intended as a fallback price source.
+type StooqClient struct {
+	baseURL string
+	client  *http.Client
+	breaker *circuitBreaker
+}
+
+// NewStooqClient creates a StooqClient. An empty baseURL uses the public Stooq endpoint.
+func NewStooqClient(baseURL string, breaker BreakerConfig) *StooqClient {
+	if baseURL == "" {
+		baseURL = stooqURL
+	}
+	return &StooqClient{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		breaker: newCircuitBreaker("stooq", breaker),
+	}
+}
+
+// Name identifies the provider in logs and QUOTE_PROVIDERS.
+func (sc *StooqClient) Name() string { return "stooq" }
+
+// Health reports the circuit breaker state.
+func (sc *StooqClient) Health() []ProviderHealth {
+	return []ProviderHealth{sc.breaker.health()}
+}
+
+// SearchTickers is not supported by Stooq.
+func (sc *StooqClient) SearchTickers(ctx context.Context, query string) ([]TickerResult, error) {
+	return nil, ErrUnsupported
+}
+
+// GetQuotes fetches the latest close for each Yahoo-style symbol in a single request.
+// Symbols on markets Stooq does not cover are left out of the result.
+func (sc *StooqClient) GetQuotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	quotes := make(map[string]Quote, len(symbols))
+	if len(symbols) == 0 {
+		return quotes, nil
+	}
+
+	type target struct {
+		symbol   string
+		currency string
+	}
+	targets := make(map[string]target, len(symbols))
+	stooqSymbols := make([]string, 0, len(symbols))
+	var unsupported []string
+	for _, sym := range symbols {
+		stooqSym, currency, ok := toStooqSymbol(sym)
+		if !ok {
+			unsupported = append(unsupported, sym)
+			continue
+		}
+		targets[strings.ToUpper(stooqSym)] = target{symbol: sym, currency: currency}
+		stooqSymbols = append(stooqSymbols, stooqSym)
+	}
+	if len(stooqSymbols) == 0 {
+		return quotes, fmt.Errorf("stooq does not cover symbols %v", unsupported)
+	}
+
+	params := url.Values{}
+	params.Set("s", strings.Join(stooqSymbols, " "))
+	params.Set("f", "sd2t2ohlcv")
+	params.Set("h", "")
+	params.Set("e", "csv")
+
+	var records [][]string
+	err := sc.breaker.do(func() error {
+		var err error
+		records, err = sc.fetchCSV(ctx, params)
+		return err
+	})
+	if err != nil {
+		return quotes, err
+	}
+
+	// Columns: Symbol,Date,Time,Open,High,Low,Close,Volume (first row is the header).
+	for i, rec := range records {
+		if i == 0 || len(rec) < 7 {
+			continue
+		}
+		t, ok := targets[strings.ToUpper(rec[0])]
+		if !ok {
+			continue
+		}
+		price, err := strconv.ParseFloat(rec[6], 64)
+		if err != nil || price <= 0 {
+			continue // "N/D" for unknown symbols
+		}
+		currency, divisor := normalizeYahooCurrency(t.currency)
+		q := Quote{
+			Symbol:   t.symbol,
+			Price:    price / divisor,
+			Currency: currency,
+			Source:   "stooq",
+		}
+		if high, err := strconv.ParseFloat(rec[4], 64); err == nil {
+			q.DayHigh = high / divisor
+		}
+		if low, err := strconv.ParseFloat(rec[5], 64); err == nil {
+			q.DayLow = low / divisor
+		}
+		quotes[t.symbol] = q
+	}
+
+	var missing []string
+	for _, sym := range symbols {
+		if _, ok := quotes[sym]; !ok {
+			missing = append(missing, sym)
+		}
+	}
+	if len(missing) > 0 {
+		return quotes, fmt.Errorf("stooq returned no data for %v: %w", missing, ErrNoPrice)
+	}
+	return quotes, nil
+}
+
+// fetchCSV performs the quote request and returns the parsed CSV rows.
+func (sc *StooqClient) fetchCSV(ctx context.Context, params url.Values) ([][]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sc.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build stooq request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+
+	resp, err := sc.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("stooq request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("stooq: %w", newHTTPError(resp))
+	}
+
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse stooq response: %w", err)
+	}
+	return records, nil
+}
+
+// GetRates returns how many units of pair.To equal one unit of pair.From.
+func (sc *StooqClient) GetRates(ctx context.Context, pairs []CurrencyPair) (map[CurrencyPair]float64, error) {
+	return pairRates(ctx, pairs, sc.GetQuotes)
+}
+
+// toStooqSymbol converts a Yahoo symbol (AAPL, VOD.L, EURUSD=X) to its Stooq
+// equivalent and the Yahoo currency code its prices are quoted in.
+func toStooqSymbol(symbol string) (string, string, bool) {
+	sym := strings.ToUpper(strings.TrimSpace(symbol))
+
+	if pair, ok := strings.CutSuffix(sym, "=X"); ok {
+		if len(pair) != 6 {
+			return "", "", false
+		}
+		return strings.ToLower(pair), pair[3:], true
+	}
+
+	base, suffix := sym, ""
+	if i := strings.LastIndex(sym, "."); i > 0 {
+		base, suffix = sym[:i], sym[i:]
+	}
+	market, ok := stooqMarkets[suffix]
+	if !ok || strings.ContainsAny(base, "^=") {
+		return "", "", false
+	}
+	return strings.ToLower(base) + market.suffix, market.currency, true
+}
@@ -0,0 +1,95 @@
+package finance
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestToStooqSymbol(t *testing.T) {
+	tests := []struct {
+		symbol   string
+		stooq    string
+		currency string
+		ok       bool
+	}{
+		{"AAPL", "aapl.us", "USD", true},
+		{"brk.b", "brk.us", "", false}, // share classes are not a market suffix Stooq knows
+		{"VOD.L", "vod.uk", "GBp", true},
+		{"SAP.DE", "sap.de", "EUR", true},
+		{"7203.T", "7203.jp", "JPY", true},
+		{"0700.HK", "0700.hk", "HKD", true},
+		{"EURUSD=X", "eurusd", "USD", true},
+		{"usdjpy=x", "usdjpy", "JPY", true},
+		{"EUR=X", "", "", false},
+		{"^GSPC", "", "", false},
+		{"ES=F", "", "", false},
+		{"SHOP.TO", "", "", false},
+	}
+	for _, tt := range tests {
+		stooq, currency, ok := toStooqSymbol(tt.symbol)
+		if ok != tt.ok || (ok && (stooq != tt.stooq || currency != tt.currency)) {
+			t.Errorf("toStooqSymbol(%q) = %q, %q, %v; want %q, %q, %v",
+				tt.symbol, stooq, currency, ok, tt.stooq, tt.currency, tt.ok)
+		}
+	}
+}
+
+func TestStooqGetQuotes(t *testing.T) {
+	const csv = "Symbol,Date,Time,Open,High,Low,Close,Volume\n" +
+		"AAPL.US,2024-03-08,22:00:07,169.0,173.7,168.9,170.73,76114634\n" +
+		"VOD.UK,2024-03-08,17:35:12,70.1,71.2,69.8,70.5,51234567\n" +
+		"EURUSD,2024-03-08,22:59:58,1.0942,1.0981,1.0932,1.0938,\n" +
+		"NOPE.US,N/D,N/D,N/D,N/D,N/D,N/D,N/D\n"
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("s")
+		_, _ = w.Write([]byte(csv))
+	}))
+	defer srv.Close()
+
+	sc := NewStooqClient(srv.URL, BreakerConfig{})
+	quotes, err := sc.GetQuotes(context.Background(), []string{"AAPL", "VOD.L", "EURUSD=X", "NOPE"})
+	if !errors.Is(err, ErrNoPrice) {
+		t.Errorf("error = %v, want ErrNoPrice for NOPE", err)
+	}
+	if gotQuery != "aapl.us vod.uk eurusd nope.us" {
+		t.Errorf("requested %q", gotQuery)
+	}
+
+	tests := []struct {
+		symbol   string
+		price    float64
+		high     float64
+		currency string
+	}{
+		{"AAPL", 170.73, 173.7, "USD"},
+		{"VOD.L", 0.705, 0.712, "GBP"}, // pence in pounds
+		{"EURUSD=X", 1.0938, 1.0981, "USD"},
+	}
+	for _, tt := range tests {
+		q, ok := quotes[tt.symbol]
+		if !ok {
+			t.Errorf("%s missing", tt.symbol)
+			continue
+		}
+		if math.Abs(q.Price-tt.price) > 1e-9 || math.Abs(q.DayHigh-tt.high) > 1e-9 ||
+			q.Currency != tt.currency || q.Source != "stooq" {
+			t.Errorf("%s = %+v, want %v (high %v) %s from stooq", tt.symbol, q, tt.price, tt.high, tt.currency)
+		}
+	}
+	if _, ok := quotes["NOPE"]; ok {
+		t.Error("N/D row returned a quote")
+	}
+}
+
+func TestStooqGetQuotesUncovered(t *testing.T) {
+	sc := NewStooqClient("http://127.0.0.1:1", BreakerConfig{}) // never contacted
+	quotes, err := sc.GetQuotes(context.Background(), []string{"^GSPC", "SHOP.TO"})
+	if err == nil || len(quotes) != 0 {
+		t.Errorf("GetQuotes = %v, %v; want no quotes and an error", quotes, err)
+	}
+}
@@ -13,20 +13,49 @@ import (
 )
 
 const (
-	searchURL  = "https://query2.finance.yahoo.com/v1/finance/search"
-	chartURL   = "https://query1.finance.yahoo.com/v8/finance/chart"
-	consentURL = "https://fc.yahoo.com/"
-	crumbURL   = "https://query2.finance.yahoo.com/v1/test/getcrumb"
-
+	searchTTL  = time.Hour
 	sessionTTL = 30 * time.Minute
 )
 
+// YahooEndpoints are the URLs the client talks to. Override them to run against
+// a stand-in such as financetest.YahooServer.
+type YahooEndpoints struct {
+	Search       string // v1/finance/search
+	Chart        string // v8/finance/chart, symbol is appended as a path segment
+	QuoteSummary string // v10/finance/quoteSummary, symbol is appended as a path segment
+	Consent      string // sets the session cookie
+	Crumb        string // exchanges the cookie for a crumb
+}
+
+// DefaultYahooEndpoints are the public Yahoo Finance URLs.
+var DefaultYahooEndpoints = YahooEndpoints{
+	Search:       "https://query2.finance.yahoo.com/v1/finance/search",
+	Chart:        "https://query1.finance.yahoo.com/v8/finance/chart",
+	QuoteSummary: "https://query2.finance.yahoo.com/v10/finance/quoteSummary",
+	Consent:      "https://fc.yahoo.com/",
+	Crumb:        "https://query2.finance.yahoo.com/v1/test/getcrumb",
+}
+
+// YahooEndpointsAt returns endpoints with Yahoo's paths under a single base URL,
+// e.g. "http://127.0.0.1:8080".
+func YahooEndpointsAt(baseURL string) YahooEndpoints {
+	base := strings.TrimRight(baseURL, "/")
+	return YahooEndpoints{
+		Search:       base + "/v1/finance/search",
+		Chart:        base + "/v8/finance/chart",
+		QuoteSummary: base + "/v10/finance/quoteSummary",
+		Consent:      base + "/consent",
+		Crumb:        base + "/v1/test/getcrumb",
+	}
+}
+
 // TickerResult is a single search result from Yahoo Finance.
 type TickerResult struct {
-	Symbol   string
-	Name     string
-	Exchange string
-	Type     string // "EQUITY", "ETF", etc.
+	Symbol       string
+	Name         string
+	Exchange     string // Yahoo exchange code, e.g. "GER"
+	ExchangeName string // display name, e.g. "XETRA"
+	Type         string // Yahoo quote type, one of the Type* constants
 }
 
 // Quote holds the latest price data for a symbol.
@@ -34,32 +63,100 @@ type Quote struct {
 	Symbol   string
 	Price    float64
 	Currency string
+	Type     string    // Yahoo quote type if the provider reports it, e.g. TypeFuture
+	Source   string    // provider name, e.g. "yahoo"
+	AsOf     time.Time // when the price was fetched; set by the cache
+	Stale    bool      // last known price served because a refresh failed
+
+	// Session data; zero when the provider does not report it.
+	PreviousClose    float64
+	Change           float64 // Price - PreviousClose
+	ChangePercent    float64 // Change relative to PreviousClose, in percent
+	DayHigh          float64
+	DayLow           float64
+	FiftyTwoWeekHigh float64
+	FiftyTwoWeekLow  float64
+	ExchangeTimezone string // IANA name, e.g. "America/New_York"
+	MarketState      MarketState
+}
+
+// MarketState is the trading session a quote was taken in.
+type MarketState string
+
+const (
+	MarketPre     MarketState = "PRE"
+	MarketRegular MarketState = "REGULAR"
+	MarketPost    MarketState = "POST"
+	MarketClosed  MarketState = "CLOSED"
+)
+
+// setPreviousClose fills PreviousClose and the derived day change fields.
+func (q *Quote) setPreviousClose(prev float64) {
+	q.PreviousClose = prev
+	if prev <= 0 {
+		return
+	}
+	q.Change = q.Price - prev
+	q.ChangePercent = q.Change / prev * 100
 }
 
 // yahooSession holds the cookie and crumb required by Yahoo Finance API.
 type yahooSession struct {
 	cookie    string
 	crumb     string
+	createdAt time.Time
 	expiresAt time.Time
 }
 
-// YahooClient fetches data from Yahoo Finance with session-based auth and a shared price cache.
+// YahooClient fetches data from Yahoo Finance with session-based auth.
 type YahooClient struct {
-	cache  *PriceCache
-	client *http.Client
-
-	sessionMu sync.Mutex
-	session   *yahooSession
+	history      *HistoryCache
+	fundamentals *FundamentalsCache
+	news         *NewsCache
+	searches     *SearchCache
+	queue        *FetchQueue
+	breaker      *circuitBreaker
+	endpoints    YahooEndpoints
+	client       *http.Client
+
+	sessionMu    sync.Mutex
+	session      *yahooSession
+	sessionStore SessionStore
+	sessionStats sessionStats
 }
 
-// NewYahooClient creates a YahooClient backed by the given PriceCache.
-func NewYahooClient(cache *PriceCache) *YahooClient {
+// NewYahooClient creates a YahooClient that sends at most ratePerSec chart requests
+// per second (non-positive disables the limit). Candle series are cached for
+// historyTTL. Requests fail fast with ErrCircuitOpen while breaker considers Yahoo down.
+func NewYahooClient(historyTTL time.Duration, ratePerSec float64, breaker BreakerConfig) *YahooClient {
 	return &YahooClient{
-		cache:  cache,
-		client: &http.Client{Timeout: 10 * time.Second},
+		history:      NewHistoryCache(historyTTL),
+		fundamentals: NewFundamentalsCache(fundamentalsTTL),
+		news:         NewNewsCache(newsTTL),
+		searches:     NewSearchCache(searchTTL),
+		queue:        NewFetchQueue(ratePerSec, defaultFetchWorkers),
+		breaker:      newCircuitBreaker("yahoo", breaker),
+		endpoints:    DefaultYahooEndpoints,
+		client:       &http.Client{Timeout: 10 * time.Second},
 	}
 }
 
+// SetEndpoints points the client at different URLs. Call it before first use.
+func (yc *YahooClient) SetEndpoints(e YahooEndpoints) {
+	yc.endpoints = e
+}
+
+// Name identifies the provider in logs and QUOTE_PROVIDERS.
+func (yc *YahooClient) Name() string { return "yahoo" }
+
+// Health reports the circuit breaker and session state.
+func (yc *YahooClient) Health() []ProviderHealth {
+	h := yc.breaker.health()
+	session := yc.SessionState()
+	h.Session = &session
+	return []ProviderHealth{h}
+}
+
 // --- session management ---
 
 func (yc *YahooClient) getSession(ctx context.Context) (*yahooSession, error) {
@@ -71,22 +168,27 @@ func (yc *YahooClient) getSession(ctx context.Context) (*yahooSession, error) {
 	}
 
 	sess, err := yc.fetchNewSession(ctx)
+	yc.recordSessionFetch(err)
 	if err != nil {
 		return nil, err
 	}
-	yc.session = sess
+	yc.setSessionLocked(sess, SessionFetched)
 	return sess, nil
 }
 
-func (yc *YahooClient) invalidateSession() {
+// invalidateSession drops sess if it is still the current session, so concurrent
+// requests failing with the same stale session trigger only one refresh.
+func (yc *YahooClient) invalidateSession(sess *yahooSession) {
 	yc.sessionMu.Lock()
-	yc.session = nil
+	if yc.session == sess {
+		yc.session = nil
+	}
 	yc.sessionMu.Unlock()
 }
 
 func (yc *YahooClient) fetchNewSession(ctx context.Context) (*yahooSession, error) {
 	// Step 1: hit fc.yahoo.com to get a consent cookie.
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, consentURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, yc.endpoints.Consent, nil)
 	if err != nil {
 		return nil, fmt.Errorf("build consent request: %w", err)
 	}
@@ -112,7 +214,7 @@ func (yc *YahooClient) fetchNewSession(ctx context.Context) (*yahooSession, erro
 	}
 
 	// Step 2: exchange the cookie for a crumb.
-	crumbReq, err := http.NewRequestWithContext(ctx, http.MethodGet, crumbURL, nil)
+	crumbReq, err := http.NewRequestWithContext(ctx, http.MethodGet, yc.endpoints.Crumb, nil)
 	if err != nil {
 		return nil, fmt.Errorf("build crumb request: %w", err)
 	}
@@ -135,10 +237,12 @@ func (yc *YahooClient) fetchNewSession(ctx context.Context) (*yahooSession, erro
 		return nil, fmt.Errorf("yahoo returned invalid crumb: %q", crumb)
 	}
 
+	now := time.Now()
 	return &yahooSession{
 		cookie:    cookie,
 		crumb:     crumb,
-		expiresAt: time.Now().Add(sessionTTL),
+		createdAt: now,
+		expiresAt: now.Add(sessionTTL),
 	}, nil
 }
 
@@ -153,31 +257,24 @@ func extractCookies(resp *http.Response) string {
 
 // --- public API ---
 
-// SearchTickers queries Yahoo Finance for tickers matching query.
-// Results are filtered to EQUITY and ETF types only.
+// SearchTickers queries Yahoo Finance for tickers matching query. ISINs are
+// resolved to their listings by the endpoint itself.
+// Results are filtered to the quote types in typeLabels and cached per query;
+// the returned slice is shared and must not be modified.
 // Search does not require authentication.
 func (yc *YahooClient) SearchTickers(ctx context.Context, query string) ([]TickerResult, error) {
+	if results, ok := yc.searches.Get(query); ok {
+		return results, nil
+	}
+
 	params := url.Values{}
 	params.Set("q", query)
 	params.Set("quotesCount", "8")
 	params.Set("newsCount", "0")
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
-		searchURL+"?"+params.Encode(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("build search request: %w", err)
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0")
-
-	resp, err := yc.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("search request: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := yc.search(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("read search response: %w", err)
+		return nil, err
 	}
 
 	var payload struct {
@@ -186,6 +283,7 @@ func (yc *YahooClient) SearchTickers(ctx context.Context, query string) ([]Ticke
 			Shortname string `json:"shortname"`
 			Longname  string `json:"longname"`
 			Exchange  string `json:"exchange"`
+			ExchDisp  string `json:"exchDisp"`
 			QuoteType string `json:"quoteType"`
 		} `json:"quotes"`
 	}
@@ -195,7 +293,7 @@ func (yc *YahooClient) SearchTickers(ctx context.Context, query string) ([]Ticke
 
 	var results []TickerResult
 	for _, q := range payload.Quotes {
-		if q.QuoteType != "EQUITY" && q.QuoteType != "ETF" {
+		if !SupportedType(q.QuoteType) {
 			continue
 		}
 		name := q.Shortname
@@ -203,171 +301,132 @@ func (yc *YahooClient) SearchTickers(ctx context.Context, query string) ([]Ticke
 			name = q.Longname
 		}
 		results = append(results, TickerResult{
-			Symbol:   q.Symbol,
-			Name:     name,
-			Exchange: q.Exchange,
-			Type:     q.QuoteType,
+			Symbol:       q.Symbol,
+			Name:         name,
+			Exchange:     q.Exchange,
+			ExchangeName: q.ExchDisp,
+			Type:         q.QuoteType,
 		})
 	}
+	yc.searches.Set(query, results)
 	return results, nil
 }
 
-// GetQuotes returns prices for the given symbols, using the cache where fresh.
-func (yc *YahooClient) GetQuotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
-	if len(symbols) == 0 {
-		return map[string]Quote{}, nil
-	}
+// search calls the unauthenticated v1/finance/search endpoint through the circuit
+// breaker and returns the raw body.
+func (yc *YahooClient) search(ctx context.Context, params url.Values) ([]byte, error) {
+	var body []byte
+	err := yc.breaker.do(func() error {
+		var err error
+		body, err = yc.doSearch(ctx, params)
+		return err
+	})
+	return body, err
+}
 
-	found, missing := yc.cache.GetMulti(symbols)
-	if len(missing) == 0 {
-		return found, nil
+func (yc *YahooClient) doSearch(ctx context.Context, params url.Values) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		yc.endpoints.Search+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build search request: %w", err)
 	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
 
-	fetched, err := yc.fetchBatch(ctx, missing)
+	resp, err := yc.client.Do(req)
 	if err != nil {
-		return found, err
+		return nil, fmt.Errorf("search request: %w", err)
 	}
+	defer func() { _ = resp.Body.Close() }()
 
-	yc.cache.SetMulti(fetched)
-	for k, v := range fetched {
-		found[k] = v
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("search: %w", newHTTPError(resp))
 	}
-	return found, nil
-}
 
-// GetUSDRates returns exchange rates for the requested currencies relative to USD.
-// The returned value is how many units of each currency equal 1 USD.
-func (yc *YahooClient) GetUSDRates(ctx context.Context, currencies []string) (map[string]float64, error) {
-	rates := make(map[string]float64, len(currencies))
-	if len(currencies) == 0 {
-		return rates, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read search response: %w", err)
 	}
+	return body, nil
+}
 
-	forexSymbols := make([]string, 0, len(currencies))
-	symbolToCurrency := make(map[string]string, len(currencies))
-	seen := make(map[string]struct{}, len(currencies))
-
-	for _, code := range currencies {
-		currency, _ := normalizeYahooCurrency(strings.TrimSpace(code))
-		currency = strings.ToUpper(currency)
-		if currency == "" {
-			return nil, fmt.Errorf("currency code cannot be empty")
-		}
-		if _, ok := seen[currency]; ok {
-			continue
-		}
-		seen[currency] = struct{}{}
-
-		if currency == "USD" {
-			rates[currency] = 1
-			continue
-		}
-
-		symbol := currency + "USD=X"
-		forexSymbols = append(forexSymbols, symbol)
-		symbolToCurrency[symbol] = currency
+// GetQuotes returns prices for the given symbols. If some symbols fail, the rest
+// are still returned together with a *BatchError. Quotes are not cached here; put
+// the client behind a FallbackProvider with a cache for that.
+func (yc *YahooClient) GetQuotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	if len(symbols) == 0 {
+		return map[string]Quote{}, nil
 	}
+	return yc.fetchBatch(ctx, symbols)
+}
 
-	if len(forexSymbols) == 0 {
-		return rates, nil
-	}
+// GetRates returns exchange rates for the requested currency pairs, quoting crosses
+// directly where Yahoo lists them and triangulating through USD otherwise.
+func (yc *YahooClient) GetRates(ctx context.Context, pairs []CurrencyPair) (map[CurrencyPair]float64, error) {
+	return pairRates(ctx, pairs, yc.fetchBatch)
+}
 
-	quotes, err := yc.fetchBatch(ctx, forexSymbols)
-	fetchErr := err
-	var missing []string
-	for symbol, currency := range symbolToCurrency {
-		q, ok := quotes[symbol]
-		if !ok {
-			missing = append(missing, currency)
-			continue
-		}
-		if q.Price <= 0 {
-			missing = append(missing, currency)
-			continue
+// fetchBatch fetches prices for multiple symbols using the v8/chart endpoint,
+// one request per symbol (the chart endpoint is per-symbol, not batch), through
+// the rate-limited fetch queue. A failing symbol does not abort the others: the
+// successful quotes are returned together with a *BatchError listing the failures.
+func (yc *YahooClient) fetchBatch(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	if yc.breaker.rejecting() {
+		// Skip the queue's rate limiter too: callers fall back to stale prices at once.
+		failures := make(map[string]error, len(symbols))
+		for _, sym := range symbols {
+			failures[sym] = fmt.Errorf("yahoo: %w", ErrCircuitOpen)
 		}
-
-		// Yahoo symbol <CURRENCY>USD=X is <CURRENCY>/USD, invert for USD-><CURRENCY>.
-		rates[currency] = 1 / q.Price
+		return map[string]Quote{}, &BatchError{Failures: failures}
 	}
 
-	if fetchErr != nil || len(missing) > 0 {
-		errMsg := ""
-		if fetchErr != nil {
-			errMsg = fmt.Sprintf("fetch usd rates: %v", fetchErr)
-		}
-		if len(missing) > 0 {
-			if errMsg != "" {
-				errMsg += "; "
-			}
-			errMsg += fmt.Sprintf("missing forex quotes for currencies %v", missing)
-		}
-		return rates, fmt.Errorf(errMsg)
+	quotes, failures := yc.queue.Fetch(ctx, symbols, yc.fetchQuote)
+	if len(failures) > 0 {
+		return quotes, &BatchError{Failures: failures}
 	}
-
-	return rates, nil
+	return quotes, nil
 }
 
-// fetchBatch fetches prices for multiple symbols using the v8/chart endpoint,
-// one request per symbol (the chart endpoint is per-symbol, not batch).
-// It retries once with a fresh session on 401/403.
-func (yc *YahooClient) fetchBatch(ctx context.Context, symbols []string) (map[string]Quote, error) {
-	sess, err := yc.getSession(ctx)
+// fetchQuote fetches a single quote, retrying once with a fresh session on 401/403.
+func (yc *YahooClient) fetchQuote(ctx context.Context, symbol string) (Quote, error) {
+	params := url.Values{}
+	params.Set("range", "1d")
+	params.Set("interval", "1d")
+
+	body, err := yc.fetchChartWithRetry(ctx, symbol, params)
 	if err != nil {
-		return nil, fmt.Errorf("get yahoo session: %w", err)
-	}
-
-	quotes := make(map[string]Quote, len(symbols))
-	for _, sym := range symbols {
-		q, err := yc.fetchOne(ctx, sym, sess)
-		if err != nil {
-			// On auth failure, refresh session and retry once.
-			if isAuthError(err) {
-				yc.invalidateSession()
-				sess, err = yc.getSession(ctx)
-				if err != nil {
-					return quotes, fmt.Errorf("refresh yahoo session: %w", err)
-				}
-				q, err = yc.fetchOne(ctx, sym, sess)
-			}
-			if err != nil {
-				return quotes, fmt.Errorf("fetch %s: %w", sym, err)
-			}
-		}
-		quotes[sym] = q
+		return Quote{}, err
 	}
-	return quotes, nil
+	return parseChartResponse(body, symbol)
 }
 
-func (yc *YahooClient) fetchOne(ctx context.Context, symbol string, sess *yahooSession) (Quote, error) {
-	u := fmt.Sprintf("%s/%s?range=1d&interval=1d&crumb=%s",
-		chartURL, url.PathEscape(symbol), url.QueryEscape(sess.crumb))
+// fetchAuthed performs an authenticated GET against a Yahoo endpoint and returns the
+// raw body. what names the request in errors, e.g. "chart AAPL".
+func (yc *YahooClient) fetchAuthed(ctx context.Context, what, endpoint string, params url.Values, sess *yahooSession) ([]byte, error) {
+	params.Set("crumb", sess.crumb)
+	u := endpoint + "?" + params.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
-		return Quote{}, fmt.Errorf("build chart request: %w", err)
+		return nil, fmt.Errorf("build %s request: %w", what, err)
 	}
 	req.Header.Set("Cookie", sess.cookie)
 	req.Header.Set("User-Agent", "Mozilla/5.0")
 
 	resp, err := yc.client.Do(req)
 	if err != nil {
-		return Quote{}, fmt.Errorf("chart request: %w", err)
+		return nil, fmt.Errorf("%s request: %w", what, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
-		return Quote{}, fmt.Errorf("auth error: HTTP %d", resp.StatusCode)
-	}
 	if resp.StatusCode != http.StatusOK {
-		return Quote{}, fmt.Errorf("HTTP %d for %s", resp.StatusCode, symbol)
+		return nil, fmt.Errorf("%s: %w", what, newHTTPError(resp))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return Quote{}, fmt.Errorf("read chart response: %w", err)
+		return nil, fmt.Errorf("read %s response: %w", what, err)
 	}
-
-	return parseChartResponse(body, symbol)
+	return body, nil
 }
 
 func parseChartResponse(body []byte, symbol string) (Quote, error) {
@@ -375,15 +434,25 @@ func parseChartResponse(body []byte, symbol string) (Quote, error) {
 		Chart struct {
 			Result []struct {
 				Meta struct {
-					Symbol             string  `json:"symbol"`
-					Currency           string  `json:"currency"`
-					RegularMarketPrice float64 `json:"regularMarketPrice"`
-					ChartPreviousClose float64 `json:"chartPreviousClose"`
+					Symbol               string  `json:"symbol"`
+					Currency             string  `json:"currency"`
+					InstrumentType       string  `json:"instrumentType"`
+					ExchangeTimezoneName string  `json:"exchangeTimezoneName"`
+					RegularMarketPrice   float64 `json:"regularMarketPrice"`
+					ChartPreviousClose   float64 `json:"chartPreviousClose"`
+					PreviousClose        float64 `json:"previousClose"`
+					RegularMarketDayHigh float64 `json:"regularMarketDayHigh"`
+					RegularMarketDayLow  float64 `json:"regularMarketDayLow"`
+					FiftyTwoWeekHigh     float64 `json:"fiftyTwoWeekHigh"`
+					FiftyTwoWeekLow      float64 `json:"fiftyTwoWeekLow"`
+					CurrentTradingPeriod struct {
+						Pre     tradingPeriod `json:"pre"`
+						Regular tradingPeriod `json:"regular"`
+						Post    tradingPeriod `json:"post"`
+					} `json:"currentTradingPeriod"`
 				} `json:"meta"`
 			} `json:"result"`
-			Error *struct {
-				Description string `json:"description"`
-			} `json:"error"`
+			Error *chartError `json:"error"`
 		} `json:"chart"`
 	}
 
@@ -392,32 +461,83 @@ func parseChartResponse(body []byte, symbol string) (Quote, error) {
 	}
 
 	if payload.Chart.Error != nil {
-		return Quote{}, fmt.Errorf("yahoo error: %s", payload.Chart.Error.Description)
+		return Quote{}, payload.Chart.Error.err()
 	}
 
 	if len(payload.Chart.Result) == 0 {
-		return Quote{}, fmt.Errorf("no chart result for %s", symbol)
+		return Quote{}, fmt.Errorf("no chart result for %s: %w", symbol, ErrSymbolNotFound)
 	}
 
 	meta := payload.Chart.Result[0].Meta
 	currency, subunitDivisor := normalizeYahooCurrency(meta.Currency)
+	if subunitDivisor <= 0 {
+		subunitDivisor = 1
+	}
 
 	price := meta.RegularMarketPrice
 	if price == 0 {
 		price = meta.ChartPreviousClose // fallback for closed markets
 	}
-	if subunitDivisor > 0 && subunitDivisor != 1 {
-		price = price / subunitDivisor
-	}
+	price = price / subunitDivisor
 	if price == 0 {
-		return Quote{}, fmt.Errorf("no price data for %s", symbol)
+		return Quote{}, fmt.Errorf("%s: %w", symbol, ErrNoPrice)
 	}
 
-	return Quote{
-		Symbol:   meta.Symbol,
-		Price:    price,
-		Currency: currency,
-	}, nil
+	q := Quote{
+		Symbol:           meta.Symbol,
+		Price:            price,
+		Currency:         currency,
+		Type:             meta.InstrumentType,
+		Source:           "yahoo",
+		DayHigh:          meta.RegularMarketDayHigh / subunitDivisor,
+		DayLow:           meta.RegularMarketDayLow / subunitDivisor,
+		FiftyTwoWeekHigh: meta.FiftyTwoWeekHigh / subunitDivisor,
+		FiftyTwoWeekLow:  meta.FiftyTwoWeekLow / subunitDivisor,
+		ExchangeTimezone: meta.ExchangeTimezoneName,
+		MarketState: marketState(time.Now(),
+			meta.CurrentTradingPeriod.Pre,
+			meta.CurrentTradingPeriod.Regular,
+			meta.CurrentTradingPeriod.Post),
+	}
+
+	if q.Type == TypeCrypto {
+		q.MarketState = MarketRegular // trades around the clock
+	}
+
+	// With range=1d, chartPreviousClose is the prior session's close.
+	prev := meta.PreviousClose
+	if prev == 0 {
+		prev = meta.ChartPreviousClose
+	}
+	q.setPreviousClose(prev / subunitDivisor)
+
+	return q, nil
+}
+
+// tradingPeriod is a session window in Unix seconds as reported by v8/chart.
+type tradingPeriod struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+func (tp tradingPeriod) contains(t time.Time) bool {
+	return tp.Start > 0 && t.Unix() >= tp.Start && t.Unix() < tp.End
+}
+
+// marketState classifies now against the pre, regular and post session windows.
+func marketState(now time.Time, pre, regular, post tradingPeriod) MarketState {
+	switch {
+	case regular.contains(now):
+		return MarketRegular
+	case pre.contains(now):
+		return MarketPre
+	case post.contains(now):
+		return MarketPost
+	case regular.Start == 0:
+		return "" // not reported
+	default:
+		return MarketClosed
+	}
 }
 
 // normalizeYahooCurrency maps Yahoo-specific currency codes to canonical ISO-like codes
@@ -437,6 +557,12 @@ func normalizeYahooCurrency(raw string) (string, float64) {
 	case "ILA":
 		// Legacy agorot-like Yahoo code: 100 agorot = 1 ILS.
 		return "ILS", 100
+	case "ZAC":
+		// South African cents, used for JSE listings: 100 cents = 1 ZAR.
+		return "ZAR", 100
+	case "USX":
+		// US cents, used for grain futures.
+		return "USD", 100
 	case "RUR":
 		// Legacy Russian ruble alias.
 		return "RUB", 1
@@ -445,12 +571,15 @@ func normalizeYahooCurrency(raw string) (string, float64) {
 	}
 }
 
-func isAuthError(err error) bool {
-	if err == nil {
-		return false
+// chartError is the error object embedded in v8/chart responses.
+type chartError struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+func (ce *chartError) err() error {
+	if ce.Code == "Not Found" {
+		return fmt.Errorf("yahoo error: %s: %w", ce.Description, ErrSymbolNotFound)
 	}
-	s := err.Error()
-	return strings.Contains(s, "auth error") ||
-		strings.Contains(s, "HTTP 401") ||
-		strings.Contains(s, "HTTP 403")
+	return fmt.Errorf("yahoo error: %s", ce.Description)
 }
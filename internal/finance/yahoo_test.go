@@ -0,0 +1,147 @@
+package finance_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"stock-portfolio-bot/internal/finance"
+	"stock-portfolio-bot/internal/finance/financetest"
+)
+
+// newYahoo returns a client pointed at a fresh fake Yahoo server.
+func newYahoo(t *testing.T) (*finance.YahooClient, *financetest.YahooServer) {
+	t.Helper()
+	ys := financetest.NewYahooServer()
+	t.Cleanup(ys.Close)
+	yc := finance.NewYahooClient(time.Minute, 0, finance.BreakerConfig{})
+	yc.SetEndpoints(ys.Endpoints())
+	return yc, ys
+}
+
+func TestYahooGetQuotesPartialBatch(t *testing.T) {
+	yc, ys := newYahoo(t)
+	ys.SetQuote("AAPL", financetest.Quote{Price: 190, PreviousClose: 200, Currency: "USD"})
+	ys.SetQuote("VOD.L", financetest.Quote{Price: 7250, PreviousClose: 7000, Currency: "GBp"})
+
+	quotes, err := yc.GetQuotes(context.Background(), []string{"AAPL", "VOD.L", "NOPE"})
+
+	var batch *finance.BatchError
+	if !errors.As(err, &batch) {
+		t.Fatalf("error = %v, want *BatchError", err)
+	}
+	if len(batch.Failures) != 1 || !errors.Is(batch.Failures["NOPE"], finance.ErrSymbolNotFound) {
+		t.Errorf("failures = %v, want only NOPE not found", batch.Failures)
+	}
+
+	tests := []struct {
+		symbol    string
+		price     float64
+		currency  string
+		changePct float64
+	}{
+		{"AAPL", 190, "USD", -5},
+		{"VOD.L", 72.5, "GBP", 3.5714}, // pence reported in pounds
+	}
+	for _, tt := range tests {
+		q, ok := quotes[tt.symbol]
+		if !ok {
+			t.Errorf("%s missing from %v", tt.symbol, quotes)
+			continue
+		}
+		if q.Price != tt.price || q.Currency != tt.currency || math.Abs(q.ChangePercent-tt.changePct) > 1e-3 {
+			t.Errorf("%s = %.2f %s (%.4f%%), want %.2f %s (%.4f%%)",
+				tt.symbol, q.Price, q.Currency, q.ChangePercent, tt.price, tt.currency, tt.changePct)
+		}
+	}
+	if _, ok := quotes["NOPE"]; ok {
+		t.Error("NOPE returned a quote")
+	}
+}
+
+func TestYahooRefreshesSessionOnUnauthorized(t *testing.T) {
+	yc, ys := newYahoo(t)
+	ys.SetQuote("AAPL", financetest.Quote{Price: 190, Currency: "USD"})
+	ys.SetQuote("MSFT", financetest.Quote{Price: 410, Currency: "USD"})
+
+	if _, err := yc.GetQuotes(context.Background(), []string{"AAPL"}); err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	ys.ExpireSessions()
+
+	quotes, err := yc.GetQuotes(context.Background(), []string{"MSFT"})
+	if err != nil {
+		t.Fatalf("fetch after expiry: %v", err)
+	}
+	if quotes["MSFT"].Price != 410 {
+		t.Errorf("MSFT = %+v, want 410", quotes["MSFT"])
+	}
+
+	stats := ys.Stats()
+	if stats.Unauthorized != 1 {
+		t.Errorf("unauthorized requests = %d, want 1", stats.Unauthorized)
+	}
+	if stats.Consent != 2 || stats.Crumb != 2 {
+		t.Errorf("sessions fetched: consent %d, crumb %d, want 2 each", stats.Consent, stats.Crumb)
+	}
+	if stats.Chart["MSFT"] != 2 {
+		t.Errorf("MSFT chart requests = %d, want 2 (rejected, then retried)", stats.Chart["MSFT"])
+	}
+}
+
+func TestYahooGetRates(t *testing.T) {
+	yc, ys := newYahoo(t)
+	ys.SetQuote("EURUSD=X", financetest.Quote{Price: 1.08, Currency: "USD"})
+	ys.SetQuote("GBPUSD=X", financetest.Quote{Price: 1.25, Currency: "USD"})
+	ys.SetQuote("USDJPY=X", financetest.Quote{Price: 150, Currency: "JPY"})
+
+	pairs := []finance.CurrencyPair{
+		finance.NewCurrencyPair("eur", "usd"), // direct
+		finance.NewCurrencyPair("USD", "JPY"), // direct
+		finance.NewCurrencyPair("EUR", "GBP"), // triangulated through USD
+		finance.NewCurrencyPair("CHF", "CHF"), // identity, never fetched
+	}
+	rates, err := yc.GetRates(context.Background(), pairs)
+	if err != nil {
+		t.Fatalf("GetRates: %v", err)
+	}
+
+	tests := []struct {
+		pair finance.CurrencyPair
+		want float64
+	}{
+		{finance.NewCurrencyPair("EUR", "USD"), 1.08},
+		{finance.NewCurrencyPair("USD", "JPY"), 150},
+		{finance.NewCurrencyPair("EUR", "GBP"), 1.08 / 1.25},
+		{finance.NewCurrencyPair("CHF", "CHF"), 1},
+	}
+	for _, tt := range tests {
+		if got, ok := rates[tt.pair]; !ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s = %v (present %v), want %v", tt.pair, got, ok, tt.want)
+		}
+	}
+
+	_, err = yc.GetRates(context.Background(), []finance.CurrencyPair{finance.NewCurrencyPair("XAU", "SEK")})
+	if err == nil {
+		t.Error("unquoted pair returned no error")
+	}
+}
+
+func TestYahooGetFundamentals(t *testing.T) {
+	yc, ys := newYahoo(t)
+	ys.SetQuote("VOD.L", financetest.Quote{Price: 7250, Currency: "GBp", InstrumentType: finance.TypeEquity})
+
+	f, err := yc.GetFundamentals(context.Background(), "VOD.L")
+	if err != nil {
+		t.Fatalf("GetFundamentals: %v", err)
+	}
+	if f.Symbol != "VOD.L" || f.Name != "VOD.L" || f.Currency != "GBP" || f.Type != finance.TypeEquity {
+		t.Errorf("fundamentals = %+v", f)
+	}
+
+	if _, err := yc.GetFundamentals(context.Background(), "NOPE"); !errors.Is(err, finance.ErrSymbolNotFound) {
+		t.Errorf("unknown symbol error = %v, want ErrSymbolNotFound", err)
+	}
+}
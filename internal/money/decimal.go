@@ -0,0 +1,407 @@
+// Package money provides a fixed-point decimal for share quantities and monetary
+// amounts, so sums of many fractional lots and converted values stay exact.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Places is the number of fractional digits a Decimal holds. Eight covers crypto
+// quantities (satoshis) and sub-cent prices.
+const Places = 8
+
+const unit = 100_000_000 // 10^Places
+
+var (
+	// ErrSyntax is returned by Parse for text that is not a decimal number.
+	ErrSyntax = errors.New("invalid decimal")
+
+	// ErrOverflow is returned by Parse, Div and Value when the value does not fit in
+	// a Decimal.
+	ErrOverflow = errors.New("decimal out of range")
+
+	// ErrDivisionByZero is returned by Div when the divisor is zero.
+	ErrDivisionByZero = errors.New("decimal division by zero")
+)
+
+// Decimal is a signed fixed-point number with Places fractional digits, stored as an
+// int64 count of 10^-8 units; the largest magnitude is about 92 billion. The zero
+// value is 0. Arithmetic that would round rounds half away from zero.
+//
+// Results out of range saturate instead of wrapping: they become an overflowed
+// Decimal, reported by Overflowed, which stays overflowed through further arithmetic
+// and cannot be stored (Value fails). Large trades, or portfolios valued in a currency
+// such as IDR or VND, can get there, so callers that sum amounts check the result.
+type Decimal struct {
+	units int64
+}
+
+// Zero is the zero Decimal.
+var Zero = Decimal{}
+
+// maxUnits marks an overflowed Decimal, ±maxUnits; Parse never returns it.
+const maxUnits = math.MaxInt64
+
+// saturated returns the overflowed Decimal with the given sign.
+func saturated(sign int) Decimal {
+	if sign < 0 {
+		return Decimal{units: -maxUnits}
+	}
+	return Decimal{units: maxUnits}
+}
+
+// fromBig returns n units as a Decimal, saturating if it is out of range.
+func fromBig(n *big.Int) Decimal {
+	if n.CmpAbs(big.NewInt(maxUnits)) >= 0 {
+		return saturated(n.Sign())
+	}
+	return Decimal{units: n.Int64()}
+}
+
+// FromInt returns n as a Decimal.
+func FromInt(n int64) Decimal {
+	return fromBig(new(big.Int).Mul(big.NewInt(n), big.NewInt(unit)))
+}
+
+// FromFloat converts f via its shortest decimal representation, rounded to Places,
+// so 0.1 becomes exactly 0.1. NaN and infinities become Zero; values out of range
+// saturate.
+func FromFloat(f float64) Decimal {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Zero
+	}
+	d, err := Parse(strconv.FormatFloat(f, 'e', -1, 64))
+	if err != nil {
+		return saturated(int(math.Copysign(1, f)))
+	}
+	return d
+}
+
+// Parse reads a decimal such as "12", "-0.5", "2.50" or "1e-5". Digits beyond
+// Places are rounded half away from zero.
+func Parse(s string) (Decimal, error) {
+	text := strings.TrimSpace(s)
+	neg := false
+	switch {
+	case strings.HasPrefix(text, "-"):
+		neg, text = true, text[1:]
+	case strings.HasPrefix(text, "+"):
+		text = text[1:]
+	}
+
+	exp := 0
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		e, err := strconv.Atoi(text[i+1:])
+		if err != nil {
+			return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+		exp, text = e, text[:i]
+	}
+
+	intPart, fracPart, _ := strings.Cut(text, ".")
+	digits := intPart + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Zero, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+	if exp > 100 || exp < -100 {
+		return Zero, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+
+	n, _ := new(big.Int).SetString(digits, 10)
+	if neg {
+		n.Neg(n)
+	}
+	// n × 10^(exp - len(fracPart)) in units of 10^-Places.
+	shift := exp - len(fracPart) + Places
+	if shift >= 0 {
+		n.Mul(n, pow10(shift))
+	} else {
+		n = divRound(n, pow10(-shift))
+	}
+	d := fromBig(n)
+	if d.Overflowed() {
+		return Zero, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+	return d, nil
+}
+
+// MustParse is Parse for constants; it panics on invalid input.
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Overflowed reports whether d is the result of arithmetic out of range.
+func (d Decimal) Overflowed() bool {
+	return abs64(d.units) == maxUnits
+}
+
+// Add returns d + e, saturating on overflow.
+func (d Decimal) Add(e Decimal) Decimal {
+	switch {
+	case d.Overflowed():
+		return d
+	case e.Overflowed():
+		return e
+	}
+	sum := d.units + e.units
+	if (d.units > 0 && e.units > 0 && sum < 0) || (d.units < 0 && e.units < 0 && sum >= 0) ||
+		sum == math.MinInt64 || abs64(sum) == maxUnits {
+		return saturated(d.Sign() + e.Sign())
+	}
+	return Decimal{units: sum}
+}
+
+// Sub returns d - e.
+func (d Decimal) Sub(e Decimal) Decimal {
+	return d.Add(e.Neg())
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: -d.units}
+}
+
+// Abs returns |d|.
+func (d Decimal) Abs() Decimal {
+	if d.units < 0 {
+		return d.Neg()
+	}
+	return d
+}
+
+// Mul returns d × e rounded to Places, saturating on overflow.
+func (d Decimal) Mul(e Decimal) Decimal {
+	if d.Overflowed() || e.Overflowed() {
+		if d.IsZero() || e.IsZero() {
+			return Zero
+		}
+		return saturated(d.Sign() * e.Sign())
+	}
+	n := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(e.units))
+	return fromBig(divRound(n, big.NewInt(unit)))
+}
+
+// MulFloat returns d × f, taking f at its shortest decimal representation.
+// It is how exact quantities meet provider prices and rates, which are float64.
+func (d Decimal) MulFloat(f float64) Decimal {
+	return d.Mul(FromFloat(f))
+}
+
+// Div returns d ÷ e rounded to Places. It fails with ErrOverflow if either operand
+// has overflowed or the quotient is out of range.
+func (d Decimal) Div(e Decimal) (Decimal, error) {
+	if e.units == 0 {
+		return Zero, ErrDivisionByZero
+	}
+	if d.Overflowed() || e.Overflowed() {
+		return Zero, ErrOverflow
+	}
+	n := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(unit))
+	q := fromBig(divRound(n, big.NewInt(e.units)))
+	if q.Overflowed() {
+		return Zero, ErrOverflow
+	}
+	return q, nil
+}
+
+// MulDiv returns d × num ÷ den rounded once to Places. The product is kept exact, so
+// it only fails if the result is out of range (ErrOverflow) or den is zero; use it to
+// scale by a ratio, where Mul then Div would overflow on the intermediate product.
+func (d Decimal) MulDiv(num, den Decimal) (Decimal, error) {
+	if den.units == 0 {
+		return Zero, ErrDivisionByZero
+	}
+	if d.Overflowed() || num.Overflowed() || den.Overflowed() {
+		return Zero, ErrOverflow
+	}
+	n := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(num.units))
+	q := fromBig(divRound(n, big.NewInt(den.units)))
+	if q.Overflowed() {
+		return Zero, ErrOverflow
+	}
+	return q, nil
+}
+
+// Round returns d rounded half away from zero to places fractional digits.
+func (d Decimal) Round(places int) Decimal {
+	if places >= Places || d.Overflowed() {
+		return d
+	}
+	if places < 0 {
+		places = 0
+	}
+	f := int64(math.Pow10(Places - places))
+	q, r := d.units/f, d.units%f
+	if 2*abs64(r) >= f {
+		if d.units < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return fromBig(new(big.Int).Mul(big.NewInt(q), big.NewInt(f)))
+}
+
+// RoundCurrency rounds d to the minor unit of currency, e.g. cents for USD and
+// whole yen for JPY.
+func (d Decimal) RoundCurrency(currency string) Decimal {
+	return d.Round(CurrencyPlaces(currency))
+}
+
+// Sign returns -1, 0 or +1.
+func (d Decimal) Sign() int {
+	switch {
+	case d.units < 0:
+		return -1
+	case d.units > 0:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether d is 0.
+func (d Decimal) IsZero() bool { return d.units == 0 }
+
+// Cmp returns -1, 0 or +1 as d is less than, equal to or greater than e.
+func (d Decimal) Cmp(e Decimal) int {
+	switch {
+	case d.units < e.units:
+		return -1
+	case d.units > e.units:
+		return 1
+	}
+	return 0
+}
+
+// Float64 returns the nearest float64, for ratios and percentages.
+func (d Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac64(d.units, unit).Float64()
+	return f
+}
+
+// String formats d with no trailing fractional zeros, e.g. "2.5" or "-10".
+func (d Decimal) String() string {
+	s := d.StringFixed(Places)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// StringFixed formats d rounded to exactly places fractional digits, e.g. "2.50".
+func (d Decimal) StringFixed(places int) string {
+	if places > Places {
+		places = Places
+	}
+	if places < 0 {
+		places = 0
+	}
+	r := d.Round(places)
+	sign := ""
+	u := r.units
+	if u < 0 {
+		sign = "-"
+	}
+	whole := abs64(u / unit)
+	frac := abs64(u % unit)
+	if places == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	fracDigits := fmt.Sprintf("%0*d", Places, frac)[:places]
+	return fmt.Sprintf("%s%d.%s", sign, whole, fracDigits)
+}
+
+// Value implements driver.Valuer; decimals are stored as TEXT so they round-trip
+// exactly. An overflowed Decimal is refused with ErrOverflow.
+func (d Decimal) Value() (driver.Value, error) {
+	if d.Overflowed() {
+		return nil, ErrOverflow
+	}
+	return d.String(), nil
+}
+
+// Scan implements sql.Scanner. It accepts TEXT written by Value as well as INTEGER
+// and REAL values from rows written before decimals were introduced.
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Zero
+	case string:
+		return d.scanText(v)
+	case []byte:
+		return d.scanText(string(v))
+	case int64:
+		*d = FromInt(v)
+	case float64:
+		*d = FromFloat(v)
+	default:
+		return fmt.Errorf("scan decimal: unsupported type %T", src)
+	}
+	return nil
+}
+
+func (d *Decimal) scanText(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return fmt.Errorf("scan decimal: %w", err)
+	}
+	*d = parsed
+	return nil
+}
+
+// CurrencyPlaces returns the number of minor-unit digits of an ISO 4217 currency:
+// 0 for JPY and KRW, 3 for the dinars, 2 for everything else.
+func CurrencyPlaces(currency string) int {
+	code := strings.ToUpper(strings.TrimSpace(currency))
+	if _, ok := zeroDecimalCurrencies[code]; ok {
+		return 0
+	}
+	if _, ok := threeDecimalCurrencies[code]; ok {
+		return 3
+	}
+	return 2
+}
+
+var zeroDecimalCurrencies = map[string]struct{}{
+	"BIF": {}, "CLP": {}, "DJF": {}, "GNF": {}, "ISK": {}, "JPY": {}, "KMF": {}, "KRW": {},
+	"PYG": {}, "RWF": {}, "UGX": {}, "VND": {}, "VUV": {}, "XAF": {}, "XOF": {}, "XPF": {},
+}
+
+var threeDecimalCurrencies = map[string]struct{}{
+	"BHD": {}, "IQD": {}, "JOD": {}, "KWD": {}, "LYD": {}, "OMR": {}, "TND": {},
+}
+
+// divRound returns n ÷ d rounded half away from zero.
+func divRound(n, d *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	r.Abs(r).Lsh(r, 1)
+	if r.Cmp(new(big.Int).Abs(d)) >= 0 {
+		if n.Sign()*d.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
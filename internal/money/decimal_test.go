@@ -0,0 +1,234 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0", "0"},
+		{"12", "12"},
+		{"-0.5", "-0.5"},
+		{"+2.50", "2.5"},
+		{"0.00000001", "0.00000001"},
+		{"1e-5", "0.00001"},
+		{"1.5E3", "1500"},
+		{"0.000000005", "0.00000001"}, // half away from zero
+		{"-0.000000005", "-0.00000001"},
+		{"0.000000004", "0"},
+		{"92233720368.54775806", "92233720368.54775806"}, // largest magnitude
+		{"-92233720368.54775806", "-92233720368.54775806"},
+		{" 7 ", "7"},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got := d.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		back, err := Parse(d.String())
+		if err != nil || back != d {
+			t.Errorf("Parse(%q) does not round-trip: %v, %v", d.String(), back, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+	}{
+		{"", ErrSyntax},
+		{"abc", ErrSyntax},
+		{"1.2.3", ErrSyntax},
+		{"1e", ErrSyntax},
+		{"--1", ErrSyntax},
+		{"92233720368.54775807", ErrOverflow}, // reserved for overflowed values
+		{"100000000000", ErrOverflow},
+		{"1e200", ErrOverflow},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.in); !errors.Is(err, tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestStringFixed(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int
+		want   string
+	}{
+		{"2.5", 2, "2.50"},
+		{"-2.005", 2, "-2.01"},
+		{"1234.5", 0, "1235"},
+		{"0.1", 12, "0.10000000"},
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.in).StringFixed(tt.places); got != tt.want {
+			t.Errorf("StringFixed(%s, %d) = %q, want %q", tt.in, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestRoundCurrency(t *testing.T) {
+	tests := []struct {
+		in       string
+		currency string
+		want     string
+	}{
+		{"10.005", "USD", "10.01"},
+		{"10.004", "usd", "10"},
+		{"-10.005", "EUR", "-10.01"},
+		{"1234.5", "JPY", "1235"},
+		{"1234.49", "KRW", "1234"},
+		{"1.2345", "KWD", "1.235"},
+		{"1.2344", "BHD", "1.234"},
+		{"0.125", "", "0.13"},
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.in).RoundCurrency(tt.currency).String(); got != tt.want {
+			t.Errorf("RoundCurrency(%s, %q) = %s, want %s", tt.in, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := MustParse("0.1"), MustParse("0.2")
+	if got := a.Add(b); got != MustParse("0.3") {
+		t.Errorf("0.1 + 0.2 = %s", got)
+	}
+	if got := a.Sub(b); got != MustParse("-0.1") {
+		t.Errorf("0.1 - 0.2 = %s", got)
+	}
+	if got := MustParse("1.5").Mul(MustParse("-2.25")); got != MustParse("-3.375") {
+		t.Errorf("1.5 × -2.25 = %s", got)
+	}
+	if got := MustParse("10").MulFloat(0.1); got != MustParse("1") {
+		t.Errorf("10 × 0.1 = %s", got)
+	}
+	if got := FromFloat(math.NaN()); got != Zero {
+		t.Errorf("FromFloat(NaN) = %s", got)
+	}
+}
+
+func TestDiv(t *testing.T) {
+	got, err := MustParse("1").Div(MustParse("3"))
+	if err != nil || got != MustParse("0.33333333") {
+		t.Errorf("1 ÷ 3 = %s, %v", got, err)
+	}
+	got, err = MustParse("2").Div(MustParse("-3"))
+	if err != nil || got != MustParse("-0.66666667") {
+		t.Errorf("2 ÷ -3 = %s, %v", got, err)
+	}
+	if _, err := MustParse("1").Div(Zero); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("1 ÷ 0 error = %v, want ErrDivisionByZero", err)
+	}
+	if _, err := MustParse("90000000000").Div(MustParse("0.5")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("9e10 ÷ 0.5 error = %v, want ErrOverflow", err)
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	tests := []struct {
+		d, num, den string
+		want        string
+	}{
+		{"3", "2", "3", "2"},
+		{"10", "1", "3", "3.33333333"},
+		{"1000000", "2000000", "1000000", "2000000"}, // 2:1 split of a large holding
+		{"50000000", "750000", "1000000", "37500000"},
+		{"-9", "1", "2", "-4.5"},
+	}
+	for _, tt := range tests {
+		got, err := MustParse(tt.d).MulDiv(MustParse(tt.num), MustParse(tt.den))
+		if err != nil || got != MustParse(tt.want) {
+			t.Errorf("%s × %s ÷ %s = %s, %v, want %s", tt.d, tt.num, tt.den, got, err, tt.want)
+		}
+	}
+	if _, err := MustParse("1").MulDiv(MustParse("1"), Zero); !errors.Is(err, ErrDivisionByZero) {
+		t.Errorf("÷ 0 error = %v, want ErrDivisionByZero", err)
+	}
+	if _, err := MustParse("90000000000").MulDiv(MustParse("2"), MustParse("1")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("out of range error = %v, want ErrOverflow", err)
+	}
+}
+
+func TestOverflowSaturates(t *testing.T) {
+	largest := MustParse("92233720368.54775806")
+	tests := []struct {
+		name string
+		got  Decimal
+		sign int
+	}{
+		{"largest + smallest unit", largest.Add(MustParse("0.00000001")), 1},
+		{"-largest - smallest unit", largest.Neg().Sub(MustParse("0.00000001")), -1},
+		{"largest + largest", largest.Add(largest), 1},
+		{"mul", MustParse("1000000000").Mul(MustParse("200")), 1},
+		{"mul negative", MustParse("-1000000000").Mul(MustParse("200")), -1},
+		{"mul float", MustParse("1000000").MulFloat(1e6), 1},
+		{"from float", FromFloat(-1e20), -1},
+		{"from int", FromInt(math.MaxInt64), 1},
+		{"sticky add", largest.Add(largest).Sub(largest), 1},
+		{"sticky mul", largest.Add(largest).Mul(MustParse("-0.5")), -1},
+		{"round", largest.Add(largest).Round(2), 1},
+	}
+	for _, tt := range tests {
+		if !tt.got.Overflowed() || tt.got.Sign() != tt.sign {
+			t.Errorf("%s = %s, want overflowed with sign %d", tt.name, tt.got, tt.sign)
+		}
+	}
+
+	if largest.Overflowed() || largest.Neg().Overflowed() {
+		t.Error("largest representable value reported as overflowed")
+	}
+	if got := largest.Sub(MustParse("0.00000001")).Add(MustParse("0.00000001")); got != largest {
+		t.Errorf("largest - unit + unit = %s, want %s", got, largest)
+	}
+	if got := largest.Add(largest).Mul(Zero); got != Zero {
+		t.Errorf("overflowed × 0 = %s, want 0", got)
+	}
+	if _, err := largest.Add(largest).Div(MustParse("2")); !errors.Is(err, ErrOverflow) {
+		t.Errorf("overflowed ÷ 2 error = %v, want ErrOverflow", err)
+	}
+	if _, err := largest.Add(largest).Value(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Value of overflowed error = %v, want ErrOverflow", err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  any
+		want string
+	}{
+		{nil, "0"},
+		{"2.5", "2.5"},
+		{[]byte("-1.25"), "-1.25"},
+		{int64(3), "3"},
+		{0.1, "0.1"},
+	}
+	for _, tt := range tests {
+		var d Decimal
+		if err := d.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v): %v", tt.src, err)
+			continue
+		}
+		if got := d.String(); got != tt.want {
+			t.Errorf("Scan(%v) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+
+	var d Decimal
+	if err := d.Scan("1e20"); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Scan(1e20) error = %v, want ErrOverflow", err)
+	}
+}
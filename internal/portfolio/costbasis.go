@@ -0,0 +1,293 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/finance"
+	"stock-portfolio-bot/internal/money"
+)
+
+// Cost basis methods, chosen per user.
+const (
+	CostFIFO    = "fifo"    // sales use the oldest lots first
+	CostLIFO    = "lifo"    // sales use the newest lots first
+	CostAverage = "average" // one pooled lot at the average cost
+
+	// settingCostMethod is the user_settings key for the cost basis method.
+	settingCostMethod = "cost_method"
+
+	// DefaultCostMethod is used until a user picks another.
+	DefaultCostMethod = CostFIFO
+)
+
+// ErrUnknownCostMethod is returned by SetCostMethod for a method it does not know.
+var ErrUnknownCostMethod = errors.New("unknown cost basis method")
+
+// CostMethod returns the user's cost basis method.
+func (s *Service) CostMethod(chatID int64) (string, error) {
+	value, err := s.repo.GetUserSetting(chatID, settingCostMethod)
+	if err != nil {
+		return DefaultCostMethod, err
+	}
+	if value == "" {
+		return DefaultCostMethod, nil
+	}
+	return value, nil
+}
+
+// SetCostMethod changes the user's cost basis method. It accepts "fifo", "lifo" and
+// "average" (or "avg").
+func (s *Service) SetCostMethod(chatID int64, method string) (string, error) {
+	m := strings.ToLower(strings.TrimSpace(method))
+	switch m {
+	case CostFIFO, CostLIFO, CostAverage:
+	case "avg":
+		m = CostAverage
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownCostMethod, method)
+	}
+
+	value := m
+	if m == DefaultCostMethod {
+		value = "" // back to the default
+	}
+	if err := s.repo.SetUserSetting(chatID, settingCostMethod, value); err != nil {
+		return "", err
+	}
+	return m, nil
+}
+
+// costMethod returns the user's cost basis method, falling back to the default if the
+// setting cannot be read.
+func (s *Service) costMethod(chatID int64, caller string) string {
+	method, err := s.CostMethod(chatID)
+	if err != nil {
+		log.Printf("%s: %v", caller, err)
+	}
+	return method
+}
+
+// FormatCostMethod describes the cost basis method for the /cost command.
+func FormatCostMethod(method string) string {
+	switch method {
+	case CostLIFO:
+		return "Cost basis uses LIFO: sales use up your newest purchases first."
+	case CostAverage:
+		return "Cost basis uses average cost: every share costs the average price paid."
+	}
+	return "Cost basis uses FIFO: sales use up your oldest purchases first."
+}
+
+// positionCosts replays every ledger in the report's portfolio, open and closed
+// positions alike, and adds up realized gains in report. It returns the cost of each
+// position by symbol; a failure to load the ledgers is logged and leaves costs out of
+// the report.
+func (s *Service) positionCosts(ctx context.Context, report *BalanceReport, quotes map[string]finance.Quote) map[string]PositionCost {
+	ledgers, err := s.repo.GetPortfolioTransactions(report.Portfolio.ID)
+	if err != nil {
+		log.Printf("ComputeBalance: get transactions (portfolio %d): %v", report.Portfolio.ID, err)
+		return nil
+	}
+
+	rateOn := s.dayRates(ctx, report.Currency)
+	costs := make(map[string]PositionCost, len(ledgers))
+	for symbol, txs := range ledgers {
+		multiplier, _ := finance.ContractMultiplier(symbol)
+		pc := positionCost(txs, report.CostMethod, report.Currency, quotes[symbol].Currency, multiplier, rateOn)
+		costs[symbol] = pc
+		if len(pc.Sales) > 0 || !pc.RealizedKnown {
+			report.HasRealized = true
+			report.Realized = report.Realized.Add(pc.Realized)
+			report.RealizedPartial = report.RealizedPartial || !pc.RealizedKnown
+		}
+	}
+	return costs
+}
+
+// PositionCost is a position's cost basis and gains, in the base currency. Buy fees
+// are part of the cost; sell fees reduce the proceeds.
+type PositionCost struct {
+	Shares    money.Decimal // held after replaying the ledger
+	Cost      money.Decimal // cost basis of the shares held
+	CostKnown bool          // false if a held lot has no price or its rate is unavailable
+
+	Realized      money.Decimal // gains on shares sold
+	RealizedKnown bool          // false if a sale could not be valued; Realized covers the rest
+	Sales         map[int64]money.Decimal
+}
+
+// lot is a block of shares bought together (or, for average cost, all shares held).
+type lot struct {
+	shares money.Decimal
+	cost   money.Decimal // in the base currency
+	known  bool
+}
+
+// positionCost replays a position's ledger (oldest first) with method, valuing each
+// trade in base at the exchange rate of its day from rateOn. Trades with no recorded currency are
+// taken to be in quoteCurrency. Sales maps each sell transaction's ID to its gain.
+func positionCost(txs []db.Transaction, method, base, quoteCurrency string, multiplier float64, rateOn rateLookup) PositionCost {
+	pc := PositionCost{CostKnown: true, RealizedKnown: true, Sales: make(map[int64]money.Decimal)}
+	var lots []lot
+
+	for _, t := range txs {
+		switch {
+		case t.Kind == db.TxSplit:
+			// Scale every lot's shares by the split ratio; their cost is unchanged. A lot
+			// that cannot be scaled no longer says what its shares cost. What rounding
+			// leaves over goes to the last lot, so the lots still add up to the position.
+			after := pc.Shares.Add(t.Quantity)
+			scaledSum, allScaled := money.Zero, true
+			for i := range lots {
+				scaled, err := lots[i].shares.MulDiv(after, pc.Shares)
+				if err != nil {
+					lots[i].known, allScaled = false, false
+					continue
+				}
+				lots[i].shares = scaled
+				scaledSum = scaledSum.Add(scaled)
+			}
+			if n := len(lots); n > 0 && allScaled {
+				lots[n-1].shares = lots[n-1].shares.Add(after.Sub(scaledSum))
+			}
+			pc.Shares = after
+
+		case t.Quantity.Sign() > 0:
+			gross, fees, ok := tradeAmounts(t, quoteCurrency, multiplier, rateOn)
+			bought := lot{shares: t.Quantity, cost: gross.Add(fees), known: ok}
+			if method == CostAverage && len(lots) > 0 {
+				lots[0] = lot{
+					shares: lots[0].shares.Add(bought.shares),
+					cost:   lots[0].cost.Add(bought.cost),
+					known:  lots[0].known && bought.known,
+				}
+			} else {
+				lots = append(lots, bought)
+			}
+			pc.Shares = pc.Shares.Add(t.Quantity)
+
+		case t.Quantity.Sign() < 0:
+			sold := t.Quantity.Neg()
+			var cost money.Decimal
+			var known bool
+			lots, cost, known = consumeLots(lots, sold, method)
+			gross, fees, ok := tradeAmounts(t, quoteCurrency, multiplier, rateOn)
+			if ok && known && !gross.Sub(fees).Sub(cost).Overflowed() {
+				gain := gross.Sub(fees).Sub(cost).RoundCurrency(base)
+				pc.Sales[t.ID] = gain
+				pc.Realized = pc.Realized.Add(gain)
+			} else {
+				pc.RealizedKnown = false
+			}
+			pc.Shares = pc.Shares.Sub(sold)
+		}
+	}
+
+	for _, l := range lots {
+		pc.Cost = pc.Cost.Add(l.cost)
+		pc.CostKnown = pc.CostKnown && l.known
+	}
+	pc.Cost = pc.Cost.RoundCurrency(base)
+	return pc
+}
+
+// consumeLots removes shares from lots, oldest first for FIFO and average cost (which
+// has a single lot) and newest first for LIFO. It returns the remaining lots and the
+// cost of the shares removed; known is false if any of them had an unknown cost, or
+// if the lots run out first.
+func consumeLots(lots []lot, shares money.Decimal, method string) ([]lot, money.Decimal, bool) {
+	cost, known := money.Zero, true
+	for shares.Sign() > 0 && len(lots) > 0 {
+		i := 0
+		if method == CostLIFO {
+			i = len(lots) - 1
+		}
+		l := &lots[i]
+		known = known && l.known
+
+		if shares.Cmp(l.shares) < 0 {
+			// Part of the lot: its cost goes in proportion to the shares taken. If that
+			// cannot be worked out, neither the part taken nor the rest has a known cost.
+			part, err := l.cost.MulDiv(shares, l.shares)
+			if err != nil {
+				known, l.known = false, false
+			}
+			cost = cost.Add(part)
+			l.cost = l.cost.Sub(part)
+			l.shares = l.shares.Sub(shares)
+			shares = money.Zero
+			break
+		}
+		cost = cost.Add(l.cost)
+		shares = shares.Sub(l.shares)
+		if method == CostLIFO {
+			lots = lots[:i]
+		} else {
+			lots = lots[1:]
+		}
+	}
+	// Shares left over were never bought, as far as the lots know, so the cost of the
+	// sale is not all there.
+	if shares.Sign() > 0 {
+		known = false
+	}
+	return lots, cost, known
+}
+
+// tradeAmounts returns a trade's gross value (shares × price × multiplier) and fees,
+// converted with rateOn. ok is false if the price, the currency or the rate is unknown.
+func tradeAmounts(t db.Transaction, quoteCurrency string, multiplier float64, rateOn rateLookup) (gross, fees money.Decimal, ok bool) {
+	if t.Price == nil {
+		return money.Zero, money.Zero, false
+	}
+	currency := finance.NormalizeCurrency(t.Currency)
+	if currency == "" {
+		currency = finance.NormalizeCurrency(quoteCurrency)
+	}
+	if currency == "" {
+		return money.Zero, money.Zero, false
+	}
+	rate, ok := rateOn(currency, t.Date)
+	if !ok {
+		return money.Zero, money.Zero, false
+	}
+	gross = t.Quantity.Abs().Mul(*t.Price).MulFloat(multiplier).MulFloat(rate)
+	fees = t.Fees.MulFloat(rate)
+	return gross, fees, true
+}
+
+// rateLookup returns how many units of the base currency one unit of currency was
+// worth on date; ok is false if the rate is unavailable.
+type rateLookup func(currency string, date time.Time) (rate float64, ok bool)
+
+// dayRates returns a rateLookup into base, memoised for the duration of one report.
+// Failures are logged once per currency and day.
+func (s *Service) dayRates(ctx context.Context, base string) rateLookup {
+	type key struct {
+		currency string
+		day      time.Time
+	}
+	memo := make(map[key]float64)
+	return func(currency string, date time.Time) (float64, bool) {
+		if currency == base {
+			return 1, true
+		}
+		k := key{currency, finance.CalendarDay(date)}
+		if rate, ok := memo[k]; ok {
+			return rate, rate > 0
+		}
+		rate, _, err := s.RateOn(ctx, currency, base, date)
+		if err != nil {
+			log.Printf("cost basis: %s/%s rate on %s: %v", currency, base, k.day.Format("2006-01-02"), err)
+			rate = 0
+		}
+		memo[k] = rate
+		return rate, rate > 0
+	}
+}
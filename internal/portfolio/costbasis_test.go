@@ -0,0 +1,231 @@
+package portfolio
+
+import (
+	"testing"
+	"time"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/money"
+)
+
+// tx returns a ledger entry in USD; price "" leaves it unpriced, as opening balances
+// and splits are.
+func tx(id int64, kind, date, quantity, price string) db.Transaction {
+	t := db.Transaction{ID: id, Kind: kind, Date: day(date), Quantity: money.MustParse(quantity), Currency: "USD"}
+	if price != "" {
+		p := money.MustParse(price)
+		t.Price = &p
+	}
+	return t
+}
+
+// testRates values EUR at 1.1 USD and knows no other currency but USD.
+func testRates(currency string, _ time.Time) (float64, bool) {
+	switch currency {
+	case "USD":
+		return 1, true
+	case "EUR":
+		return 1.1, true
+	}
+	return 0, false
+}
+
+func TestPositionCost(t *testing.T) {
+	withFees := func(t db.Transaction, fees string) db.Transaction {
+		t.Fees = money.MustParse(fees)
+		return t
+	}
+	inCurrency := func(t db.Transaction, currency string) db.Transaction {
+		t.Currency = currency
+		return t
+	}
+	twoLots := []db.Transaction{
+		tx(1, db.TxBuy, "2024-01-02", "10", "100"),
+		tx(2, db.TxBuy, "2024-02-01", "10", "120"),
+		tx(3, db.TxSell, "2024-03-01", "-15", "150"),
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		multiplier    float64
+		txs           []db.Transaction
+		shares        string
+		cost          string
+		costKnown     bool
+		realized      string
+		realizedKnown bool
+	}{
+		{"fifo", CostFIFO, 1, twoLots, "5", "600", true, "650", true},
+		{"lifo", CostLIFO, 1, twoLots, "5", "500", true, "550", true},
+		{"average", CostAverage, 1, twoLots, "5", "550", true, "600", true},
+		{
+			name: "partial lot", method: CostFIFO, multiplier: 1,
+			txs: []db.Transaction{
+				tx(1, db.TxBuy, "2024-01-02", "3", "10"),
+				tx(2, db.TxSell, "2024-01-03", "-1", "13"),
+			},
+			shares: "2", cost: "20", costKnown: true, realized: "3", realizedKnown: true,
+		},
+		{
+			name: "fees", method: CostFIFO, multiplier: 1,
+			txs: []db.Transaction{
+				withFees(tx(1, db.TxBuy, "2024-01-02", "10", "100"), "5"),
+				withFees(tx(2, db.TxSell, "2024-01-03", "-10", "110"), "5"),
+			},
+			shares: "0", cost: "0", costKnown: true, realized: "90", realizedKnown: true,
+		},
+		{
+			name: "split scales lots, not cost", method: CostFIFO, multiplier: 1,
+			txs: []db.Transaction{
+				tx(1, db.TxBuy, "2024-01-02", "10", "100"),
+				tx(2, db.TxSplit, "2024-02-01", "10", ""),
+				tx(3, db.TxSell, "2024-03-01", "-5", "60"),
+			},
+			shares: "15", cost: "750", costKnown: true, realized: "50", realizedKnown: true,
+		},
+		{
+			name: "reverse split", method: CostLIFO, multiplier: 1,
+			txs: []db.Transaction{
+				tx(1, db.TxBuy, "2024-01-02", "30", "10"),
+				tx(2, db.TxSplit, "2024-02-01", "-20", ""),
+				tx(3, db.TxSell, "2024-03-01", "-5", "40"),
+			},
+			shares: "5", cost: "150", costKnown: true, realized: "50", realizedKnown: true,
+		},
+		{
+			name: "split of a large holding", method: CostFIFO, multiplier: 1,
+			txs: []db.Transaction{
+				tx(1, db.TxBuy, "2024-01-02", "1000000", "1"),
+				tx(2, db.TxSplit, "2024-02-01", "1000000", ""),
+				tx(3, db.TxSell, "2024-03-01", "-1000000", "1"),
+			},
+			shares: "1000000", cost: "500000", costKnown: true, realized: "500000", realizedKnown: true,
+		},
+		{
+			name: "odd split ratio, then sold out", method: CostFIFO, multiplier: 1,
+			txs: []db.Transaction{
+				tx(1, db.TxBuy, "2024-01-02", "1", "90"),
+				tx(2, db.TxSplit, "2024-02-01", "0.5", ""),
+				tx(3, db.TxSell, "2024-03-01", "-1.5", "100"),
+			},
+			shares: "0", cost: "0", costKnown: true, realized: "60", realizedKnown: true,
+		},
+		{
+			name: "split rounding goes to the last lot", method: CostFIFO, multiplier: 1,
+			txs: []db.Transaction{
+				tx(1, db.TxBuy, "2024-01-02", "1", "10"),
+				tx(2, db.TxBuy, "2024-01-03", "1", "10"),
+				tx(3, db.TxBuy, "2024-01-04", "1", "10"),
+				tx(4, db.TxSplit, "2024-02-01", "-2.57142857", ""), // 1:7 reverse split
+				tx(5, db.TxSell, "2024-03-01", "-0.42857143", "70"),
+			},
+			shares: "0", cost: "0", costKnown: true, realized: "0", realizedKnown: true,
+		},
+		{
+			name: "foreign currency and contract multiplier", method: CostFIFO, multiplier: 50,
+			txs: []db.Transaction{
+				inCurrency(tx(1, db.TxBuy, "2024-01-02", "2", "100"), "EUR"),
+			},
+			shares: "2", cost: "11000", costKnown: true, realized: "0", realizedKnown: true,
+		},
+		{
+			name: "opening balance has no cost", method: CostFIFO, multiplier: 1,
+			txs: []db.Transaction{
+				tx(1, db.TxBuy, "2024-01-02", "10", ""),
+				tx(2, db.TxBuy, "2024-02-01", "5", "20"),
+				tx(3, db.TxSell, "2024-03-01", "-4", "30"),
+			},
+			shares: "11", cost: "100", costKnown: false, realized: "0", realizedKnown: false,
+		},
+		{
+			name: "unknown rate", method: CostFIFO, multiplier: 1,
+			txs: []db.Transaction{
+				tx(1, db.TxBuy, "2024-01-02", "5", "20"),
+				inCurrency(tx(2, db.TxBuy, "2024-02-01", "5", "20"), "XYZ"),
+			},
+			shares: "10", cost: "100", costKnown: false, realized: "0", realizedKnown: true,
+		},
+	}
+	for _, tt := range tests {
+		pc := positionCost(tt.txs, tt.method, "USD", "USD", tt.multiplier, testRates)
+		if pc.Shares.String() != tt.shares || pc.Cost.String() != tt.cost || pc.CostKnown != tt.costKnown {
+			t.Errorf("%s: %s shares costing %s (known %v), want %s costing %s (known %v)",
+				tt.name, pc.Shares, pc.Cost, pc.CostKnown, tt.shares, tt.cost, tt.costKnown)
+		}
+		if pc.Realized.String() != tt.realized || pc.RealizedKnown != tt.realizedKnown {
+			t.Errorf("%s: realized %s (known %v), want %s (known %v)",
+				tt.name, pc.Realized, pc.RealizedKnown, tt.realized, tt.realizedKnown)
+		}
+	}
+}
+
+func TestConsumeLots(t *testing.T) {
+	lots := func() []lot {
+		return []lot{
+			{shares: money.MustParse("10"), cost: money.MustParse("100"), known: true},
+			{shares: money.MustParse("10"), cost: money.MustParse("200"), known: false},
+			{shares: money.MustParse("10"), cost: money.MustParse("300"), known: true},
+		}
+	}
+	tests := []struct {
+		name   string
+		method string
+		shares string
+		cost   string
+		known  bool
+		left   []string // shares in each remaining lot
+	}{
+		{"fifo within first lot", CostFIFO, "4", "40", true, []string{"6", "10", "10"}},
+		{"fifo whole first lot", CostFIFO, "10", "100", true, []string{"10", "10"}},
+		{"fifo into unknown lot", CostFIFO, "15", "200", false, []string{"5", "10"}},
+		{"lifo within last lot", CostLIFO, "5", "150", true, []string{"10", "10", "5"}},
+		{"lifo across lots", CostLIFO, "12", "340", false, []string{"10", "8"}},
+		{"more than held", CostFIFO, "40", "600", false, nil},
+	}
+	for _, tt := range tests {
+		left, cost, known := consumeLots(lots(), money.MustParse(tt.shares), tt.method)
+		if cost.String() != tt.cost || known != tt.known {
+			t.Errorf("%s: cost %s (known %v), want %s (known %v)", tt.name, cost, known, tt.cost, tt.known)
+		}
+		var got []string
+		for _, l := range left {
+			got = append(got, l.shares.String())
+		}
+		if len(got) != len(tt.left) {
+			t.Errorf("%s: lots left %v, want %v", tt.name, got, tt.left)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.left[i] {
+				t.Errorf("%s: lots left %v, want %v", tt.name, got, tt.left)
+				break
+			}
+		}
+	}
+}
+
+func TestConsumeLotsUnapportionableCost(t *testing.T) {
+	huge := money.MustParse("90000000000")
+	lots := []lot{{shares: money.MustParse("10"), cost: huge.Add(huge), known: true}}
+
+	left, _, known := consumeLots(lots, money.MustParse("3"), CostFIFO)
+	if known {
+		t.Error("cost of a part of an overflowed lot reported as known")
+	}
+	if len(left) != 1 || left[0].known {
+		t.Errorf("remaining lot %+v, want one with unknown cost", left)
+	}
+}
+
+func TestConsumeLotsRunningOut(t *testing.T) {
+	lots := []lot{{shares: money.MustParse("1.49999999"), cost: money.MustParse("90"), known: true}}
+
+	left, cost, known := consumeLots(lots, money.MustParse("1.5"), CostFIFO)
+	if known {
+		t.Errorf("cost %s of a sale larger than its lots reported as known", cost)
+	}
+	if len(left) != 0 {
+		t.Errorf("lots left %+v, want none", left)
+	}
+}
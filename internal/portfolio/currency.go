@@ -0,0 +1,247 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/finance"
+	"stock-portfolio-bot/internal/money"
+)
+
+const (
+	// settingBaseCurrency is the user_settings key for the currency reports are valued in.
+	settingBaseCurrency = "base_currency"
+
+	// DefaultBaseCurrency is used until a user picks another.
+	DefaultBaseCurrency = "USD"
+
+	// fxMaxGap is how far before the requested day a stored rate may be and still
+	// count as that day's rate (weekends, holidays). Beyond it the history is fetched.
+	fxMaxGap = 7 * 24 * time.Hour
+)
+
+// ErrUnknownCurrency is returned when a base currency has no exchange rate.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// BaseCurrency returns the currency the user's reports are valued in.
+func (s *Service) BaseCurrency(chatID int64) (string, error) {
+	value, err := s.repo.GetUserSetting(chatID, settingBaseCurrency)
+	if err != nil {
+		return DefaultBaseCurrency, err
+	}
+	if value == "" {
+		return DefaultBaseCurrency, nil
+	}
+	return value, nil
+}
+
+// SetBaseCurrency changes the currency the user's reports are valued in. The code must
+// be one the provider can quote against USD; otherwise ErrUnknownCurrency is returned.
+func (s *Service) SetBaseCurrency(ctx context.Context, chatID int64, code string) (string, error) {
+	currency := finance.NormalizeCurrency(code)
+	if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	if _, err := s.GetRate(ctx, DefaultBaseCurrency, currency); err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrUnknownCurrency, currency, err)
+	}
+
+	value := currency
+	if currency == DefaultBaseCurrency {
+		value = "" // back to the default
+	}
+	if err := s.repo.SetUserSetting(chatID, settingBaseCurrency, value); err != nil {
+		return "", err
+	}
+	return currency, nil
+}
+
+// GetRate returns how many units of to equal one unit of from, served from the rate
+// cache where fresh.
+func (s *Service) GetRate(ctx context.Context, from, to string) (float64, error) {
+	pair := finance.NewCurrencyPair(from, to)
+	rates, err := s.pairRates(ctx, []finance.CurrencyPair{pair})
+	if rate, ok := rates[pair]; ok && rate > 0 {
+		return rate, nil
+	}
+	if err == nil {
+		err = finance.ErrNoPrice
+	}
+	return 0, fmt.Errorf("get rate %s: %w", pair, err)
+}
+
+// PrewarmRates fetches and caches missing rates from currencies into every base
+// currency in use.
+func (s *Service) PrewarmRates(ctx context.Context, currencies []string) error {
+	bases, err := s.repo.GetDistinctSettingValues(settingBaseCurrency)
+	if err != nil {
+		return err
+	}
+	bases = append(bases, DefaultBaseCurrency)
+
+	var pairs []finance.CurrencyPair
+	seen := make(map[finance.CurrencyPair]struct{})
+	for _, code := range currencies {
+		for _, base := range bases {
+			pair := finance.NewCurrencyPair(code, base)
+			if pair.From == "" || pair.From == pair.To {
+				continue
+			}
+			if _, ok := seen[pair]; ok {
+				continue
+			}
+			seen[pair] = struct{}{}
+			pairs = append(pairs, pair)
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	if _, err := s.pairRates(ctx, pairs); err != nil {
+		return fmt.Errorf("get rates: %w", err)
+	}
+	return nil
+}
+
+// RateOn returns how many units of to equaled one unit of from on date, using the
+// close of the nearest trading day on or before it, and that day. Stored history is
+// used where it covers the date; otherwise the pair's daily history is fetched and stored.
+func (s *Service) RateOn(ctx context.Context, from, to string, date time.Time) (float64, time.Time, error) {
+	pair := finance.NewCurrencyPair(from, to)
+	day := finance.CalendarDay(date)
+	if pair.From == pair.To {
+		return 1, day, nil
+	}
+
+	fx, err := s.repo.GetFXRateOn(pair.From, pair.To, day)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if fx != nil && day.Sub(fx.Date) <= fxMaxGap {
+		return fx.Rate, fx.Date, nil
+	}
+
+	if err := s.syncFXHistory(ctx, pair, fxRangeFor(day)); err != nil {
+		return 0, time.Time{}, err
+	}
+	fx, err = s.repo.GetFXRateOn(pair.From, pair.To, day)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if fx == nil || day.Sub(fx.Date) > fxMaxGap {
+		return 0, time.Time{}, fmt.Errorf("no %s rate on or shortly before %s: %w",
+			pair, day.Format("2006-01-02"), finance.ErrNoPrice)
+	}
+	return fx.Rate, fx.Date, nil
+}
+
+// syncFXHistory fetches daily rates for pair over rng and stores them.
+func (s *Service) syncFXHistory(ctx context.Context, pair finance.CurrencyPair, rng string) error {
+	hp, ok := s.provider.(finance.FXHistoryProvider)
+	if !ok {
+		return finance.ErrUnsupported
+	}
+	rates, err := hp.GetFXHistory(ctx, pair, rng)
+	if err != nil {
+		return fmt.Errorf("get fx history %s: %w", pair, err)
+	}
+
+	records := make([]db.FXRateRecord, len(rates))
+	for i, r := range rates {
+		records[i] = db.FXRateRecord{From: pair.From, To: pair.To, Date: r.Date, Rate: r.Rate}
+	}
+	if err := s.repo.SaveFXHistory(records); err != nil {
+		return fmt.Errorf("save fx history %s: %w", pair, err)
+	}
+	return nil
+}
+
+// fxRangeFor picks the shortest chart range that reaches back past day, with room
+// for a nearest-prior lookup.
+func fxRangeFor(day time.Time) string {
+	age := time.Since(day) + fxMaxGap
+	switch {
+	case age <= 28*24*time.Hour:
+		return "1mo"
+	case age <= 90*24*time.Hour:
+		return "3mo"
+	case age <= 365*24*time.Hour:
+		return "1y"
+	case age <= 2*365*24*time.Hour:
+		return "2y"
+	case age <= 5*365*24*time.Hour:
+		return "5y"
+	case age <= 10*365*24*time.Hour:
+		return "10y"
+	}
+	return "max"
+}
+
+// pairRates returns rates for pairs, served from the rate cache where fresh.
+// Concurrent misses for the same pair share one upstream fetch.
+func (s *Service) pairRates(ctx context.Context, pairs []finance.CurrencyPair) (map[finance.CurrencyPair]float64, error) {
+	if s.rates == nil {
+		return s.provider.GetRates(ctx, pairs)
+	}
+	return s.rates.GetOrFetch(ctx, pairs, s.provider.GetRates)
+}
+
+// conversionRates returns, for each currency, how many units of base one unit is
+// worth. Currencies whose rate could not be fetched are left out; the error is logged
+// by the caller.
+func (s *Service) conversionRates(ctx context.Context, currencies []string, base string) (map[string]float64, error) {
+	rates := map[string]float64{base: 1}
+	var pairs []finance.CurrencyPair
+	for _, code := range currencies {
+		pair := finance.NewCurrencyPair(code, base)
+		if pair.From == "" || pair.From == pair.To {
+			continue
+		}
+		pairs = append(pairs, pair)
+	}
+	if len(pairs) == 0 {
+		return rates, nil
+	}
+
+	fetched, err := s.pairRates(ctx, pairs)
+	for pair, rate := range fetched {
+		rates[pair.From] = rate
+	}
+	return rates, err
+}
+
+// Convert converts a value from the quote currency into the base currency that
+// rates were built for by conversionRates. The result is not rounded; callers round
+// to the base currency's minor unit once per reported amount.
+func (s *Service) Convert(value money.Decimal, currency string, rates map[string]float64) (money.Decimal, bool) {
+	normalized := finance.NormalizeCurrency(currency)
+	if normalized == "" {
+		return money.Zero, false
+	}
+	rate, ok := rates[normalized]
+	if !ok || rate <= 0 {
+		return money.Zero, false
+	}
+	return value.MulFloat(rate), true
+}
+
+// baseCurrency returns the user's base currency, falling back to the default if the
+// setting cannot be read.
+func (s *Service) baseCurrency(chatID int64, caller string) string {
+	base, err := s.BaseCurrency(chatID)
+	if err != nil {
+		log.Printf("%s: %v", caller, err)
+	}
+	return base
+}
+
+// FormatBaseCurrency describes the base currency for the /currency command.
+func FormatBaseCurrency(currency string) string {
+	return fmt.Sprintf("Reports are valued in %s.", currency)
+}
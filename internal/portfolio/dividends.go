@@ -0,0 +1,313 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/finance"
+	"stock-portfolio-bot/internal/money"
+)
+
+// eventsRange is how far back SyncEvents asks the provider for dividends and splits.
+const eventsRange = "2y"
+
+// DividendLine is the income received from one holding.
+type DividendLine struct {
+	Symbol    string
+	Payments  int
+	Income    money.Decimal // in Currency
+	Currency  string
+	Converted money.Decimal // Income in the report currency
+}
+
+// UpcomingDividend is a future ex-date, either announced or estimated from past cadence.
+type UpcomingDividend struct {
+	Symbol    string
+	ExDate    time.Time
+	Amount    float64 // per share, in Currency
+	Currency  string
+	Estimated bool
+}
+
+// DividendReport summarises dividends received on the shares held at each ex-date.
+type DividendReport struct {
+	Received []DividendLine
+	Currency string // base currency Converted and Total are in
+	Total    money.Decimal
+	Upcoming []UpcomingDividend
+}
+
+// Format produces a Telegram-friendly Markdown message.
+func (r *DividendReport) Format() string {
+	var sb strings.Builder
+	sb.WriteString("💵 *Dividends*\n\n")
+	if len(r.Received) == 0 {
+		sb.WriteString("No dividends received since you added your holdings.\n")
+	}
+	for _, d := range r.Received {
+		currency := strings.ToUpper(d.Currency)
+		if currency == r.Currency {
+			fmt.Fprintf(&sb, "*%s*: %s (%d payments)\n", d.Symbol, formatMoney(d.Converted, r.Currency), d.Payments)
+			continue
+		}
+		fmt.Fprintf(&sb, "*%s*: %s %s = %s (%d payments)\n",
+			d.Symbol, d.Income.StringFixed(money.CurrencyPlaces(currency)), currency,
+			formatMoney(d.Converted, r.Currency), d.Payments)
+	}
+
+	if len(r.Upcoming) > 0 {
+		sb.WriteString("\n📅 *Upcoming ex-dates*\n")
+		for _, u := range r.Upcoming {
+			est := ""
+			if u.Estimated {
+				est = " (est.)"
+			}
+			fmt.Fprintf(&sb, "*%s*: %s%s — %.4f %s/share\n",
+				u.Symbol, u.ExDate.Format("Jan 2, 2006"), est, u.Amount, strings.ToUpper(u.Currency))
+		}
+	}
+
+	fmt.Fprintf(&sb, "\n💰 *Total received: %s*", formatMoney(r.Total, r.Currency))
+	return sb.String()
+}
+
+// SyncEvents fetches dividends and splits for symbols and stores them.
+// It returns the splits that were not stored before.
+func (s *Service) SyncEvents(ctx context.Context, symbols []string) ([]db.SplitRecord, error) {
+	ep, ok := s.provider.(finance.EventProvider)
+	if !ok {
+		return nil, finance.ErrUnsupported
+	}
+
+	var newSplits []db.SplitRecord
+	var failed []string
+	for _, sym := range symbols {
+		events, err := ep.GetEvents(ctx, sym, eventsRange)
+		if err != nil {
+			log.Printf("SyncEvents: get events %s: %v", sym, err)
+			failed = append(failed, sym)
+			continue
+		}
+
+		dividends := make([]db.DividendRecord, len(events.Dividends))
+		for i, d := range events.Dividends {
+			dividends[i] = db.DividendRecord{Symbol: sym, ExDate: d.ExDate, Amount: d.Amount, Currency: d.Currency}
+		}
+		if err := s.repo.SaveDividends(dividends); err != nil {
+			return newSplits, fmt.Errorf("save dividends %s: %w", sym, err)
+		}
+
+		splits := make([]db.SplitRecord, len(events.Splits))
+		for i, sp := range events.Splits {
+			splits[i] = db.SplitRecord{Symbol: sym, Date: sp.Date, Numerator: sp.Numerator, Denominator: sp.Denominator}
+		}
+		added, err := s.repo.SaveSplits(splits)
+		if err != nil {
+			return newSplits, fmt.Errorf("save splits %s: %w", sym, err)
+		}
+		newSplits = append(newSplits, added...)
+	}
+
+	if len(failed) > 0 {
+		return newSplits, fmt.Errorf("events unavailable for %v", failed)
+	}
+	return newSplits, nil
+}
+
+// splitLookback bounds how old a split may be and still adjust holdings, so
+// splits the user already accounted for by hand are not applied twice.
+const splitLookback = 14 * 24 * time.Hour
+
+// ApplySplits adjusts share counts for recent splits of held symbols. Each holding
+// is adjusted at most once per split, so it is safe to call on every scheduler tick.
+func (s *Service) ApplySplits(ctx context.Context) ([]db.SplitAdjustment, error) {
+	splits, err := s.repo.GetSplitsForHeldSymbols(time.Now().Add(-splitLookback))
+	if err != nil {
+		return nil, fmt.Errorf("get splits: %w", err)
+	}
+
+	var adjustments []db.SplitAdjustment
+	for _, sp := range splits {
+		if ctx.Err() != nil {
+			return adjustments, ctx.Err()
+		}
+		applied, err := s.repo.ApplySplit(sp)
+		if err != nil {
+			return adjustments, fmt.Errorf("apply split %s %s: %w", sp.Symbol, sp.Date.Format("2006-01-02"), err)
+		}
+		adjustments = append(adjustments, applied...)
+	}
+	return adjustments, nil
+}
+
+// ComputeDividends reports the dividend income a user received from each holding of
+// their active portfolio, at the shares the ledger shows held on each ex-date, and the
+// next ex-dates for those holdings.
+func (s *Service) ComputeDividends(ctx context.Context, chatID int64) (*DividendReport, error) {
+	p, err := s.ActivePortfolio(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get active portfolio: %w", err)
+	}
+	holdings, err := s.repo.GetHoldings(p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get holdings: %w", err)
+	}
+	if len(holdings) == 0 {
+		return nil, nil
+	}
+	ledgers, err := s.repo.GetPortfolioTransactions(p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get transactions: %w", err)
+	}
+
+	symbols := make([]string, len(holdings))
+	earliest := time.Now()
+	for i, h := range holdings {
+		symbols[i] = h.Symbol
+		opened := h.AddedAt
+		if txs := ledgers[h.Symbol]; len(txs) > 0 {
+			opened = txs[0].Date
+		}
+		if opened.Before(earliest) {
+			earliest = opened
+		}
+	}
+
+	// Look back far enough to estimate payment cadence for recent holdings too.
+	since := earliest.AddDate(-1, 0, 0)
+	records, err := s.repo.GetDividends(symbols, since)
+	if err != nil {
+		return nil, fmt.Errorf("get dividends: %w", err)
+	}
+	base := s.baseCurrency(chatID, "ComputeDividends")
+	bySymbol := make(map[string][]db.DividendRecord)
+	currencySet := make(map[string]struct{})
+	for _, d := range records {
+		bySymbol[d.Symbol] = append(bySymbol[d.Symbol], d)
+		if c := strings.ToUpper(d.Currency); c != "" && c != base {
+			currencySet[c] = struct{}{}
+		}
+	}
+
+	rates := map[string]float64{base: 1}
+	if len(currencySet) > 0 {
+		currencies := make([]string, 0, len(currencySet))
+		for c := range currencySet {
+			currencies = append(currencies, c)
+		}
+		var rateErr error
+		rates, rateErr = s.conversionRates(ctx, currencies, base)
+		if rateErr != nil {
+			log.Printf("ComputeDividends: get %s rates for currencies %v (chatID %d): %v", base, currencies, chatID, rateErr)
+		}
+	}
+
+	now := time.Now()
+	report := &DividendReport{Currency: base}
+	for _, h := range holdings {
+		divs := bySymbol[h.Symbol]
+		if h.Shares.Sign() <= 0 {
+			continue // watch-only
+		}
+
+		splits, err := s.repo.GetSplits(h.Symbol, since)
+		if err != nil {
+			return nil, fmt.Errorf("get splits %s: %w", h.Symbol, err)
+		}
+		line := DividendLine{Symbol: h.Symbol}
+		for _, d := range divs {
+			if d.ExDate.After(now) {
+				continue
+			}
+			held := sharesBefore(ledgers[h.Symbol], d.ExDate)
+			if held.Sign() <= 0 {
+				continue
+			}
+			line.Payments++
+			line.Income = line.Income.Add(held.MulFloat(unadjustedAmount(d, splits)))
+			line.Currency = d.Currency
+		}
+		if line.Payments > 0 {
+			converted, ok := s.Convert(line.Income, line.Currency, rates)
+			if !ok {
+				log.Printf("ComputeDividends: no %s conversion rate for currency %s symbol %s (chatID %d)",
+					base, line.Currency, h.Symbol, chatID)
+			}
+			line.Converted = converted.RoundCurrency(base)
+			report.Received = append(report.Received, line)
+			report.Total = report.Total.Add(line.Converted)
+		}
+
+		if next, ok := nextExDate(divs, now); ok {
+			report.Upcoming = append(report.Upcoming, next)
+		}
+	}
+
+	sort.Slice(report.Upcoming, func(i, j int) bool {
+		return report.Upcoming[i].ExDate.Before(report.Upcoming[j].ExDate)
+	})
+	return report, nil
+}
+
+// sharesBefore returns the shares a ledger (oldest first) held going into exDate's
+// calendar day: trades and splits on the ex-date itself do not count.
+func sharesBefore(txs []db.Transaction, exDate time.Time) money.Decimal {
+	day := finance.CalendarDay(exDate)
+	shares := money.Zero
+	for _, t := range txs {
+		if !t.Date.Before(day) {
+			break
+		}
+		shares = shares.Add(t.Quantity)
+	}
+	return shares
+}
+
+// unadjustedAmount returns what a dividend paid per share held on its ex-date. The
+// provider reports amounts adjusted for later splits, but sharesBefore counts the
+// shares held before them, so the amount is scaled back by each split (oldest first)
+// dated after the ex-date's calendar day.
+func unadjustedAmount(d db.DividendRecord, splits []db.SplitRecord) float64 {
+	day := finance.CalendarDay(d.ExDate)
+	amount := d.Amount
+	for _, sp := range splits {
+		if finance.CalendarDay(sp.Date).After(day) && sp.Numerator > 0 && sp.Denominator > 0 {
+			amount *= sp.Numerator / sp.Denominator
+		}
+	}
+	return amount
+}
+
+// nextExDate returns the next ex-date after now: an announced one if stored,
+// otherwise an estimate from the average spacing of past payments.
+func nextExDate(divs []db.DividendRecord, now time.Time) (UpcomingDividend, bool) {
+	var past []db.DividendRecord
+	for _, d := range divs {
+		if d.ExDate.After(now) {
+			return UpcomingDividend{Symbol: d.Symbol, ExDate: d.ExDate, Amount: d.Amount, Currency: d.Currency}, true
+		}
+		past = append(past, d)
+	}
+	if len(past) < 2 {
+		return UpcomingDividend{}, false
+	}
+
+	first, last := past[0], past[len(past)-1]
+	interval := last.ExDate.Sub(first.ExDate) / time.Duration(len(past)-1)
+	next := last.ExDate.Add(interval)
+	if interval <= 0 || next.Before(now) {
+		return UpcomingDividend{}, false // payments appear to have stopped
+	}
+	return UpcomingDividend{
+		Symbol:    last.Symbol,
+		ExDate:    next,
+		Amount:    last.Amount,
+		Currency:  last.Currency,
+		Estimated: true,
+	}, true
+}
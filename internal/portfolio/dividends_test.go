@@ -0,0 +1,69 @@
+package portfolio
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/money"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestSharesBefore(t *testing.T) {
+	ledger := []db.Transaction{
+		{Kind: db.TxBuy, Date: day("2024-01-02"), Quantity: money.MustParse("10")},
+		{Kind: db.TxSell, Date: day("2024-03-01"), Quantity: money.MustParse("-4")},
+		{Kind: db.TxSplit, Date: day("2024-06-03"), Quantity: money.MustParse("18")}, // 4:1
+		{Kind: db.TxBuy, Date: day("2024-08-01").Add(15 * time.Hour), Quantity: money.MustParse("1")},
+	}
+	tests := []struct {
+		exDate string
+		want   string
+	}{
+		{"2023-12-01", "0"},
+		{"2024-01-02", "0"},  // bought on the ex-date: no dividend
+		{"2024-02-01", "10"}, // before the sale
+		{"2024-03-01", "10"}, // sold on the ex-date: still entitled
+		{"2024-04-01", "6"},
+		{"2024-06-03", "6"}, // split on the ex-date
+		{"2024-07-01", "24"},
+		{"2024-08-01", "24"},
+		{"2024-09-01", "25"},
+	}
+	for _, tt := range tests {
+		if got := sharesBefore(ledger, day(tt.exDate)); got != money.MustParse(tt.want) {
+			t.Errorf("sharesBefore(%s) = %s, want %s", tt.exDate, got, tt.want)
+		}
+	}
+}
+
+func TestUnadjustedAmount(t *testing.T) {
+	splits := []db.SplitRecord{
+		{Symbol: "NVDA", Date: day("2021-07-20"), Numerator: 4, Denominator: 1},
+		{Symbol: "NVDA", Date: day("2024-06-10"), Numerator: 10, Denominator: 1},
+	}
+	tests := []struct {
+		exDate string
+		amount float64 // as the provider reports it, adjusted for every split
+		want   float64
+	}{
+		{"2021-06-09", 0.004, 0.16}, // before both splits
+		{"2021-07-20", 0.004, 0.04}, // on the first split's day: only the later one counts
+		{"2024-03-05", 0.004, 0.04}, // between the splits
+		{"2024-06-11", 0.01, 0.01},  // after both
+	}
+	for _, tt := range tests {
+		d := db.DividendRecord{Symbol: "NVDA", ExDate: day(tt.exDate), Amount: tt.amount}
+		if got := unadjustedAmount(d, splits); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("unadjustedAmount(%s) = %v, want %v", tt.exDate, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/finance"
+)
+
+// symbolInfoMaxAge is how long stored sector data is trusted before SyncSymbolInfo refreshes it.
+const symbolInfoMaxAge = 30 * 24 * time.Hour
+
+// GetFundamentals returns fundamentals for symbol and stores its sector and industry.
+func (s *Service) GetFundamentals(ctx context.Context, symbol string) (*finance.Fundamentals, error) {
+	fp, ok := s.provider.(finance.FundamentalsProvider)
+	if !ok {
+		return nil, finance.ErrUnsupported
+	}
+
+	f, err := fp.GetFundamentals(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SaveSymbolInfo(symbolInfo(f)); err != nil {
+		log.Printf("GetFundamentals: %v", err)
+	}
+	return f, nil
+}
+
+// SyncSymbolInfo stores sector and industry for symbols that have none or whose
+// stored info is older than symbolInfoMaxAge.
+func (s *Service) SyncSymbolInfo(ctx context.Context, symbols []string) error {
+	fp, ok := s.provider.(finance.FundamentalsProvider)
+	if !ok {
+		return finance.ErrUnsupported
+	}
+
+	stored, err := s.repo.GetSymbolInfo(symbols)
+	if err != nil {
+		return fmt.Errorf("get symbol info: %w", err)
+	}
+
+	var failed []string
+	for _, sym := range symbols {
+		if info, ok := stored[sym]; ok && time.Since(info.UpdatedAt) < symbolInfoMaxAge {
+			continue
+		}
+		f, err := fp.GetFundamentals(ctx, sym)
+		if err != nil {
+			log.Printf("SyncSymbolInfo: get fundamentals %s: %v", sym, err)
+			failed = append(failed, sym)
+			continue
+		}
+		if err := s.repo.SaveSymbolInfo(symbolInfo(f)); err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("fundamentals unavailable for %v", failed)
+	}
+	return nil
+}
+
+func symbolInfo(f *finance.Fundamentals) db.SymbolInfo {
+	return db.SymbolInfo{
+		Symbol:    f.Symbol,
+		Name:      f.Name,
+		QuoteType: f.Type,
+		Sector:    f.Sector,
+		Industry:  f.Industry,
+		Country:   f.Country,
+	}
+}
+
+// FormatFundamentals produces a Telegram-friendly Markdown message for f.
+// Fields Yahoo did not report are left out.
+func FormatFundamentals(f *finance.Fundamentals) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "ℹ️ *%s*", f.Symbol)
+	if f.Name != "" {
+		fmt.Fprintf(&sb, " — %s", f.Name)
+	}
+	sb.WriteString("\n")
+	if f.Type != "" {
+		fmt.Fprintf(&sb, "%s\n", finance.TypeLabel(f.Type))
+	}
+	sb.WriteString("\n")
+
+	if f.Sector != "" {
+		fmt.Fprintf(&sb, "Sector: %s\n", f.Sector)
+	}
+	if f.Industry != "" {
+		fmt.Fprintf(&sb, "Industry: %s\n", f.Industry)
+	}
+	if f.Country != "" {
+		fmt.Fprintf(&sb, "Country: %s\n", f.Country)
+	}
+	if f.MarketCap > 0 {
+		fmt.Fprintf(&sb, "Market cap: %s %s\n", formatLarge(f.MarketCap), f.Currency)
+	}
+	if f.TrailingPE > 0 {
+		fmt.Fprintf(&sb, "P/E (TTM): %.2f\n", f.TrailingPE)
+	}
+	if f.ForwardPE > 0 {
+		fmt.Fprintf(&sb, "P/E (forward): %.2f\n", f.ForwardPE)
+	}
+	if f.DividendYield > 0 {
+		fmt.Fprintf(&sb, "Dividend yield: %.2f%%\n", f.DividendYield*100)
+	}
+	if f.Beta != 0 {
+		fmt.Fprintf(&sb, "Beta: %.2f\n", f.Beta)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// formatLarge renders v with a T/B/M suffix, e.g. 2.95T.
+func formatLarge(v float64) string {
+	switch {
+	case v >= 1e12:
+		return fmt.Sprintf("%.2fT", v/1e12)
+	case v >= 1e9:
+		return fmt.Sprintf("%.2fB", v/1e9)
+	case v >= 1e6:
+		return fmt.Sprintf("%.2fM", v/1e6)
+	default:
+		return fmt.Sprintf("%.0f", v)
+	}
+}
@@ -0,0 +1,57 @@
+package portfolio
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"stock-portfolio-bot/internal/finance"
+)
+
+// ProviderHealth reports the circuit state of each quote provider that tracks it.
+func (s *Service) ProviderHealth() []finance.ProviderHealth {
+	hr, ok := s.provider.(finance.HealthReporter)
+	if !ok {
+		return nil
+	}
+	return hr.Health()
+}
+
+// FormatHealth produces a Telegram-friendly Markdown message for the /health command.
+func FormatHealth(health []finance.ProviderHealth) string {
+	if len(health) == 0 {
+		return "No provider reports health."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🩺 *Provider health*\n")
+	for _, h := range health {
+		icon := "🟢"
+		switch h.State {
+		case finance.CircuitOpen:
+			icon = "🔴"
+		case finance.CircuitHalfOpen:
+			icon = "🟡"
+		}
+		fmt.Fprintf(&sb, "\n%s *%s*: %s", icon, h.Name, h.State)
+		if !h.OpenedAt.IsZero() {
+			fmt.Fprintf(&sb, " since %s", formatAsOf(h.OpenedAt))
+		}
+		fmt.Fprintf(&sb, "\n  ok %d · failed %d · rejected %d · failing streak %d\n",
+			h.Successes, h.Failures, h.Rejected, h.ConsecutiveFailures)
+		if h.LastError != "" {
+			fmt.Fprintf(&sb, "  last error %s: `%s`\n", formatAsOf(h.LastErrorAt), strings.ReplaceAll(h.LastError, "`", "'"))
+		}
+		if s := h.Session; s != nil {
+			if s.Valid {
+				fmt.Fprintf(&sb, "  session %s, expires in %s\n", s.Source, time.Until(s.ExpiresAt).Round(time.Second))
+			} else {
+				sb.WriteString("  session: none\n")
+			}
+			if s.FailedFetches > 0 {
+				fmt.Fprintf(&sb, "  session fetches ok %d · failed %d\n", s.Fetches, s.FailedFetches)
+			}
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
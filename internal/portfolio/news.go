@@ -0,0 +1,253 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"stock-portfolio-bot/internal/finance"
+)
+
+const (
+	// newsPerHolding is how many headlines /news shows for each holding.
+	newsPerHolding = 3
+
+	// newsMaxAge is the oldest headline the scheduler will still push.
+	newsMaxAge = 48 * time.Hour
+
+	// newsPushLimit caps the headlines in one pushed digest; the rest follow next tick.
+	newsPushLimit = 5
+
+	// sentNewsRetention is how long pushed headline IDs are remembered. It must
+	// exceed newsMaxAge so a headline can never be pushed twice.
+	sentNewsRetention = 30 * 24 * time.Hour
+)
+
+// NewsLine is the recent headlines for one holding.
+type NewsLine struct {
+	Symbol string
+	Items  []finance.NewsItem
+}
+
+// NewsReport lists recent headlines per holding.
+type NewsReport struct {
+	Holdings []NewsLine
+}
+
+// Format produces a Telegram-friendly Markdown message.
+func (r *NewsReport) Format() string {
+	var sb strings.Builder
+	sb.WriteString("📰 *News*\n")
+	for _, line := range r.Holdings {
+		fmt.Fprintf(&sb, "\n*%s*\n", line.Symbol)
+		if len(line.Items) == 0 {
+			sb.WriteString("No recent headlines.\n")
+			continue
+		}
+		for _, item := range line.Items {
+			writeHeadline(&sb, item, "")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// NewsDigestItem is a pushed headline and the holding it was found for.
+type NewsDigestItem struct {
+	Symbol string
+	finance.NewsItem
+}
+
+// NewsDigest is the set of new headlines to push to one subscriber.
+type NewsDigest struct {
+	ChatID int64
+	Items  []NewsDigestItem // newest first
+}
+
+// Format produces a Telegram-friendly Markdown message.
+func (d *NewsDigest) Format() string {
+	var sb strings.Builder
+	sb.WriteString("📰 *New headlines for your holdings*\n\n")
+	for _, item := range d.Items {
+		writeHeadline(&sb, item.NewsItem, item.Symbol)
+	}
+	sb.WriteString("\n_Turn off with /news off_")
+	return sb.String()
+}
+
+// writeHeadline writes "• [title](link) — publisher, 14:05" with an optional symbol tag.
+func writeHeadline(sb *strings.Builder, item finance.NewsItem, symbol string) {
+	fmt.Fprintf(sb, "• [%s](%s)", markdownSafe(item.Title), item.Link)
+	if item.Publisher != "" {
+		fmt.Fprintf(sb, " — %s", markdownSafe(item.Publisher))
+	}
+	fmt.Fprintf(sb, ", %s", formatAsOf(item.PublishedAt.Local()))
+	if symbol != "" {
+		fmt.Fprintf(sb, " (%s)", symbol)
+	}
+	sb.WriteString("\n")
+}
+
+// markdownSafe strips characters that would break legacy Telegram Markdown in
+// third-party text such as headlines.
+var markdownSafe = strings.NewReplacer("[", "(", "]", ")", "*", "", "_", " ", "`", "'").Replace
+
+// GetNews returns headlines for each symbol, fetching each symbol once no matter how
+// many users hold it. Symbols whose news could not be fetched are left out and
+// reported in the error.
+func (s *Service) GetNews(ctx context.Context, symbols []string) (map[string][]finance.NewsItem, error) {
+	np, ok := s.provider.(finance.NewsProvider)
+	if !ok {
+		return nil, finance.ErrUnsupported
+	}
+
+	news := make(map[string][]finance.NewsItem, len(symbols))
+	var failed []string
+	for _, sym := range symbols {
+		if _, done := news[sym]; done {
+			continue
+		}
+		items, err := np.GetNews(ctx, sym)
+		if err != nil {
+			log.Printf("GetNews: %s: %v", sym, err)
+			failed = append(failed, sym)
+			continue
+		}
+		news[sym] = items
+	}
+
+	if len(failed) > 0 {
+		return news, fmt.Errorf("news unavailable for %v", failed)
+	}
+	return news, nil
+}
+
+// ComputeNews returns the latest headlines for each symbol the user holds in any
+// portfolio.
+func (s *Service) ComputeNews(ctx context.Context, chatID int64) (*NewsReport, error) {
+	symbols, err := s.repo.GetUserSymbols(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get symbols: %w", err)
+	}
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+
+	news, err := s.GetNews(ctx, symbols)
+	if len(news) == 0 && err != nil {
+		return nil, fmt.Errorf("get news: %w", err)
+	}
+
+	report := &NewsReport{}
+	for _, sym := range symbols {
+		items := news[sym]
+		if len(items) > newsPerHolding {
+			items = items[:newsPerHolding]
+		}
+		report.Holdings = append(report.Holdings, NewsLine{Symbol: sym, Items: items})
+	}
+	return report, nil
+}
+
+// SetNewsSubscription opts a user in to or out of pushed headlines.
+func (s *Service) SetNewsSubscription(chatID int64, subscribed bool) error {
+	return s.repo.SetNewsSubscription(chatID, subscribed)
+}
+
+// PendingNews returns, for each subscriber, the headlines for their holdings that were
+// published after they subscribed and have not been pushed to them yet. News for each
+// symbol is fetched once across all subscribers.
+func (s *Service) PendingNews(ctx context.Context) ([]NewsDigest, error) {
+	subs, err := s.repo.GetNewsSubscriptions()
+	if err != nil {
+		return nil, fmt.Errorf("get news subscriptions: %w", err)
+	}
+	if len(subs) == 0 {
+		return nil, nil
+	}
+	if err := s.repo.PruneSentNews(time.Now().Add(-sentNewsRetention)); err != nil {
+		log.Printf("PendingNews: %v", err)
+	}
+
+	holdingsByChat := make(map[int64][]string, len(subs))
+	var symbols []string
+	seen := make(map[string]struct{})
+	for _, sub := range subs {
+		held, err := s.repo.GetUserSymbols(sub.ChatID)
+		if err != nil {
+			return nil, fmt.Errorf("get symbols %d: %w", sub.ChatID, err)
+		}
+		holdingsByChat[sub.ChatID] = held
+		for _, sym := range held {
+			if _, ok := seen[sym]; !ok {
+				seen[sym] = struct{}{}
+				symbols = append(symbols, sym)
+			}
+		}
+	}
+
+	news, newsErr := s.GetNews(ctx, symbols)
+	if len(news) == 0 && newsErr != nil {
+		return nil, fmt.Errorf("get news: %w", newsErr)
+	}
+
+	cutoff := time.Now().Add(-newsMaxAge)
+	var digests []NewsDigest
+	for _, sub := range subs {
+		since := cutoff
+		if sub.CreatedAt.After(since) {
+			since = sub.CreatedAt
+		}
+
+		byUUID := make(map[string]NewsDigestItem)
+		var uuids []string
+		for _, sym := range holdingsByChat[sub.ChatID] {
+			for _, item := range news[sym] {
+				if !item.PublishedAt.After(since) {
+					continue
+				}
+				if _, dup := byUUID[item.UUID]; dup {
+					continue // same story for two holdings
+				}
+				byUUID[item.UUID] = NewsDigestItem{Symbol: sym, NewsItem: item}
+				uuids = append(uuids, item.UUID)
+			}
+		}
+
+		unsent, err := s.repo.FilterUnsentNews(sub.ChatID, uuids)
+		if err != nil {
+			return digests, err
+		}
+		if len(unsent) == 0 {
+			continue
+		}
+
+		digest := NewsDigest{ChatID: sub.ChatID}
+		for _, uuid := range unsent {
+			digest.Items = append(digest.Items, byUUID[uuid])
+		}
+		sort.Slice(digest.Items, func(i, j int) bool {
+			return digest.Items[i].PublishedAt.After(digest.Items[j].PublishedAt)
+		})
+		if len(digest.Items) > newsPushLimit {
+			digest.Items = digest.Items[:newsPushLimit]
+		}
+		digests = append(digests, digest)
+	}
+
+	if newsErr != nil {
+		return digests, newsErr
+	}
+	return digests, nil
+}
+
+// MarkNewsSent records that a digest was pushed, so its headlines are not sent again.
+func (s *Service) MarkNewsSent(digest NewsDigest) error {
+	uuids := make([]string, len(digest.Items))
+	for i, item := range digest.Items {
+		uuids[i] = item.UUID
+	}
+	return s.repo.MarkNewsSent(digest.ChatID, uuids)
+}
@@ -0,0 +1,333 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/money"
+)
+
+const (
+	// settingActivePortfolio is the user_settings key for the ID of the portfolio that
+	// trades, /p and /d apply to.
+	settingActivePortfolio = "portfolio"
+
+	// DefaultPortfolioName is the name of the portfolio every user starts with.
+	DefaultPortfolioName = "Main"
+
+	// MaxPortfolioName is the longest portfolio name accepted, in characters.
+	MaxPortfolioName = 32
+)
+
+var (
+	// ErrPortfolioNotFound is returned when a user has no portfolio by the given name.
+	ErrPortfolioNotFound = errors.New("portfolio not found")
+
+	// ErrPortfolioExists is returned when a user already has a portfolio by the given name.
+	ErrPortfolioExists = errors.New("portfolio already exists")
+
+	// ErrInvalidPortfolioName is returned for an empty or overlong name.
+	ErrInvalidPortfolioName = errors.New("invalid portfolio name")
+
+	// ErrLastPortfolio is returned when deleting a user's only portfolio.
+	ErrLastPortfolio = errors.New("cannot delete the last portfolio")
+)
+
+// Portfolios returns the user's portfolios, oldest first, creating the default one
+// for a user who has none.
+func (s *Service) Portfolios(chatID int64) ([]db.Portfolio, error) {
+	portfolios, err := s.repo.GetPortfolios(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(portfolios) > 0 {
+		return portfolios, nil
+	}
+	// Two first messages can arrive at once; only one of them creates it.
+	if err := s.repo.EnsurePortfolio(chatID, DefaultPortfolioName); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPortfolios(chatID)
+}
+
+// ActivePortfolio returns the portfolio the user is working in: the one last switched
+// to, or their oldest.
+func (s *Service) ActivePortfolio(chatID int64) (db.Portfolio, error) {
+	portfolios, err := s.Portfolios(chatID)
+	if err != nil {
+		return db.Portfolio{}, err
+	}
+	return s.activeOf(chatID, portfolios), nil
+}
+
+// activeOf picks the active portfolio out of the user's portfolios.
+func (s *Service) activeOf(chatID int64, portfolios []db.Portfolio) db.Portfolio {
+	value, err := s.repo.GetUserSetting(chatID, settingActivePortfolio)
+	if err != nil {
+		log.Printf("ActivePortfolio: %v", err)
+	}
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		for _, p := range portfolios {
+			if p.ID == id {
+				return p
+			}
+		}
+	}
+	return portfolios[0]
+}
+
+// CreatePortfolio adds a portfolio and makes it the active one.
+func (s *Service) CreatePortfolio(chatID int64, name string) (db.Portfolio, error) {
+	name, err := portfolioName(name)
+	if err != nil {
+		return db.Portfolio{}, err
+	}
+	portfolios, err := s.Portfolios(chatID)
+	if err != nil {
+		return db.Portfolio{}, err
+	}
+	if _, ok := findPortfolio(portfolios, name); ok {
+		return db.Portfolio{}, fmt.Errorf("%w: %q", ErrPortfolioExists, name)
+	}
+
+	p, err := s.repo.CreatePortfolio(chatID, name)
+	if err != nil {
+		return db.Portfolio{}, err
+	}
+	if err := s.setActive(chatID, p); err != nil {
+		return db.Portfolio{}, err
+	}
+	return p, nil
+}
+
+// UsePortfolio makes the named portfolio the active one.
+func (s *Service) UsePortfolio(chatID int64, name string) (db.Portfolio, error) {
+	portfolios, err := s.Portfolios(chatID)
+	if err != nil {
+		return db.Portfolio{}, err
+	}
+	p, ok := findPortfolio(portfolios, name)
+	if !ok {
+		return db.Portfolio{}, fmt.Errorf("%w: %q", ErrPortfolioNotFound, strings.TrimSpace(name))
+	}
+	if err := s.setActive(chatID, p); err != nil {
+		return db.Portfolio{}, err
+	}
+	return p, nil
+}
+
+// RenamePortfolio renames the active portfolio.
+func (s *Service) RenamePortfolio(chatID int64, name string) (db.Portfolio, error) {
+	name, err := portfolioName(name)
+	if err != nil {
+		return db.Portfolio{}, err
+	}
+	portfolios, err := s.Portfolios(chatID)
+	if err != nil {
+		return db.Portfolio{}, err
+	}
+	active := s.activeOf(chatID, portfolios)
+	if other, ok := findPortfolio(portfolios, name); ok && other.ID != active.ID {
+		return db.Portfolio{}, fmt.Errorf("%w: %q", ErrPortfolioExists, name)
+	}
+
+	if err := s.repo.RenamePortfolio(chatID, active.ID, name); err != nil {
+		return db.Portfolio{}, err
+	}
+	active.Name = name
+	return active, nil
+}
+
+// DeletePortfolio removes the named portfolio with everything in it. If it was the
+// active one, the oldest remaining portfolio becomes active. It returns the deleted
+// portfolio and the active one afterwards.
+func (s *Service) DeletePortfolio(chatID int64, name string) (deleted, active db.Portfolio, err error) {
+	portfolios, err := s.Portfolios(chatID)
+	if err != nil {
+		return db.Portfolio{}, db.Portfolio{}, err
+	}
+	deleted, ok := findPortfolio(portfolios, name)
+	if !ok {
+		return db.Portfolio{}, db.Portfolio{}, fmt.Errorf("%w: %q", ErrPortfolioNotFound, strings.TrimSpace(name))
+	}
+	if len(portfolios) == 1 {
+		return db.Portfolio{}, db.Portfolio{}, ErrLastPortfolio
+	}
+	active = s.activeOf(chatID, portfolios)
+
+	if err := s.repo.DeletePortfolio(chatID, deleted.ID); err != nil {
+		return db.Portfolio{}, db.Portfolio{}, err
+	}
+	if active.ID == deleted.ID {
+		for _, p := range portfolios {
+			if p.ID != deleted.ID {
+				active = p
+				break
+			}
+		}
+		if err := s.setActive(chatID, active); err != nil {
+			return db.Portfolio{}, db.Portfolio{}, err
+		}
+	}
+	return deleted, active, nil
+}
+
+func (s *Service) setActive(chatID int64, p db.Portfolio) error {
+	return s.repo.SetUserSetting(chatID, settingActivePortfolio, strconv.FormatInt(p.ID, 10))
+}
+
+// portfolioName trims and validates a portfolio name.
+func portfolioName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > MaxPortfolioName {
+		return "", fmt.Errorf("%w: use 1 to %d characters", ErrInvalidPortfolioName, MaxPortfolioName)
+	}
+	return name, nil
+}
+
+// findPortfolio looks a portfolio up by name, ignoring case and extra spaces.
+func findPortfolio(portfolios []db.Portfolio, name string) (db.Portfolio, bool) {
+	name = strings.Join(strings.Fields(name), " ")
+	for _, p := range portfolios {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return db.Portfolio{}, false
+}
+
+// FormatPortfolios lists the user's portfolios for the /portfolios command, marking
+// the active one.
+func FormatPortfolios(portfolios []db.Portfolio, active db.Portfolio) string {
+	var sb strings.Builder
+	sb.WriteString("Your portfolios:\n")
+	for _, p := range portfolios {
+		marker := "  "
+		if p.ID == active.ID {
+			marker = "▶ "
+		}
+		fmt.Fprintf(&sb, "\n%s%s", marker, p.Name)
+	}
+	return sb.String()
+}
+
+// UserBalance is the balance of each of a user's non-empty portfolios and their
+// combined total.
+type UserBalance struct {
+	Reports  []*BalanceReport // oldest portfolio first
+	Currency string
+	Total    money.Decimal
+
+	// Failed names the portfolios left out of Total because they could not be
+	// valued, with the reason.
+	Failed map[string]error
+}
+
+// ComputeUserBalance values every portfolio of the user. A portfolio that cannot be
+// valued is logged and left out, marking the balance partial; only if none can be is
+// the error returned. It returns nil, nil if they are all empty.
+func (s *Service) ComputeUserBalance(ctx context.Context, chatID int64) (*UserBalance, error) {
+	portfolios, err := s.Portfolios(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get portfolios: %w", err)
+	}
+
+	balance := &UserBalance{Failed: make(map[string]error)}
+	var firstErr error
+	for _, p := range portfolios {
+		report, err := s.ComputePortfolioBalance(ctx, p)
+		if err != nil {
+			log.Printf("ComputeUserBalance: portfolio %d %q (chatID %d): %v", p.ID, p.Name, chatID, err)
+			balance.Failed[p.Name] = err
+			if firstErr == nil {
+				firstErr = fmt.Errorf("portfolio %q: %w", p.Name, err)
+			}
+			continue
+		}
+		if report == nil || len(report.Holdings) == 0 {
+			continue
+		}
+		balance.Currency = report.Currency
+		report.Named = len(portfolios) > 1
+		balance.Reports = append(balance.Reports, report)
+		balance.Total = balance.Total.Add(report.Total)
+	}
+
+	switch {
+	case len(balance.Reports) == 0 && firstErr != nil:
+		return nil, firstErr
+	case len(balance.Reports) == 0:
+		return nil, nil
+	case balance.Total.Overflowed():
+		return nil, fmt.Errorf("combined total: %w", money.ErrOverflow)
+	}
+	return balance, nil
+}
+
+// Complete reports whether every portfolio's total is fit to be a baseline, and
+// none was left out.
+func (b *UserBalance) Complete() bool {
+	if len(b.Failed) > 0 {
+		return false
+	}
+	for _, r := range b.Reports {
+		if !r.Complete() {
+			return false
+		}
+	}
+	return true
+}
+
+// Missing merges the holdings each portfolio left out of its total.
+func (b *UserBalance) Missing() map[string]error {
+	missing := make(map[string]error)
+	for _, r := range b.Reports {
+		for sym, err := range r.Missing {
+			missing[sym] = err
+		}
+	}
+	return missing
+}
+
+// FormatTotal returns the total line, or one line per portfolio and the combined
+// total if the user has several, in Markdown format. Portfolios left out are listed
+// after it.
+func (b *UserBalance) FormatTotal() string {
+	var sb strings.Builder
+	if len(b.Reports) == 1 && len(b.Failed) == 0 {
+		sb.WriteString(b.Reports[0].FormatTotal())
+	} else {
+		for _, r := range b.Reports {
+			fmt.Fprintf(&sb, "💼 %s: %s\n", EscapeMarkdown(r.Portfolio.Name), formatMoney(r.Total, r.Currency))
+		}
+		fmt.Fprintf(&sb, "💰 *Combined total: %s*", formatMoney(b.Total, b.Currency))
+	}
+
+	names := make([]string, 0, len(b.Failed))
+	for name := range b.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "\n⚠️ %s not included: it could not be valued right now", EscapeMarkdown(name))
+	}
+	return sb.String()
+}
+
+// FormatSummary is FormatTotal with a note when some prices are stale.
+func (b *UserBalance) FormatSummary() string {
+	text := b.FormatTotal()
+	for _, r := range b.Reports {
+		if r.HasStale() {
+			return text + "\n_(some prices are stale, see /p)_"
+		}
+	}
+	return text
+}
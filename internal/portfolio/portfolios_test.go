@@ -0,0 +1,49 @@
+package portfolio
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/money"
+)
+
+func TestFormatEscapesPortfolioNames(t *testing.T) {
+	const name = "Roth_IRA *[b]`"
+	const escaped = `Roth\_IRA \*\[b]` + "\\`"
+
+	report := func(name string) *BalanceReport {
+		return &BalanceReport{
+			Portfolio: db.Portfolio{Name: name},
+			Named:     true,
+			Currency:  "USD",
+			Total:     money.MustParse("100"),
+		}
+	}
+	balance := &UserBalance{
+		Reports:  []*BalanceReport{report(name), report("Main")},
+		Currency: "USD",
+		Total:    money.MustParse("200"),
+		Failed:   map[string]error{"*Old_one": errors.New("no prices")},
+	}
+
+	tests := []struct {
+		what string
+		text string
+		want []string
+	}{
+		{"report", report(name).Format(), []string{"Portfolio Balance — " + escaped + "*"}},
+		{"combined total", balance.FormatTotal(), []string{"💼 " + escaped + ":", `⚠️ \*Old\_one not included`}},
+	}
+	for _, tt := range tests {
+		for _, want := range tt.want {
+			if !strings.Contains(tt.text, want) {
+				t.Errorf("%s does not contain %q:\n%s", tt.what, want, tt.text)
+			}
+		}
+		if strings.Contains(tt.text, name) {
+			t.Errorf("%s has the raw name %q:\n%s", tt.what, name, tt.text)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"stock-portfolio-bot/internal/finance"
+)
+
+// settingExchanges is the user_settings key for the preferred exchange list.
+const settingExchanges = "exchanges"
+
+// SearchTickers looks up listings for a symbol, company name, ISIN or CUSIP and
+// ranks listings on the user's preferred exchanges first. An ISIN or CUSIP with a
+// bad check digit fails with finance.ErrInvalidIdentifier, and a CINS number with
+// finance.ErrUnsupportedIdentifier.
+func (s *Service) SearchTickers(ctx context.Context, chatID int64, query string) ([]finance.TickerResult, error) {
+	id, kind, err := finance.ParseIdentifier(query)
+	if err != nil {
+		return nil, err
+	}
+	queries := []string{query}
+	switch kind {
+	case finance.IdentISIN:
+		queries = []string{id}
+	case finance.IdentCUSIP:
+		// The search endpoint resolves ISINs but not CUSIPs; try each country in turn.
+		queries = finance.CUSIPToISINs(id)
+		if len(queries) == 0 {
+			return nil, fmt.Errorf("%w: CINS %s", finance.ErrUnsupportedIdentifier, id)
+		}
+	}
+
+	var results []finance.TickerResult
+	for _, q := range queries {
+		if results, err = s.provider.SearchTickers(ctx, q); err != nil {
+			return nil, err
+		}
+		if len(results) > 0 {
+			break
+		}
+	}
+
+	prefs, err := s.PreferredExchanges(chatID)
+	if err != nil {
+		log.Printf("SearchTickers: %v", err)
+	}
+	return rankByExchange(results, prefs), nil
+}
+
+// PreferredExchanges returns the user's preferred exchanges, most preferred first.
+func (s *Service) PreferredExchanges(chatID int64) ([]string, error) {
+	value, err := s.repo.GetUserSetting(chatID, settingExchanges)
+	if err != nil {
+		return nil, err
+	}
+	return ParseExchanges(value), nil
+}
+
+// SetPreferredExchanges stores the user's preferred exchanges; an empty list clears them.
+// Names are matched case-insensitively against Yahoo exchange codes ("GER") and
+// display names ("XETRA").
+func (s *Service) SetPreferredExchanges(chatID int64, exchanges []string) error {
+	return s.repo.SetUserSetting(chatID, settingExchanges, strings.Join(exchanges, ","))
+}
+
+// ParseExchanges splits a list such as "XETRA, LSE NASDAQ" into upper-case names.
+func ParseExchanges(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' '
+	})
+	var exchanges []string
+	seen := make(map[string]struct{})
+	for _, f := range fields {
+		ex := strings.ToUpper(f)
+		if _, dup := seen[ex]; dup {
+			continue
+		}
+		seen[ex] = struct{}{}
+		exchanges = append(exchanges, ex)
+	}
+	return exchanges
+}
+
+// rankByExchange returns a copy of results with listings on preferred exchanges moved
+// to the front in preference order; the provider's order is kept otherwise.
+func rankByExchange(results []finance.TickerResult, prefs []string) []finance.TickerResult {
+	ranked := append([]finance.TickerResult(nil), results...)
+	if len(prefs) == 0 {
+		return ranked
+	}
+
+	rank := func(r finance.TickerResult) int {
+		for i, p := range prefs {
+			if strings.EqualFold(p, r.Exchange) || strings.EqualFold(p, r.ExchangeName) {
+				return i
+			}
+		}
+		return len(prefs)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return rank(ranked[i]) < rank(ranked[j])
+	})
+	return ranked
+}
+
+// FormatExchanges describes the preferred exchange list for the /exchanges command.
+func FormatExchanges(exchanges []string) string {
+	if len(exchanges) == 0 {
+		return "No preferred exchanges set; search results keep Yahoo's order."
+	}
+	return fmt.Sprintf("Preferred exchanges: %s", strings.Join(exchanges, ", "))
+}
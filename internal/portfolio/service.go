@@ -2,130 +2,287 @@ package portfolio
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
 	"strings"
+	"time"
 
 	"stock-portfolio-bot/internal/db"
 	"stock-portfolio-bot/internal/finance"
+	"stock-portfolio-bot/internal/money"
 )
 
 // HoldingLine is one row in a balance report.
 type HoldingLine struct {
-	Symbol   string
-	Name     string
-	Shares   float64
-	Price    float64
-	Currency string
-	Value    float64
+	Symbol     string
+	Name       string
+	Type       string        // quote type, e.g. finance.TypeFuture
+	Shares     money.Decimal // contracts for futures
+	Multiplier float64       // units per contract; 1 for everything but futures
+	Price      float64
+	Currency   string
+	Rate       float64       // report currency per unit of Currency
+	Value      money.Decimal // in the report currency, rounded to its minor unit
+	AsOf       time.Time     // when Price was fetched
+	Stale      bool          // Price is a last known value because a refresh failed
+	Watch      bool          // watch-only (indices): shown for its price, not part of the total
+
+	// Today's move; HasDayChange is false when the provider gave no previous close.
+	HasDayChange bool
+	DayChange    money.Decimal // in the report currency
+	DayChangePct float64
+	MarketState  finance.MarketState
+
+	// Cost basis under the user's method; HasCost is false when part of it is unknown
+	// (opening balances without a price, unavailable historical rates).
+	HasCost    bool
+	Cost       money.Decimal // in the report currency
+	Unrealized money.Decimal // Value - Cost
 }
 
-// BalanceReport is the computed portfolio snapshot for a user.
+// BalanceReport is the computed snapshot of one portfolio.
 type BalanceReport struct {
+	Portfolio db.Portfolio
+	Named     bool // the user has several portfolios, so the title names this one
+
 	Holdings []HoldingLine
-	TotalUSD float64
+	Currency string // base currency Value, Total and Today are in
+	Total    money.Decimal
+	Today    money.Decimal    // sum of DayChange over holdings that report it
+	Missing  map[string]error // holdings left out of the total because no price was available
+
+	CostMethod string
+	Cost       money.Decimal // sum of Cost over holdings with HasCost
+	Unrealized money.Decimal // sum of Unrealized over holdings with HasCost
+	NoCost     int           // holdings left out of Cost and Unrealized
+
+	// Gains on every sale, closed positions included. RealizedPartial is set when
+	// some sales could not be valued and are left out.
+	HasRealized     bool
+	Realized        money.Decimal
+	RealizedPartial bool
 }
 
 // Format produces a Telegram-friendly Markdown message.
 func (r *BalanceReport) Format() string {
 	var sb strings.Builder
-	sb.WriteString("📊 *Portfolio Balance*\n\n")
+	if r.Named {
+		fmt.Fprintf(&sb, "📊 *Portfolio Balance — %s*\n\n", EscapeMarkdown(r.Portfolio.Name))
+	} else {
+		sb.WriteString("📊 *Portfolio Balance*\n\n")
+	}
 	for _, h := range r.Holdings {
+		currency := strings.ToUpper(strings.TrimSpace(h.Currency))
+		if h.Watch {
+			fmt.Fprintf(&sb, "👁 *%s* (%s)\n  %s", h.Symbol, h.Name, formatPrice(h.Price, currency))
+			if h.HasDayChange {
+				fmt.Fprintf(&sb, " · today %s", signedPct(h.DayChangePct))
+			}
+			sb.WriteString("\n")
+			if h.Stale {
+				fmt.Fprintf(&sb, "  _(price as of %s, stale)_\n", formatAsOf(h.AsOf))
+			}
+			continue
+		}
+
 		pct := 0.0
-		if r.TotalUSD > 0 {
-			pct = h.Value / r.TotalUSD * 100
+		if r.Total.Sign() > 0 {
+			pct = h.Value.Float64() / r.Total.Float64() * 100
 		}
-		currency := strings.ToUpper(strings.TrimSpace(h.Currency))
-		if currency == "" || currency == "USD" {
+		quantity := fmt.Sprintf("%s %s", h.Shares, finance.UnitLabel(h.Type))
+		if h.Multiplier != 1 {
+			quantity += fmt.Sprintf(" × %g", h.Multiplier)
+		}
+		if currency == "" || currency == r.Currency {
 			fmt.Fprintf(&sb,
-				"*%s* (%s)\n  %.4f shares × $%.2f = *$%.2f* (%.1f%%)\n",
-				h.Symbol, h.Name, h.Shares, h.Price, h.Value, pct,
+				"*%s* (%s)\n  %s × %s = *%s* (%.1f%%)\n",
+				h.Symbol, h.Name, quantity, formatPrice(h.Price, r.Currency), formatMoney(h.Value, r.Currency), pct,
+			)
+		} else {
+			fmt.Fprintf(&sb,
+				"*%s* (%s)\n  %s × %.2f %s (%s->%s) = *%s* (%.1f%%)\n",
+				h.Symbol, h.Name, quantity, h.Price, currency, currency, r.Currency, formatMoney(h.Value, r.Currency), pct,
 			)
-			continue
 		}
-
-		fmt.Fprintf(&sb,
-			"*%s* (%s)\n  %.4f shares × %.2f %s (%s->USD) = *$%.2f* (%.1f%%)\n",
-			h.Symbol, h.Name, h.Shares, h.Price, currency, currency, h.Value, pct,
-		)
+		if h.HasDayChange {
+			fmt.Fprintf(&sb, "  Today: %s (%s)", signedMoney(h.DayChange, r.Currency), signedPct(h.DayChangePct))
+			if h.MarketState != "" && h.MarketState != finance.MarketRegular {
+				fmt.Fprintf(&sb, " · %s", strings.ToLower(string(h.MarketState)))
+			}
+			sb.WriteString("\n")
+		}
+		if h.HasCost {
+			fmt.Fprintf(&sb, "  Cost: %s · P&L: %s", formatMoney(h.Cost, r.Currency), signedMoney(h.Unrealized, r.Currency))
+			if h.Cost.Sign() > 0 {
+				fmt.Fprintf(&sb, " (%s)", signedPct(h.Unrealized.Float64()/h.Cost.Float64()*100))
+			}
+			sb.WriteString("\n")
+		}
+		if h.Stale {
+			fmt.Fprintf(&sb, "  _(price as of %s, stale)_\n", formatAsOf(h.AsOf))
+		}
 	}
-	fmt.Fprintf(&sb, "\n💰 *Total: $%.2f*", r.TotalUSD)
+	if pct, ok := r.TodayPct(); ok {
+		fmt.Fprintf(&sb, "\n📅 *Today: %s (%s)*", signedMoney(r.Today, r.Currency), signedPct(pct))
+	}
+	if r.Cost.Sign() > 0 {
+		fmt.Fprintf(&sb, "\n📈 *Unrealized: %s (%s)*", signedMoney(r.Unrealized, r.Currency),
+			signedPct(r.Unrealized.Float64()/r.Cost.Float64()*100))
+		if r.NoCost > 0 {
+			fmt.Fprintf(&sb, "\n_(excludes %d holdings with unknown cost)_", r.NoCost)
+		}
+	}
+	if r.HasRealized {
+		fmt.Fprintf(&sb, "\n💵 *Realized: %s*", signedMoney(r.Realized, r.Currency))
+		if r.RealizedPartial {
+			sb.WriteString(" _(some sales have unknown cost)_")
+		}
+	}
+	sb.WriteString("\n" + r.FormatTotal())
 	return sb.String()
 }
 
-// FormatSummary returns only the total balance line in Markdown format.
-func (r *BalanceReport) FormatSummary() string {
-	return fmt.Sprintf("💰 *Total: $%.2f*", r.TotalUSD)
+// TodayPct returns the portfolio's move today relative to the previous close of the
+// holdings that report one. ok is false if none do.
+func (r *BalanceReport) TodayPct() (pct float64, ok bool) {
+	prev := money.Zero
+	for _, h := range r.Holdings {
+		if h.HasDayChange && !h.Watch {
+			prev = prev.Add(h.Value.Sub(h.DayChange))
+			ok = true
+		}
+	}
+	if !ok || prev.Sign() <= 0 {
+		return 0, false
+	}
+	return r.Today.Float64() / prev.Float64() * 100, true
 }
 
-// Service implements portfolio business logic.
-type Service struct {
-	repo  *db.Repository
-	yahoo *finance.YahooClient
-	rates *finance.ExchangeRateCache
-}
+// markdownEscaper backslash-escapes the characters Telegram's Markdown treats as markup.
+var markdownEscaper = strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[")
 
-// NewService creates a Service.
-func NewService(repo *db.Repository, yahoo *finance.YahooClient, rates *finance.ExchangeRateCache) *Service {
-	return &Service{repo: repo, yahoo: yahoo, rates: rates}
+// EscapeMarkdown makes user-entered text, such as a portfolio name, safe to put in a
+// Markdown message.
+func EscapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
 }
 
-// Repo exposes the repository (used by the scheduler).
-func (s *Service) Repo() *db.Repository { return s.repo }
-
-// GetQuotes delegates to the Yahoo client (used by the scheduler for cache pre-warming).
-func (s *Service) GetQuotes(ctx context.Context, symbols []string) (map[string]finance.Quote, error) {
-	return s.yahoo.GetQuotes(ctx, symbols)
+// formatPrice renders a price as "$1.23" for USD or "1.23 EUR" otherwise.
+func formatPrice(price float64, currency string) string {
+	if currency == "" || currency == "USD" {
+		return fmt.Sprintf("$%.2f", price)
+	}
+	return fmt.Sprintf("%.2f %s", price, currency)
 }
 
-// PrewarmUSDRates fetches and caches missing USD exchange rates for currencies.
-func (s *Service) PrewarmUSDRates(ctx context.Context, currencies []string) error {
-	if len(currencies) == 0 {
-		return nil
+// formatMoney renders an amount at its currency's minor unit: "$1.23", "1.23 EUR"
+// or "1235 JPY".
+func formatMoney(v money.Decimal, currency string) string {
+	amount := v.StringFixed(money.CurrencyPlaces(currency))
+	if currency == "" || currency == "USD" {
+		return "$" + amount
 	}
+	return amount + " " + currency
+}
 
-	normalized := make([]string, 0, len(currencies))
-	seen := make(map[string]struct{}, len(currencies))
-	for _, code := range currencies {
-		currency := strings.ToUpper(strings.TrimSpace(code))
-		if currency == "" || currency == "USD" {
-			continue
-		}
-		if _, ok := seen[currency]; ok {
-			continue
-		}
-		seen[currency] = struct{}{}
-		normalized = append(normalized, currency)
+// signedMoney formats v as "+$1.23" or "-1.23 EUR".
+func signedMoney(v money.Decimal, currency string) string {
+	if v.Sign() < 0 {
+		return "-" + formatMoney(v.Neg(), currency)
 	}
+	return "+" + formatMoney(v, currency)
+}
 
-	if len(normalized) == 0 {
-		return nil
+// signedPct formats v as "+1.23%" or "-1.23%".
+func signedPct(v float64) string {
+	if v < 0 {
+		return fmt.Sprintf("%.2f%%", v)
 	}
+	return fmt.Sprintf("+%.2f%%", v)
+}
+
+// FormatTotal returns the "💰 Total" line in Markdown format.
+func (r *BalanceReport) FormatTotal() string {
+	return fmt.Sprintf("💰 *Total: %s*", formatMoney(r.Total, r.Currency))
+}
 
-	missing := normalized
-	if s.rates != nil {
-		_, missing = s.rates.GetMulti(normalized)
+// FormatSummary returns only the total balance line in Markdown format.
+func (r *BalanceReport) FormatSummary() string {
+	text := r.FormatTotal()
+	if r.HasStale() {
+		text += "\n_(some prices are stale, see /p)_"
 	}
+	return text
+}
+
+// Complete reports whether every holding was valued with a fresh price, i.e. whether
+// the total is fit to be used as a performance baseline.
+func (r *BalanceReport) Complete() bool {
+	return len(r.Missing) == 0 && !r.HasStale()
+}
 
-	if len(missing) == 0 {
-		return nil
+// HasStale reports whether any holding in the total was valued with a stale price.
+func (r *BalanceReport) HasStale() bool {
+	for _, h := range r.Holdings {
+		if h.Stale && !h.Watch {
+			return true
+		}
 	}
+	return false
+}
 
-	fetchedRates, err := s.yahoo.GetUSDRates(ctx, missing)
-	if s.rates != nil && len(fetchedRates) > 0 {
-		s.rates.SetMulti(fetchedRates)
+// formatAsOf renders a fetch time as "14:05", or "Jan 2 14:05" if not from today.
+func formatAsOf(t time.Time) string {
+	if t.IsZero() {
+		return "unknown time"
 	}
-	if err != nil {
-		return fmt.Errorf("get USD rates: %w", err)
+	now := time.Now()
+	if y, m, d := t.Date(); y == now.Year() && m == now.Month() && d == now.Day() {
+		return t.Format("15:04")
 	}
+	return t.Format("Jan 2 15:04")
+}
 
-	return nil
+// Service implements portfolio business logic.
+type Service struct {
+	repo     *db.Repository
+	provider finance.QuoteProvider
+	rates    *finance.ExchangeRateCache
+}
+
+// NewService creates a Service that prices holdings through provider.
+func NewService(repo *db.Repository, provider finance.QuoteProvider, rates *finance.ExchangeRateCache) *Service {
+	return &Service{repo: repo, provider: provider, rates: rates}
 }
 
-// ComputeBalance fetches the latest prices and computes the total portfolio value for a user.
+// Repo exposes the repository (used by the scheduler).
+func (s *Service) Repo() *db.Repository { return s.repo }
+
+// GetQuotes delegates to the quote provider (used by the scheduler for cache pre-warming).
+func (s *Service) GetQuotes(ctx context.Context, symbols []string) (map[string]finance.Quote, error) {
+	return s.provider.GetQuotes(ctx, symbols)
+}
+
+// ComputeBalance values the user's active portfolio.
 func (s *Service) ComputeBalance(ctx context.Context, chatID int64) (*BalanceReport, error) {
-	holdings, err := s.repo.GetHoldings(chatID)
+	portfolios, err := s.Portfolios(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get portfolios: %w", err)
+	}
+	report, err := s.ComputePortfolioBalance(ctx, s.activeOf(chatID, portfolios))
+	if report != nil {
+		report.Named = len(portfolios) > 1
+	}
+	return report, err
+}
+
+// ComputePortfolioBalance fetches the latest prices and computes the total value of a
+// portfolio.
+func (s *Service) ComputePortfolioBalance(ctx context.Context, p db.Portfolio) (*BalanceReport, error) {
+	chatID := p.ChatID
+	holdings, err := s.repo.GetHoldings(p.ID)
 	if err != nil {
 		return nil, fmt.Errorf("get holdings: %w", err)
 	}
@@ -138,11 +295,17 @@ func (s *Service) ComputeBalance(ctx context.Context, chatID int64) (*BalanceRep
 		symbols[i] = h.Symbol
 	}
 
-	quotes, err := s.yahoo.GetQuotes(ctx, symbols)
+	quotes, err := s.provider.GetQuotes(ctx, symbols)
 	if err != nil {
-		return nil, fmt.Errorf("get quotes: %w", err)
+		if len(quotes) == 0 {
+			return nil, fmt.Errorf("get quotes: %w", err)
+		}
+		// Partial results: value what we have, the missing symbols are reported below.
+		log.Printf("ComputeBalance: get quotes (chatID %d): %v", chatID, err)
 	}
+	quoteErrs := symbolErrors(err)
 
+	base := s.baseCurrency(chatID, "ComputeBalance")
 	currencySet := make(map[string]struct{})
 	for _, h := range holdings {
 		q, ok := quotes[h.Symbol]
@@ -150,69 +313,124 @@ func (s *Service) ComputeBalance(ctx context.Context, chatID int64) (*BalanceRep
 			continue
 		}
 		currency := strings.ToUpper(strings.TrimSpace(q.Currency))
-		if currency == "" || currency == "USD" {
+		if currency == "" || currency == base {
 			continue
 		}
 		currencySet[currency] = struct{}{}
 	}
 
-	usdRates := map[string]float64{"USD": 1}
+	rates := map[string]float64{base: 1}
 	if len(currencySet) > 0 {
 		currencies := make([]string, 0, len(currencySet))
 		for currency := range currencySet {
 			currencies = append(currencies, currency)
 		}
 
-		missing := currencies
-		if s.rates != nil {
-			cachedRates, missingRates := s.rates.GetMulti(currencies)
-			for currency, rate := range cachedRates {
-				usdRates[currency] = rate
-			}
-			missing = missingRates
-		}
-
-		if len(missing) > 0 {
-			fetchedRates, rateErr := s.yahoo.GetUSDRates(ctx, missing)
-			for currency, rate := range fetchedRates {
-				usdRates[currency] = rate
-			}
-			if s.rates != nil && len(fetchedRates) > 0 {
-				s.rates.SetMulti(fetchedRates)
-			}
-			if rateErr != nil {
-				log.Printf("ComputeBalance: get USD rates for currencies %v (chatID %d): %v", missing, chatID, rateErr)
-			}
+		var rateErr error
+		rates, rateErr = s.conversionRates(ctx, currencies, base)
+		if rateErr != nil {
+			log.Printf("ComputeBalance: get %s rates for currencies %v (chatID %d): %v", base, currencies, chatID, rateErr)
 		}
 	}
 
-	report := &BalanceReport{Holdings: make([]HoldingLine, 0, len(holdings))}
+	report := &BalanceReport{
+		Portfolio:  p,
+		Holdings:   make([]HoldingLine, 0, len(holdings)),
+		Currency:   base,
+		Missing:    make(map[string]error),
+		CostMethod: s.costMethod(chatID, "ComputeBalance"),
+	}
+	costs := s.positionCosts(ctx, report, quotes)
 	missingConversionCurrencies := make(map[string]struct{})
 	for _, h := range holdings {
 		q, ok := quotes[h.Symbol]
 		if !ok {
 			log.Printf("ComputeBalance: no quote returned for symbol %s (chatID %d)", h.Symbol, chatID)
+			quoteErr, ok := quoteErrs[h.Symbol]
+			if !ok {
+				quoteErr = finance.ErrNoPrice
+			}
+			report.Missing[h.Symbol] = quoteErr
+			continue
+		}
+		quoteType := q.Type
+		if quoteType == "" {
+			quoteType = finance.GuessType(h.Symbol)
+		}
+		multiplier, ok := finance.ContractMultiplier(h.Symbol)
+		if !ok {
+			log.Printf("ComputeBalance: unknown contract size for %s, valuing per unit (chatID %d)", h.Symbol, chatID)
+		}
+		if finance.WatchOnly(quoteType) {
+			line := HoldingLine{
+				Symbol:      h.Symbol,
+				Name:        h.Name,
+				Type:        quoteType,
+				Multiplier:  1,
+				Price:       q.Price,
+				Currency:    q.Currency,
+				AsOf:        q.AsOf,
+				Stale:       q.Stale,
+				Watch:       true,
+				MarketState: q.MarketState,
+			}
+			if q.PreviousClose > 0 {
+				line.HasDayChange = true
+				line.DayChangePct = q.ChangePercent
+			}
+			report.Holdings = append(report.Holdings, line)
+			continue
+		}
+
+		units := h.Shares.MulFloat(multiplier)
+		value, ok := s.Convert(units.MulFloat(q.Price), q.Currency, rates)
+		if ok && value.Overflowed() {
+			log.Printf("ComputeBalance: value of %s out of range in %s (chatID %d)", h.Symbol, base, chatID)
+			report.Missing[h.Symbol] = money.ErrOverflow
 			continue
 		}
-		valueUSD, ok := s.ConvertToUSD(h.Shares*q.Price, q.Currency, usdRates)
 		if !ok {
 			currency := strings.ToUpper(strings.TrimSpace(q.Currency))
 			if currency == "" {
 				currency = "UNKNOWN"
 			}
 			missingConversionCurrencies[currency] = struct{}{}
-			log.Printf("ComputeBalance: no USD conversion rate for currency %s symbol %s (chatID %d)", currency, h.Symbol, chatID)
+			log.Printf("ComputeBalance: no %s conversion rate for currency %s symbol %s (chatID %d)", base, currency, h.Symbol, chatID)
 			continue
 		}
-		report.Holdings = append(report.Holdings, HoldingLine{
-			Symbol:   h.Symbol,
-			Name:     h.Name,
-			Shares:   h.Shares,
-			Price:    q.Price,
-			Currency: q.Currency,
-			Value:    valueUSD,
-		})
-		report.TotalUSD += valueUSD
+		line := HoldingLine{
+			Symbol:      h.Symbol,
+			Name:        h.Name,
+			Type:        quoteType,
+			Shares:      h.Shares,
+			Multiplier:  multiplier,
+			Price:       q.Price,
+			Currency:    q.Currency,
+			Rate:        rates[finance.NormalizeCurrency(q.Currency)],
+			Value:       value.RoundCurrency(base),
+			AsOf:        q.AsOf,
+			Stale:       q.Stale,
+			MarketState: q.MarketState,
+		}
+		if q.PreviousClose > 0 {
+			if change, ok := s.Convert(units.MulFloat(q.Change), q.Currency, rates); ok {
+				line.HasDayChange = true
+				line.DayChange = change.RoundCurrency(base)
+				line.DayChangePct = q.ChangePercent
+				report.Today = report.Today.Add(line.DayChange)
+			}
+		}
+		if pc, ok := costs[h.Symbol]; ok && pc.CostKnown && !pc.Cost.Overflowed() {
+			line.HasCost = true
+			line.Cost = pc.Cost
+			line.Unrealized = line.Value.Sub(pc.Cost)
+			report.Cost = report.Cost.Add(line.Cost)
+			report.Unrealized = report.Unrealized.Add(line.Unrealized)
+		} else {
+			report.NoCost++
+		}
+		report.Holdings = append(report.Holdings, line)
+		report.Total = report.Total.Add(line.Value)
 	}
 	if len(missingConversionCurrencies) > 0 {
 		currencies := make([]string, 0, len(missingConversionCurrencies))
@@ -220,55 +438,103 @@ func (s *Service) ComputeBalance(ctx context.Context, chatID int64) (*BalanceRep
 			currencies = append(currencies, currency)
 		}
 		sort.Strings(currencies)
-		return nil, fmt.Errorf("missing USD conversion rates for currencies %v", currencies)
+		return nil, fmt.Errorf("missing %s conversion rates for currencies %v", base, currencies)
+	}
+	if report.Total.Overflowed() {
+		return nil, fmt.Errorf("portfolio total: %w", money.ErrOverflow)
 	}
 	if len(report.Holdings) == 0 {
-		return nil, fmt.Errorf("quotes returned no data for symbols %v", symbols)
+		return nil, fmt.Errorf("quotes returned no data: %w", &finance.BatchError{Failures: report.Missing})
 	}
 	return report, nil
 }
 
-// ResetBaseline computes the current portfolio balance, saves it as a new baseline
-// for future performance comparisons, and returns the report along with the previous
-// baseline value. This should be called after portfolio composition changes (adding/removing stocks)
-// to ensure subsequent scheduler reports only reflect true performance changes.
-// Returns (nil, 0, nil) if the portfolio is empty.
-func (s *Service) ResetBaseline(ctx context.Context, chatID int64) (*BalanceReport, float64, error) {
-	report, err := s.ComputeBalance(ctx, chatID)
+// Snapshots returns the holdings that make up Total, for the history table.
+func (r *BalanceReport) Snapshots() []db.HoldingSnapshot {
+	snapshots := make([]db.HoldingSnapshot, 0, len(r.Holdings))
+	for _, h := range r.Holdings {
+		if h.Watch {
+			continue
+		}
+		snapshots = append(snapshots, db.HoldingSnapshot{
+			Symbol:     h.Symbol,
+			Shares:     h.Shares,
+			Multiplier: h.Multiplier,
+			Price:      h.Price,
+			Currency:   h.Currency,
+			Rate:       h.Rate,
+			Value:      h.Value,
+		})
+	}
+	return snapshots
+}
+
+// symbolErrors extracts per-symbol failures from a GetQuotes error.
+func symbolErrors(err error) map[string]error {
+	failures := make(map[string]error)
+	var batchErr *finance.BatchError
+	if errors.As(err, &batchErr) {
+		for sym, symErr := range batchErr.Failures {
+			failures[sym] = symErr
+		}
+	}
+	return failures
+}
+
+// ResetBaseline computes the current balance of every portfolio of the user, saves
+// each as a new baseline for future performance comparisons, and returns them along
+// with the previous combined baseline. This should be called after portfolio
+// composition changes (adding/removing stocks) to ensure subsequent scheduler reports
+// only reflect true performance changes.
+// Returns (nil, zero, nil) if every portfolio is empty.
+func (s *Service) ResetBaseline(ctx context.Context, chatID int64) (*UserBalance, money.Decimal, error) {
+	balance, err := s.ComputeUserBalance(ctx, chatID)
 	if err != nil {
-		return nil, 0, fmt.Errorf("compute balance: %w", err)
+		return nil, money.Zero, fmt.Errorf("compute balance: %w", err)
 	}
-	if report == nil || len(report.Holdings) == 0 {
-		return nil, 0, nil
+	if balance == nil {
+		return nil, money.Zero, nil
 	}
 
-	prevTotal, err := s.repo.GetLastReport(chatID)
+	prevTotal, err := s.LastTotal(balance)
 	if err != nil {
-		return nil, 0, fmt.Errorf("get last report: %w", err)
+		return nil, money.Zero, fmt.Errorf("get last report: %w", err)
 	}
 
-	if err := s.repo.SaveReport(chatID, report.TotalUSD); err != nil {
-		return nil, 0, fmt.Errorf("save report: %w", err)
+	if !balance.Complete() {
+		// A partial total would make the next scheduler report look like a jump.
+		log.Printf("ResetBaseline: keep previous baseline for %d (incomplete prices)", chatID)
+		return balance, prevTotal, nil
 	}
 
-	return report, prevTotal, nil
+	if err := s.SaveBaseline(balance); err != nil {
+		return nil, money.Zero, err
+	}
+	return balance, prevTotal, nil
 }
 
-// ConvertToUSD converts a value from the quote currency to USD.
-// usdRates map values are USD->currency conversions.
-func (s *Service) ConvertToUSD(value float64, currency string, usdRates map[string]float64) (float64, bool) {
-	normalizedCurrency := strings.ToUpper(strings.TrimSpace(currency))
-	if normalizedCurrency == "USD" {
-		return value, true
-	}
-	if normalizedCurrency == "" {
-		return 0, false
+// LastTotal returns the combined baseline of the portfolios in balance: the sum of
+// each one's last saved total. Portfolios that have since been emptied are left out,
+// like the positions closed in the others.
+func (s *Service) LastTotal(balance *UserBalance) (money.Decimal, error) {
+	total := money.Zero
+	for _, r := range balance.Reports {
+		last, err := s.repo.GetLastReport(r.Portfolio.ID)
+		if err != nil {
+			return money.Zero, err
+		}
+		total = total.Add(last)
 	}
+	return total, nil
+}
 
-	rate, ok := usdRates[normalizedCurrency]
-	if !ok || rate <= 0 {
-		return 0, false
+// SaveBaseline records each portfolio's total and its per-holding breakdown in the
+// history table.
+func (s *Service) SaveBaseline(balance *UserBalance) error {
+	for _, r := range balance.Reports {
+		if err := s.repo.SaveReport(r.Portfolio.ID, r.Total, r.Snapshots()); err != nil {
+			return fmt.Errorf("save report %d: %w", r.Portfolio.ID, err)
+		}
 	}
-
-	return value / rate, true
+	return nil
 }
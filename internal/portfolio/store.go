@@ -0,0 +1,90 @@
+package portfolio
+
+import (
+	"fmt"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/finance"
+)
+
+// CacheStore persists quotes and FX rates written to the finance caches in the
+// quotes table, and loads them back on startup.
+type CacheStore struct {
+	repo *db.Repository
+}
+
+// NewCacheStore creates a CacheStore backed by repo.
+func NewCacheStore(repo *db.Repository) *CacheStore {
+	return &CacheStore{repo: repo}
+}
+
+// SaveQuotes implements finance.CacheStore.
+func (cs *CacheStore) SaveQuotes(quotes []finance.Quote) error {
+	records := make([]db.QuoteRecord, len(quotes))
+	for i, q := range quotes {
+		records[i] = db.QuoteRecord{
+			Symbol:    q.Symbol,
+			Price:     q.Price,
+			Currency:  q.Currency,
+			Source:    q.Source,
+			FetchedAt: q.AsOf,
+		}
+	}
+	return cs.repo.SaveQuotes(records)
+}
+
+// Restore loads the latest persisted quote per symbol into the price and rate caches.
+func (cs *CacheStore) Restore(prices *finance.PriceCache, rates *finance.ExchangeRateCache) (int, error) {
+	records, err := cs.repo.GetLatestQuotes()
+	if err != nil {
+		return 0, fmt.Errorf("load latest quotes: %w", err)
+	}
+
+	quotes := make([]finance.Quote, len(records))
+	for i, r := range records {
+		quotes[i] = finance.Quote{
+			Symbol:   r.Symbol,
+			Price:    r.Price,
+			Currency: r.Currency,
+			Source:   r.Source,
+			AsOf:     r.FetchedAt,
+		}
+	}
+	prices.Restore(quotes)
+	rates.Restore(quotes)
+	return len(quotes), nil
+}
+
+// SessionStore persists the Yahoo session in the yahoo_session table.
+type SessionStore struct {
+	repo *db.Repository
+}
+
+// NewSessionStore creates a SessionStore backed by repo.
+func NewSessionStore(repo *db.Repository) *SessionStore {
+	return &SessionStore{repo: repo}
+}
+
+// LoadSession implements finance.SessionStore.
+func (ss *SessionStore) LoadSession() (*finance.StoredSession, error) {
+	s, err := ss.repo.GetYahooSession()
+	if err != nil || s == nil {
+		return nil, err
+	}
+	return &finance.StoredSession{
+		Cookie:    s.Cookie,
+		Crumb:     s.Crumb,
+		CreatedAt: s.CreatedAt,
+		ExpiresAt: s.ExpiresAt,
+	}, nil
+}
+
+// SaveSession implements finance.SessionStore.
+func (ss *SessionStore) SaveSession(s finance.StoredSession) error {
+	return ss.repo.SaveYahooSession(db.YahooSession{
+		Cookie:    s.Cookie,
+		Crumb:     s.Crumb,
+		CreatedAt: s.CreatedAt,
+		ExpiresAt: s.ExpiresAt,
+	})
+}
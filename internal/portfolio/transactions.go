@@ -0,0 +1,316 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/finance"
+	"stock-portfolio-bot/internal/money"
+)
+
+// Trade input limits. They keep a trade's amounts well inside money.Decimal's range,
+// so valuing it, possibly in a currency with many units to the dollar, stays exact.
+var (
+	maxTradeQuantity = money.FromInt(1_000_000_000)
+	maxTradeAmount   = money.FromInt(1_000_000_000) // quantity × price, and fees
+)
+
+var (
+	// ErrInvalidTrade is returned by ParseTrade for input it cannot read.
+	ErrInvalidTrade = errors.New("invalid trade")
+
+	// ErrPriceRequired is returned by RecordTrade for a past trade entered without a
+	// price; only today's trades can default to the current quote.
+	ErrPriceRequired = errors.New("price required for a past trade")
+
+	// ErrStaleQuote is returned by RecordTrade for a trade entered without a price when
+	// only an out-of-date quote is available to default to.
+	ErrStaleQuote = errors.New("only a stale quote is available")
+
+	// ErrTradeBeforeSplit is returned by RecordTrade for a trade dated before a split
+	// that is too old to be applied to the position, so its quantity would never be
+	// scaled.
+	ErrTradeBeforeSplit = errors.New("trade dated before a split")
+
+	// ErrHoldingNotFound is returned by ClosePosition for a position the portfolio
+	// does not hold.
+	ErrHoldingNotFound = errors.New("holding not found")
+)
+
+// Trade is a buy or sell as entered by the user.
+type Trade struct {
+	Kind     string        // db.TxBuy or db.TxSell
+	Quantity money.Decimal // positive
+	Price    *money.Decimal
+	Date     time.Time // calendar day of the trade, midnight UTC
+	Fees     money.Decimal
+}
+
+// ParseTrade reads a trade such as "10", "10 @ 152.30", "sell 5 @ 160 on 2024-03-01"
+// or "2.5 @ 98 fee 1". It defaults to a buy today at no stated price.
+func ParseTrade(text string, now time.Time) (Trade, error) {
+	trade := Trade{Kind: db.TxBuy, Date: finance.CalendarDay(now)}
+	fields := strings.Fields(strings.ToLower(strings.ReplaceAll(text, "@", " @ ")))
+	if len(fields) > 0 && (fields[0] == db.TxBuy || fields[0] == db.TxSell) {
+		trade.Kind, fields = fields[0], fields[1:]
+	}
+	if len(fields) == 0 {
+		return Trade{}, fmt.Errorf("%w: missing quantity", ErrInvalidTrade)
+	}
+
+	quantity, err := money.Parse(fields[0])
+	if err != nil || quantity.Sign() <= 0 || quantity.Cmp(maxTradeQuantity) > 0 {
+		return Trade{}, fmt.Errorf("%w: quantity %q", ErrInvalidTrade, fields[0])
+	}
+	trade.Quantity = quantity
+
+	for rest := fields[1:]; len(rest) > 0; rest = rest[2:] {
+		if len(rest) < 2 {
+			return Trade{}, fmt.Errorf("%w: %q needs a value", ErrInvalidTrade, rest[0])
+		}
+		keyword, value := rest[0], rest[1]
+		switch keyword {
+		case "@", "at":
+			price, err := money.Parse(value)
+			if err != nil || price.Sign() <= 0 || price.Cmp(maxTradeAmount) > 0 {
+				return Trade{}, fmt.Errorf("%w: price %q", ErrInvalidTrade, value)
+			}
+			trade.Price = &price
+		case "on":
+			date, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return Trade{}, fmt.Errorf("%w: date %q", ErrInvalidTrade, value)
+			}
+			if date.After(trade.Date) {
+				return Trade{}, fmt.Errorf("%w: date %s is in the future", ErrInvalidTrade, value)
+			}
+			trade.Date = date
+		case "fee", "fees":
+			fees, err := money.Parse(value)
+			if err != nil || fees.Sign() < 0 || fees.Cmp(maxTradeAmount) > 0 {
+				return Trade{}, fmt.Errorf("%w: fees %q", ErrInvalidTrade, value)
+			}
+			trade.Fees = fees
+		default:
+			return Trade{}, fmt.Errorf("%w: unexpected %q", ErrInvalidTrade, keyword)
+		}
+	}
+	if trade.Price != nil && trade.Quantity.Mul(*trade.Price).Cmp(maxTradeAmount) > 0 {
+		return Trade{}, fmt.Errorf("%w: %s × %s is too large", ErrInvalidTrade, trade.Quantity, *trade.Price)
+	}
+	return trade, nil
+}
+
+// TradeResult is a recorded trade and the position it left.
+type TradeResult struct {
+	Transaction db.Transaction
+	Shares      money.Decimal // held after the trade
+
+	// For sales, the realized gain in Currency under the user's cost basis method;
+	// HasRealized is false if the cost of the shares sold is unknown.
+	HasRealized bool
+	Realized    money.Decimal
+	Currency    string
+}
+
+// RecordTrade adds trade to the ledger of the position in symbol in the user's active
+// portfolio. A trade dated today without a price is recorded at the current quote, if
+// it is fresh; the quote also supplies the currency the price is in.
+func (s *Service) RecordTrade(ctx context.Context, chatID int64, symbol, name string, trade Trade) (*TradeResult, error) {
+	p, err := s.ActivePortfolio(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("get active portfolio: %w", err)
+	}
+	return s.recordTrade(ctx, chatID, p.ID, symbol, name, trade)
+}
+
+// ClosePosition sells every share of symbol in one of the user's portfolios at the
+// current quote. Unlike deleting the holding, it keeps the position's ledger, and with
+// it the gains realized on earlier sales. It returns the sale and the holding as it
+// was before.
+func (s *Service) ClosePosition(ctx context.Context, chatID, portfolioID int64, symbol string) (*TradeResult, db.Holding, error) {
+	holdings, err := s.repo.GetHoldings(portfolioID)
+	if err != nil {
+		return nil, db.Holding{}, fmt.Errorf("get holdings: %w", err)
+	}
+	for _, h := range holdings {
+		if h.Symbol != symbol || h.ChatID != chatID {
+			continue
+		}
+		if h.Shares.Sign() <= 0 {
+			break // watch-only: nothing to sell
+		}
+		trade := Trade{Kind: db.TxSell, Quantity: h.Shares, Date: finance.CalendarDay(time.Now())}
+		result, err := s.recordTrade(ctx, chatID, portfolioID, symbol, h.Name, trade)
+		return result, h, err
+	}
+	return nil, db.Holding{}, fmt.Errorf("%w: %s", ErrHoldingNotFound, symbol)
+}
+
+func (s *Service) recordTrade(ctx context.Context, chatID, portfolioID int64, symbol, name string, trade Trade) (*TradeResult, error) {
+	today := trade.Date.Equal(finance.CalendarDay(time.Now()))
+	if trade.Price == nil && !today {
+		return nil, ErrPriceRequired
+	}
+
+	t := db.Transaction{
+		ChatID:      chatID,
+		PortfolioID: portfolioID,
+		Symbol:      symbol,
+		Kind:        trade.Kind,
+		Date:        trade.Date,
+		Quantity:    trade.Quantity,
+		Price:       trade.Price,
+		Fees:        trade.Fees,
+	}
+	if today {
+		// Time of entry rather than midnight, so same-day trades keep their order.
+		t.Date = time.Now().UTC()
+	}
+	if trade.Kind == db.TxSell {
+		t.Quantity = trade.Quantity.Neg()
+	}
+
+	quotes, err := s.provider.GetQuotes(ctx, []string{symbol})
+	q, ok := quotes[symbol]
+	switch {
+	case ok:
+		t.Currency = q.Currency
+		if t.Price == nil {
+			if q.Stale {
+				return nil, fmt.Errorf("%w: %s as of %s", ErrStaleQuote, symbol, q.AsOf.Format(time.RFC3339))
+			}
+			price := money.FromFloat(q.Price)
+			if trade.Quantity.Mul(price).Cmp(maxTradeAmount) > 0 {
+				return nil, fmt.Errorf("%w: %s × %s is too large", ErrInvalidTrade, trade.Quantity, price)
+			}
+			t.Price = &price
+		}
+	case t.Price == nil:
+		if err == nil {
+			err = finance.ErrNoPrice
+		}
+		return nil, fmt.Errorf("get quote %s: %w", symbol, err)
+	default:
+		// The stated price stands; the currency stays unknown.
+		log.Printf("RecordTrade: get quote %s for currency (chatID %d): %v", symbol, chatID, err)
+	}
+
+	if err := s.checkSplitsAfter(portfolioID, symbol, t.Date); err != nil {
+		return nil, err
+	}
+
+	id, shares, err := s.repo.RecordTransaction(t, name)
+	if err != nil {
+		return nil, fmt.Errorf("record transaction: %w", err)
+	}
+	t.ID = id
+	result := &TradeResult{Transaction: t, Shares: shares}
+	if t.Kind == db.TxSell {
+		s.realizeSale(ctx, chatID, result, q.Currency)
+	}
+	return result, nil
+}
+
+// checkSplitsAfter rejects a trade dated before a split of symbol that is older than
+// ApplySplits looks back and missing from the position's ledger. Splits in the ledger
+// are re-derived when the trade is recorded, and recent ones are applied by the next
+// ApplySplits, but this one would leave the trade's quantity unscaled for good.
+func (s *Service) checkSplitsAfter(portfolioID int64, symbol string, date time.Time) error {
+	splits, err := s.repo.GetSplits(symbol, date)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-splitLookback)
+
+	var ledger []db.Transaction
+	for _, sp := range splits {
+		if !sp.Date.After(date) || !sp.Date.Before(cutoff) {
+			continue
+		}
+		if ledger == nil {
+			if ledger, err = s.repo.GetTransactions(portfolioID, symbol); err != nil {
+				return err
+			}
+		}
+		applied := false
+		for _, t := range ledger {
+			applied = applied || (t.Kind == db.TxSplit && t.Date.Equal(sp.Date))
+		}
+		if !applied {
+			return fmt.Errorf("%w: %s split %g:%g on %s", ErrTradeBeforeSplit,
+				symbol, sp.Numerator, sp.Denominator, sp.Date.Format("2006-01-02"))
+		}
+	}
+	return nil
+}
+
+// realizeSale fills in the realized gain of the sale in result. Failures only leave it
+// out of the confirmation, so they are logged here.
+func (s *Service) realizeSale(ctx context.Context, chatID int64, result *TradeResult, quoteCurrency string) {
+	t := result.Transaction
+	txs, err := s.repo.GetTransactions(t.PortfolioID, t.Symbol)
+	if err != nil {
+		log.Printf("RecordTrade: get transactions %s (chatID %d): %v", t.Symbol, chatID, err)
+		return
+	}
+	base := s.baseCurrency(chatID, "RecordTrade")
+	method := s.costMethod(chatID, "RecordTrade")
+	multiplier, _ := finance.ContractMultiplier(t.Symbol)
+
+	pc := positionCost(txs, method, base, quoteCurrency, multiplier, s.dayRates(ctx, base))
+	if gain, ok := pc.Sales[t.ID]; ok {
+		result.HasRealized = true
+		result.Realized = gain
+		result.Currency = base
+	}
+}
+
+// Format describes the recorded trade and the resulting position.
+func (r *TradeResult) Format(name, quoteType string) string {
+	t := r.Transaction
+	verb := "Bought"
+	if t.Kind == db.TxSell {
+		verb = "Sold"
+	}
+	unit := finance.UnitLabel(quoteType)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "✅ %s %s %s of %s (%s)", verb, t.Quantity.Abs(), unit, t.Symbol, name)
+	if t.Price != nil {
+		fmt.Fprintf(&sb, " at %s", formatUnitPrice(*t.Price, t.Currency))
+	}
+	fmt.Fprintf(&sb, " on %s", t.Date.Format("Jan 2, 2006"))
+	if t.Fees.Sign() > 0 {
+		fmt.Fprintf(&sb, ", fees %s", formatMoney(t.Fees, t.Currency))
+	}
+	sb.WriteString(".\n")
+	if r.HasRealized {
+		fmt.Fprintf(&sb, "Realized gain: %s.\n", signedMoney(r.Realized, r.Currency))
+	}
+	if r.Shares.IsZero() {
+		fmt.Fprintf(&sb, "Position in %s closed.", t.Symbol)
+	} else {
+		fmt.Fprintf(&sb, "You now hold %s %s.", r.Shares, unit)
+	}
+	return sb.String()
+}
+
+// formatUnitPrice formats a per-unit price with at least the currency's minor-unit
+// digits, keeping any extra precision the user entered.
+func formatUnitPrice(price money.Decimal, currency string) string {
+	places := money.CurrencyPlaces(currency)
+	text := price.String()
+	if _, frac, ok := strings.Cut(text, "."); !ok || len(frac) < places {
+		text = price.StringFixed(places)
+	}
+	if currency == "" || currency == "USD" {
+		return "$" + text
+	}
+	return text + " " + currency
+}
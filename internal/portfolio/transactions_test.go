@@ -0,0 +1,81 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stock-portfolio-bot/internal/db"
+	"stock-portfolio-bot/internal/finance"
+	"stock-portfolio-bot/internal/money"
+)
+
+// quoteProvider answers GetQuotes from quotes and supports nothing else.
+type quoteProvider map[string]finance.Quote
+
+func (p quoteProvider) Name() string { return "fake" }
+
+func (p quoteProvider) SearchTickers(context.Context, string) ([]finance.TickerResult, error) {
+	return nil, finance.ErrUnsupported
+}
+
+func (p quoteProvider) GetQuotes(_ context.Context, symbols []string) (map[string]finance.Quote, error) {
+	got := make(map[string]finance.Quote)
+	for _, sym := range symbols {
+		if q, ok := p[sym]; ok {
+			got[sym] = q
+		}
+	}
+	return got, nil
+}
+
+func (p quoteProvider) GetRates(context.Context, []finance.CurrencyPair) (map[finance.CurrencyPair]float64, error) {
+	return nil, finance.ErrUnsupported
+}
+
+// newTestService returns a Service over a fresh in-memory database holding user 1.
+func newTestService(t *testing.T, provider finance.QuoteProvider) *Service {
+	t.Helper()
+	database, err := db.New(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	repo := db.NewRepository(database)
+	if err := repo.UpsertUser(1, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(repo, provider, finance.NewExchangeRateCache(time.Minute))
+}
+
+func TestRecordTradeAtQuote(t *testing.T) {
+	svc := newTestService(t, quoteProvider{
+		"AAPL": {Symbol: "AAPL", Price: 190, Currency: "USD"},
+		"SAP":  {Symbol: "SAP", Price: 170, Currency: "EUR", Stale: true, AsOf: time.Now().Add(-48 * time.Hour)},
+	})
+	today := finance.CalendarDay(time.Now())
+	buy := func(price string) Trade {
+		trade := Trade{Kind: db.TxBuy, Quantity: money.MustParse("10"), Date: today}
+		if price != "" {
+			p := money.MustParse(price)
+			trade.Price = &p
+		}
+		return trade
+	}
+
+	result, err := svc.RecordTrade(context.Background(), 1, "AAPL", "Apple", buy(""))
+	if err != nil {
+		t.Fatalf("unpriced trade at a fresh quote: %v", err)
+	}
+	if result.Transaction.Price.String() != "190" {
+		t.Errorf("recorded at %s, want the quote's 190", result.Transaction.Price)
+	}
+
+	if _, err := svc.RecordTrade(context.Background(), 1, "SAP", "SAP", buy("")); !errors.Is(err, ErrStaleQuote) {
+		t.Errorf("unpriced trade at a stale quote: error = %v, want ErrStaleQuote", err)
+	}
+	if _, err := svc.RecordTrade(context.Background(), 1, "SAP", "SAP", buy("168")); err != nil {
+		t.Errorf("priced trade with a stale quote: %v", err)
+	}
+}
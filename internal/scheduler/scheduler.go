@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"runtime/debug"
 	"strings"
 	"time"
 
 	"stock-portfolio-bot/internal/portfolio"
 )
 
+// eventSyncInterval is how often dividends, splits and sector data are re-fetched for held symbols.
+const eventSyncInterval = 24 * time.Hour
+
 // changeThreshold is the minimum percentage change (as a decimal) required
 // to trigger a notification. 0.0005 = 0.05%.
 const changeThreshold = 0.0005
@@ -26,6 +30,8 @@ type Scheduler struct {
 	svc      *portfolio.Service
 	notifier Notifier
 	interval time.Duration
+
+	lastEventSync time.Time
 }
 
 // New creates a Scheduler.
@@ -71,7 +77,7 @@ func (s *Scheduler) notifyAll(ctx context.Context) {
 	currencySet := make(map[string]struct{})
 	for _, q := range quotes {
 		currency := strings.ToUpper(strings.TrimSpace(q.Currency))
-		if currency == "" || currency == "USD" {
+		if currency == "" {
 			continue
 		}
 		currencySet[currency] = struct{}{}
@@ -82,13 +88,27 @@ func (s *Scheduler) notifyAll(ctx context.Context) {
 		for currency := range currencySet {
 			currencies = append(currencies, currency)
 		}
-		if err := s.svc.PrewarmUSDRates(ctx, currencies); err != nil {
-			log.Printf("scheduler: pre-warm USD rates: %v", err)
+		if err := s.svc.PrewarmRates(ctx, currencies); err != nil {
+			log.Printf("scheduler: pre-warm FX rates: %v", err)
 			// Continue anyway — per-user computations will retry.
 		}
 	}
 
-	// 3. Notify each active user (balance reads from cache → instant).
+	// 3. Refresh dividend and split events and sector data once a day.
+	if time.Since(s.lastEventSync) >= eventSyncInterval {
+		if _, err := s.svc.SyncEvents(ctx, symbols); err != nil {
+			log.Printf("scheduler: sync events: %v", err)
+		}
+		if err := s.svc.SyncSymbolInfo(ctx, symbols); err != nil {
+			log.Printf("scheduler: sync symbol info: %v", err)
+		}
+		s.lastEventSync = time.Now()
+	}
+
+	// 4. Adjust shares for splits before comparing against the last report.
+	s.applySplits(ctx)
+
+	// 5. Notify each active user (balance reads from cache → instant).
 	users, err := repo.GetAllActiveUsers()
 	if err != nil {
 		log.Printf("scheduler: get active users: %v", err)
@@ -96,47 +116,110 @@ func (s *Scheduler) notifyAll(ctx context.Context) {
 	}
 
 	for _, chatID := range users {
-		report, err := s.svc.ComputeBalance(ctx, chatID)
-		if err != nil {
-			log.Printf("scheduler: compute balance %d: %v", chatID, err)
-			continue
-		}
-		if report == nil || len(report.Holdings) == 0 {
-			continue
+		s.notifyUser(ctx, chatID)
+	}
+
+	// 6. Push new headlines to users who opted in.
+	s.pushNews(ctx)
+}
+
+// notifyUser sends one user their balance if it moved enough since the last report,
+// and saves it as the new baseline.
+func (s *Scheduler) notifyUser(ctx context.Context, chatID int64) {
+	// One user's bad data must not stop the round for everyone else.
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("scheduler: panic notifying %d: %v\n%s", chatID, r, debug.Stack())
 		}
+	}()
 
-		// Fetch previous report to detect changes.
-		prev, err := repo.GetLastReport(chatID)
-		if err != nil {
-			log.Printf("scheduler: get last report %d: %v", chatID, err)
+	balance, err := s.svc.ComputeUserBalance(ctx, chatID)
+	if err != nil {
+		log.Printf("scheduler: compute balance %d: %v", chatID, err)
+		return
+	}
+	if balance == nil || balance.Total.IsZero() {
+		return // empty or watch-only portfolios
+	}
+	if !balance.Complete() {
+		// Don't push or record a baseline built from stale or missing prices.
+		log.Printf("scheduler: skip report for %d (stale or missing prices)", chatID)
+		return
+	}
+
+	// Fetch the previous reports to detect changes.
+	prev, err := s.svc.LastTotal(balance)
+	if err != nil {
+		log.Printf("scheduler: get last report %d: %v", chatID, err)
+	}
+
+	// Skip sending and saving if the change is below threshold.
+	if prev.Sign() > 0 {
+		changeRatio := math.Abs(balance.Total.Sub(prev).Float64()) / prev.Float64()
+		if changeRatio < changeThreshold {
+			log.Printf("scheduler: skip report for %d (change %.4f%% < threshold %.2f%%)",
+				chatID, changeRatio*100, changeThreshold*100)
+			return
 		}
+	}
+
+	text := balance.FormatSummary()
 
-		// Skip sending and saving if the change is below threshold.
-		if prev > 0 {
-			changeRatio := math.Abs(report.TotalUSD-prev) / prev
-			if changeRatio < changeThreshold {
-				log.Printf("scheduler: skip report for %d (change %.4f%% < threshold %.2f%%)",
-					chatID, changeRatio*100, changeThreshold*100)
-				continue
-			}
+	// Append % change vs previous report if one exists.
+	if prev.Sign() > 0 {
+		change := balance.Total.Sub(prev).Float64() / prev.Float64() * 100
+		sign := "+"
+		if change < 0 {
+			sign = ""
 		}
+		text += fmt.Sprintf("\n📈 *Change since last report: %s%.2f%%*", sign, change)
+	}
 
-		text := report.FormatSummary()
+	s.notifier.SendMarkdown(chatID, text)
 
-		// Append % change vs previous report if one exists.
-		if prev > 0 {
-			change := (report.TotalUSD - prev) / prev * 100
-			sign := "+"
-			if change < 0 {
-				sign = ""
-			}
-			text += fmt.Sprintf("\n📈 *Change since last report: %s%.2f%%*", sign, change)
+	if err := s.svc.SaveBaseline(balance); err != nil {
+		log.Printf("scheduler: save report %d: %v", chatID, err)
+	}
+}
+
+// pushNews sends each news subscriber the headlines for their holdings they have not seen.
+func (s *Scheduler) pushNews(ctx context.Context) {
+	digests, err := s.svc.PendingNews(ctx)
+	if err != nil {
+		log.Printf("scheduler: pending news: %v", err)
+		// Digests built before the error are still sent.
+	}
+	for _, digest := range digests {
+		s.notifier.SendMarkdown(digest.ChatID, digest.Format())
+		if err := s.svc.MarkNewsSent(digest); err != nil {
+			log.Printf("scheduler: mark news sent %d: %v", digest.ChatID, err)
 		}
+	}
+}
 
-		s.notifier.SendMarkdown(chatID, text)
+// applySplits scales holdings for new splits, tells each affected user and resets
+// their baseline so the split is not reported as a gain or loss.
+func (s *Scheduler) applySplits(ctx context.Context) {
+	adjustments, err := s.svc.ApplySplits(ctx)
+	if err != nil {
+		log.Printf("scheduler: apply splits: %v", err)
+		// Adjustments already committed are still announced below.
+	}
+
+	affected := make(map[int64]struct{})
+	for _, a := range adjustments {
+		log.Printf("scheduler: split %s %s for %d/%d: %s -> %s shares",
+			a.Symbol, a.Ratio, a.ChatID, a.PortfolioID, a.OldShares, a.NewShares)
+		s.notifier.SendMarkdown(a.ChatID, fmt.Sprintf(
+			"🔀 *%s* split %s on %s.\nYour shares in %s were adjusted from %s to %s.",
+			a.Symbol, a.Ratio, a.SplitDate.Format("Jan 2, 2006"), portfolio.EscapeMarkdown(a.PortfolioName),
+			a.OldShares, a.NewShares))
+		affected[a.ChatID] = struct{}{}
+	}
 
-		if err := repo.SaveReport(chatID, report.TotalUSD); err != nil {
-			log.Printf("scheduler: save report %d: %v", chatID, err)
+	for chatID := range affected {
+		if _, _, err := s.svc.ResetBaseline(ctx, chatID); err != nil {
+			log.Printf("scheduler: reset baseline %d after split: %v", chatID, err)
 		}
 	}
 }